@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BBleae/whisper-lrc/internal/input"
+)
+
+// archiveMember is an audio file of an archive input
+type archiveMember struct {
+	archive string
+	name    string
+	path    string
+}
+
+// archiveMembers maps the labels of archive members (the archive path joined
+// with the member name, e.g. album.zip/disc1/01.mp3) to the extracted files
+var archiveMembers = map[string]archiveMember{}
+
+// archived collects the outputs of archive members for --archive-output,
+// keyed by output path
+var archived map[string]string
+
+// archivedMu guards archived while several inputs are processed at once
+var archivedMu sync.Mutex
+
+// expandArchives replaces local archive inputs with their audio files,
+// extracted to a temporary directory that cleanup removes
+func expandArchives(args []string) (expanded []string, cleanup func(), err error) {
+	var dirs []string
+	cleanup = func() {
+		for _, dir := range dirs {
+			os.RemoveAll(dir)
+		}
+	}
+
+	for _, arg := range args {
+		if strings.Contains(arg, "://") || !input.IsArchive(arg) {
+			expanded = append(expanded, arg)
+			continue
+		}
+
+		dir, err := os.MkdirTemp("", "whisper-lrc-archive-")
+		if err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		dirs = append(dirs, dir)
+
+		members, err := input.ExtractArchive(arg, dir)
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		if len(members) == 0 {
+			cleanup()
+			return nil, nil, fmt.Errorf("%s contains no supported audio files", arg)
+		}
+		for _, m := range members {
+			label := filepath.Join(arg, filepath.FromSlash(m.Name))
+			archiveMembers[label] = archiveMember{archive: arg, name: m.Name, path: m.Path}
+			expanded = append(expanded, label)
+		}
+	}
+	return expanded, cleanup, nil
+}
+
+// memberOutputPath places the output of an archive member in a folder named
+// after the archive, keeping the member's folders
+func memberOutputPath(m archiveMember, outputDir, format string) string {
+	name := strings.TrimSuffix(filepath.FromSlash(m.name), filepath.Ext(m.name))
+	return filepath.Join(memberFolder(m.archive, outputDir), name+"."+format)
+}
+
+// memberFolder returns the folder for the outputs of an archive's members:
+// named after the archive, in the output directory or next to the archive
+func memberFolder(archive, outputDir string) string {
+	dir := outputDir
+	if dir == "" {
+		dir = filepath.Dir(archive)
+	}
+	return filepath.Join(dir, archiveStem(archive))
+}
+
+// archiveStem returns the archive file name without its extension
+func archiveStem(archive string) string {
+	base := filepath.Base(archive)
+	lower := strings.ToLower(base)
+	for _, ext := range []string{".tar.gz", ".tgz", ".tar", ".zip"} {
+		if strings.HasSuffix(lower, ext) {
+			return base[:len(base)-len(ext)]
+		}
+	}
+	return base
+}
+
+// writeResultArchives writes the collected outputs of each archive input to
+// <name>.lyrics.zip next to its output folder
+func writeResultArchives(outputDir string) ([]string, error) {
+	// Archives with the same name share a folder and so a result archive
+	seen := map[string]bool{}
+	var folders []string
+	for _, m := range archiveMembers {
+		if folder := memberFolder(m.archive, outputDir); !seen[folder] {
+			seen[folder] = true
+			folders = append(folders, folder)
+		}
+	}
+	sort.Strings(folders)
+
+	var written []string
+	for _, folder := range folders {
+		var names []string
+		for path := range archived {
+			if rel, err := filepath.Rel(folder, path); err == nil && filepath.IsLocal(rel) {
+				names = append(names, path)
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+		sort.Strings(names)
+
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for _, path := range names {
+			rel, _ := filepath.Rel(folder, path)
+			w, err := zw.CreateHeader(&zip.FileHeader{Name: filepath.ToSlash(rel), Method: zip.Deflate, Modified: time.Now()})
+			if err != nil {
+				return written, fmt.Errorf("failed to write result archive: %w", err)
+			}
+			if _, err := w.Write([]byte(archived[path])); err != nil {
+				return written, fmt.Errorf("failed to write result archive: %w", err)
+			}
+		}
+		if err := zw.Close(); err != nil {
+			return written, fmt.Errorf("failed to write result archive: %w", err)
+		}
+
+		zipPath := folder + ".lyrics.zip"
+		if err := outputs.Write(zipPath, buf.Bytes()); err != nil {
+			return written, fmt.Errorf("failed to write result archive: %w", err)
+		}
+		written = append(written, outputs.Location(zipPath))
+	}
+	return written, nil
+}
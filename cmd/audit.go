@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/BBleae/whisper-lrc/internal/audio"
+	"github.com/BBleae/whisper-lrc/internal/input"
+	"github.com/BBleae/whisper-lrc/internal/lyrics"
+	"github.com/spf13/cobra"
+)
+
+var (
+	auditFormat  string
+	auditMissing bool
+)
+
+// Lyrics states reported by audit
+const (
+	auditSynced   = "synced"
+	auditUnsynced = "unsynced"
+	auditNone     = "none"
+)
+
+// auditEntry is the lyrics state of one track
+type auditEntry struct {
+	Path   string `json:"path"`
+	Lyrics string `json:"lyrics"`
+	// Source is where the lyrics were found: a sidecar file or "tags"
+	Source string `json:"source,omitempty"`
+}
+
+var auditCmd = &cobra.Command{
+	Use:   "audit [directories or files...]",
+	Short: "Report which tracks of a music library have synced lyrics",
+	Long: `Scan music folders and report for each track whether it has synced lyrics
+(an LRC file next to it, or timed lyrics in its tags), unsynced lyrics (a .txt
+or untimed .lrc file, or plain lyrics in its tags) or none. Reading the tags
+of FLAC, Ogg and M4A files needs ffprobe.
+
+With --missing, only the tracks without synced lyrics are printed, one per
+line, ready for a follow-up transcription run.
+
+Examples:
+  whisper-lrc audit ~/Music
+  whisper-lrc audit ~/Music --format csv > lyrics.csv
+  whisper-lrc audit ~/Music --missing | xargs -d '\n' whisper-lrc`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runAudit,
+}
+
+func init() {
+	auditCmd.Flags().StringVarP(&auditFormat, "format", "f", "table", "Report format: table, csv or json")
+	auditCmd.Flags().BoolVar(&auditMissing, "missing", false, "Only print the paths of tracks without synced lyrics")
+	rootCmd.AddCommand(auditCmd)
+}
+
+func runAudit(cmd *cobra.Command, args []string) error {
+	switch auditFormat {
+	case "table", "csv", "json":
+	default:
+		return fmt.Errorf("invalid audit format: %s. Use 'table', 'csv' or 'json'", auditFormat)
+	}
+
+	var tracks []string
+	for _, arg := range args {
+		err := filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && input.IsSupported(path) {
+				tracks = append(tracks, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var entries []auditEntry
+	tagsChecked := true
+	for _, path := range tracks {
+		entry, err := auditTrack(path)
+		if err != nil && tagsChecked {
+			fmt.Fprintf(os.Stderr, "Warning: tags not checked: %v\n", err)
+			tagsChecked = false
+		}
+		entries = append(entries, entry)
+	}
+
+	w := cmd.OutOrStdout()
+	if auditMissing {
+		for _, e := range entries {
+			if e.Lyrics != auditSynced {
+				fmt.Fprintln(w, e.Path)
+			}
+		}
+		return nil
+	}
+	switch auditFormat {
+	case "csv":
+		return writeAuditCSV(w, entries)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	default:
+		printAudit(w, entries)
+		return nil
+	}
+}
+
+// auditTrack finds the best lyrics of a track: a sidecar LRC file, then its
+// tags, then a plain text file. The error reports tags that could not be
+// read; the entry is still filled from the sidecar files.
+func auditTrack(path string) (auditEntry, error) {
+	stem := strings.TrimSuffix(path, filepath.Ext(path))
+	entry := auditEntry{Path: path, Lyrics: auditNone}
+
+	if data, err := os.ReadFile(stem + ".lrc"); err == nil {
+		entry.Source = filepath.Base(stem + ".lrc")
+		if len(lyrics.ParseLRC(string(data)).Lines) > 0 {
+			entry.Lyrics = auditSynced
+			return entry, nil
+		}
+		entry.Lyrics = auditUnsynced
+	}
+
+	embedded, err := audio.ReadEmbeddedLyrics(path)
+	if embedded != nil {
+		if embedded.Synced || len(lyrics.ParseLRC(embedded.Text).Lines) > 0 {
+			return auditEntry{Path: path, Lyrics: auditSynced, Source: "tags"}, nil
+		}
+		if entry.Lyrics == auditNone {
+			entry.Lyrics, entry.Source = auditUnsynced, "tags"
+		}
+	}
+
+	if entry.Lyrics == auditNone {
+		if _, statErr := os.Stat(stem + ".txt"); statErr == nil {
+			entry.Lyrics, entry.Source = auditUnsynced, filepath.Base(stem+".txt")
+		}
+	}
+	return entry, err
+}
+
+// printAudit prints the counts and the tracks without synced lyrics
+func printAudit(w io.Writer, entries []auditEntry) {
+	counts := map[string]int{}
+	for _, e := range entries {
+		counts[e.Lyrics]++
+	}
+	fmt.Fprintf(w, "%d track(s): %d synced, %d unsynced, %d without lyrics\n",
+		len(entries), counts[auditSynced], counts[auditUnsynced], counts[auditNone])
+	if counts[auditSynced] == len(entries) {
+		return
+	}
+
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LYRICS\tSOURCE\tTRACK")
+	for _, e := range entries {
+		if e.Lyrics == auditSynced {
+			continue
+		}
+		source := e.Source
+		if source == "" {
+			source = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", e.Lyrics, source, e.Path)
+	}
+	tw.Flush()
+}
+
+// writeAuditCSV writes every track with its lyrics state
+func writeAuditCSV(w io.Writer, entries []auditEntry) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"path", "lyrics", "source"})
+	for _, e := range entries {
+		cw.Write([]string{e.Path, e.Lyrics, e.Source})
+	}
+	cw.Flush()
+	return cw.Error()
+}
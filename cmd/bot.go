@@ -0,0 +1,289 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BBleae/whisper-lrc/internal/filename"
+	"github.com/BBleae/whisper-lrc/internal/history"
+	"github.com/BBleae/whisper-lrc/internal/input"
+	"github.com/BBleae/whisper-lrc/internal/output"
+	"github.com/BBleae/whisper-lrc/internal/postprocess"
+	"github.com/BBleae/whisper-lrc/internal/redact"
+	"github.com/BBleae/whisper-lrc/internal/telegram"
+	"github.com/BBleae/whisper-lrc/internal/tlsconfig"
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+	"github.com/spf13/cobra"
+)
+
+var (
+	telegramToken string
+	telegramAPI   string
+	botChats      []int64
+	botFormat     string
+)
+
+// botPollTimeout is how long each getUpdates request waits for messages
+const botPollTimeout = 50 * time.Second
+
+// botHelp is the reply to /start, /help and messages without audio
+const botHelp = `Send me a song, voice message or video (up to 20 MB), or a link to one, and I'll reply with its lyrics.
+
+Start the caption with /lrc, /srt or /vtt to pick the format.`
+
+var botCmd = &cobra.Command{
+	Use:   "bot",
+	Short: "Run a Telegram bot that replies to audio with lyrics files",
+	Long: `Listen for messages to a Telegram bot and reply to audio files, voice
+messages, videos and links with their lyrics as an LRC, SRT or WebVTT file.
+Messages are handled one at a time. Press Ctrl+C to stop.
+
+Create the bot with @BotFather and pass its token with --telegram-token or
+TELEGRAM_BOT_TOKEN. Only the chats given with --allow-chat are served, since
+every transcription is billed to your API key; other chats are told their
+chat ID so you can add them.
+
+Examples:
+  whisper-lrc bot --telegram-token 123456:ABC... --allow-chat 987654321
+  TELEGRAM_BOT_TOKEN=123456:ABC... whisper-lrc bot --allow-chat 987654321 --format srt`,
+	Args: cobra.NoArgs,
+	RunE: runBot,
+}
+
+func init() {
+	botCmd.Flags().StringVar(&telegramToken, "telegram-token", "", "Telegram bot token from @BotFather (or set TELEGRAM_BOT_TOKEN env)")
+	botCmd.Flags().StringVar(&telegramAPI, "telegram-api", "", "Bot API server, e.g. a local telegram-bot-api server (default: "+telegram.DefaultBaseURL+")")
+	botCmd.Flags().Int64SliceVar(&botChats, "allow-chat", nil, "Chat IDs the bot serves (users or groups)")
+	botCmd.Flags().StringVarP(&botFormat, "format", "f", "lrc", "Default reply format: lrc, srt or vtt")
+	botCmd.Flags().BoolVar(&useYtDlp, "yt-dlp", false, "Use yt-dlp for YouTube/video links")
+	rootCmd.AddCommand(botCmd)
+}
+
+func runBot(cmd *cobra.Command, args []string) error {
+	key, err := resolveAPIKey()
+	if err != nil {
+		return err
+	}
+	token := telegramToken
+	if token == "" {
+		token = os.Getenv("TELEGRAM_BOT_TOKEN")
+	}
+	if token == "" {
+		return fmt.Errorf("Telegram bot token required. Set --telegram-token or TELEGRAM_BOT_TOKEN environment variable")
+	}
+	redact.Register(token)
+	if _, _, err := botFormatter(botFormat); err != nil {
+		return err
+	}
+	if len(botChats) == 0 {
+		return fmt.Errorf("--allow-chat is required; message the bot to learn your chat ID")
+	}
+
+	client, err := newClient(key)
+	if err != nil {
+		return err
+	}
+	store, err := openHistory()
+	if err != nil {
+		return err
+	}
+	cfg, err := tlsconfig.Load(tlsOpts)
+	if err != nil {
+		return err
+	}
+	bot := telegram.NewBot(token,
+		telegram.WithBaseURL(telegramAPI),
+		telegram.WithHTTPClient(&http.Client{Timeout: botPollTimeout + 30*time.Second, Transport: tlsconfig.Transport(cfg)}),
+	)
+	handler := input.NewHandler(useYtDlp, input.WithTLS(tlsOpts))
+
+	stderr := redact.Writer(os.Stderr)
+	fmt.Fprintln(stderr, "Waiting for messages... press Ctrl+C to stop")
+	var offset int64
+	for {
+		updates, err := bot.Updates(offset, botPollTimeout)
+		if err != nil {
+			fmt.Fprintf(stderr, "Warning: %v\n", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			if u.Message != nil {
+				handleBotMessage(bot, client, handler, store, u.Message, stderr)
+			}
+		}
+	}
+}
+
+// handleBotMessage replies to one message with a lyrics file, help or an
+// error
+func handleBotMessage(bot *telegram.Bot, client *whisper.Client, handler *input.Handler, store *history.Store, msg *telegram.Message, log io.Writer) {
+	reply := func(text string) {
+		if err := bot.SendMessage(msg.Chat.ID, msg.MessageID, text); err != nil {
+			fmt.Fprintf(log, "Warning: %v\n", err)
+		}
+	}
+
+	if !botAllowed(msg.Chat.ID) {
+		fmt.Fprintf(log, "Ignored a message from chat %d (not in --allow-chat)\n", msg.Chat.ID)
+		reply(fmt.Sprintf("This bot is private. Ask its owner to add chat ID %d with --allow-chat.", msg.Chat.ID))
+		return
+	}
+
+	text := msg.Text
+	if text == "" {
+		text = msg.Caption
+	}
+	format := botFormat
+	words := strings.Fields(text)
+	if len(words) > 0 && strings.HasPrefix(words[0], "/") {
+		command, _, _ := strings.Cut(strings.TrimPrefix(words[0], "/"), "@")
+		switch command {
+		case "lrc", "srt", "vtt":
+			format = command
+		default:
+			reply(botHelp)
+			return
+		}
+	}
+
+	src, name, err := botSource(bot, handler, msg, words)
+	if err != nil {
+		fmt.Fprintf(log, "✗ chat %d: %v\n", msg.Chat.ID, err)
+		reply("✗ " + redact.String(err.Error()))
+		return
+	}
+	if src == nil {
+		reply(botHelp)
+		return
+	}
+	defer src.Cleanup()
+
+	started := time.Now()
+	result, err := client.TranscribeAudio(whisper.Audio{Path: src.Path, Name: src.Name, Data: src.Data}, language, effectivePrompt())
+	billed := 0.0
+	if err == nil {
+		billed = result.Duration
+	}
+	if err := recordUsage(store, billed, time.Since(started), err != nil); err != nil && verbose {
+		fmt.Fprintf(log, "Warning: %v\n", err)
+	}
+	if err != nil {
+		fmt.Fprintf(log, "✗ chat %d: %s: %v\n", msg.Chat.ID, name, err)
+		reply("✗ " + redact.String(err.Error()))
+		return
+	}
+
+	postprocess.Pipeline{postprocess.NewGapFiller()}.Process(result)
+	formatter, ext, _ := botFormatter(format)
+	caption := fmt.Sprintf("%d line(s), language %s", len(result.Segments), result.Language)
+	if err := bot.SendDocument(msg.Chat.ID, msg.MessageID, name+ext, []byte(formatter.Format(result)), caption); err != nil {
+		fmt.Fprintf(log, "Warning: %v\n", err)
+		return
+	}
+	fmt.Fprintf(log, "✓ chat %d: %s%s\n", msg.Chat.ID, name, ext)
+}
+
+// botAllowed reports whether a chat is in --allow-chat
+func botAllowed(chatID int64) bool {
+	for _, id := range botChats {
+		if id == chatID {
+			return true
+		}
+	}
+	return false
+}
+
+// botSource downloads the attachment of a message, or the first link in its
+// text, and returns it with a name for the reply. A message with neither
+// returns a nil source.
+func botSource(bot *telegram.Bot, handler *input.Handler, msg *telegram.Message, words []string) (*input.Source, string, error) {
+	if file := msg.Attachment(); file != nil {
+		if file.FileSize > telegram.MaxDownload {
+			return nil, "", fmt.Errorf("the file is larger than the 20 MB bots can download")
+		}
+		name := filename.Sanitize(strings.TrimSuffix(file.FileName, filepath.Ext(file.FileName)))
+		if name == "" {
+			name = fmt.Sprintf("message-%d", msg.MessageID)
+		}
+		ext := attachmentExt(file)
+		if !input.IsSupported(ext) {
+			return nil, "", fmt.Errorf("%w: %s", input.ErrUnsupportedFormat, ext)
+		}
+
+		// Bot downloads are small enough to keep in memory
+		var buf bytes.Buffer
+		if err := bot.Download(file, &buf); err != nil {
+			return nil, "", err
+		}
+		return &input.Source{Data: buf.Bytes(), Name: name + ext}, name, nil
+	}
+
+	for _, word := range words {
+		if strings.HasPrefix(word, "http://") || strings.HasPrefix(word, "https://") {
+			src, err := handler.Resolve(word)
+			if err != nil {
+				return nil, "", err
+			}
+			var name string
+			if u, err := url.Parse(word); err == nil {
+				base := path.Base(u.Path)
+				if base != "/" {
+					name = filename.Sanitize(strings.TrimSuffix(base, path.Ext(base)))
+				}
+			}
+			if name == "" {
+				name = fmt.Sprintf("message-%d", msg.MessageID)
+			}
+			return src, name, nil
+		}
+	}
+	return nil, "", nil
+}
+
+// attachmentExt picks the file extension of an attachment from its name or
+// MIME type. Telegram voice messages are Opus in Ogg (.oga).
+func attachmentExt(file *telegram.File) string {
+	ext := strings.ToLower(filepath.Ext(file.FileName))
+	if ext == "" {
+		if exts, _ := mime.ExtensionsByType(file.MimeType); len(exts) > 0 {
+			ext = exts[0]
+		}
+		switch file.MimeType {
+		case "audio/ogg", "audio/opus":
+			ext = ".ogg"
+		case "audio/mpeg":
+			ext = ".mp3"
+		case "video/mp4":
+			ext = ".mp4"
+		}
+	}
+	if ext == ".oga" || ext == ".opus" {
+		ext = ".ogg"
+	}
+	return ext
+}
+
+// botFormatter returns the formatter and file extension for a reply format
+func botFormatter(format string) (output.Formatter, string, error) {
+	switch format {
+	case "lrc":
+		return output.NewLRCFormatter(), ".lrc", nil
+	case "srt":
+		return output.NewSRTFormatter(), ".srt", nil
+	case "vtt":
+		return output.NewVTTFormatter(), ".vtt", nil
+	default:
+		return nil, "", fmt.Errorf("unsupported bot format %q (use lrc, srt or vtt)", format)
+	}
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"math"
+	"time"
+
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// chunkSizer chooses the length of each part of audio split for upload.
+// Parts are sent two at a time, so one uploads while the server works on
+// the other. Short parts start that overlap sooner and leave less waiting
+// at the end, but every request adds a fixed latency. The length balancing
+// the two is sqrt(remaining × latency / cost), where cost is the upload and
+// server time per second of audio, both fitted from the parts done so far.
+type chunkSizer struct {
+	shortest, longest time.Duration
+	cut               int // parts cut so far
+	samples           []chunkSample
+}
+
+// chunkSample is the timing of one transcribed part, in seconds
+type chunkSample struct {
+	length, upload, wait float64
+}
+
+// observe records the timing of a transcribed part
+func (s *chunkSizer) observe(length time.Duration, timing whisper.Timing) {
+	s.samples = append(s.samples, chunkSample{length.Seconds(), timing.Upload.Seconds(), timing.Wait.Seconds()})
+}
+
+// next returns the length of the next part given the audio remaining.
+// Until the timing of parts of two lengths is known, parts double in
+// length from the shortest.
+func (s *chunkSizer) next(remaining time.Duration) time.Duration {
+	length := s.shortest
+	if latency, cost, ok := s.fit(); ok {
+		length = time.Duration(math.Sqrt(remaining.Seconds()*latency/cost) * float64(time.Second))
+	} else {
+		for i := 0; i < s.cut && length < s.longest; i++ {
+			length *= 2
+		}
+	}
+	s.cut++
+	length = max(s.shortest, min(length, s.longest))
+	// Take the rest along rather than leave a short last part
+	if remaining-length < s.shortest && remaining <= s.longest {
+		return remaining
+	}
+	return min(length, remaining)
+}
+
+// fit estimates the latency of a request and the time per second of audio
+// by least squares over the samples; it needs parts of two lengths
+func (s *chunkSizer) fit() (latency, cost float64, ok bool) {
+	n := float64(len(s.samples))
+	var sumLength, sumWait, sumSquares, sumProducts, upload float64
+	for _, sample := range s.samples {
+		sumLength += sample.length
+		sumWait += sample.wait
+		sumSquares += sample.length * sample.length
+		sumProducts += sample.length * sample.wait
+		upload += sample.upload
+	}
+	det := n*sumSquares - sumLength*sumLength
+	if len(s.samples) < 2 || det <= 1e-6*sumSquares {
+		return 0, 0, false
+	}
+	rate := (n*sumProducts - sumLength*sumWait) / det
+	latency = (sumWait - rate*sumLength) / n
+	cost = max(rate, 0) + upload/sumLength
+	if cost <= 0 {
+		return 0, 0, false
+	}
+	return max(latency, 0), cost, true
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import "sync"
+
+// Reasons a batch stops starting inputs
+const (
+	haltInterrupted = "interrupted"
+	haltBudget      = "budget"
+	haltAuth        = "auth"
+)
+
+// runInputs runs process for the inputs 0 to n-1 in order, at most workers
+// at a time. Before starting an input it waits for a free worker and asks
+// next whether to go on, so with one worker each input starts only after the
+// previous one finished and next sees its effect on the budget. It waits for
+// the inputs in progress and returns the number of inputs started.
+func runInputs(n, workers int, next func(i int) bool, process func(i int)) int {
+	slots := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i := range n {
+		slots <- struct{}{}
+		if !next(i) {
+			wg.Wait()
+			return i
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			process(i)
+		}()
+	}
+	wg.Wait()
+	return n
+}
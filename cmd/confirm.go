@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/BBleae/whisper-lrc/internal/audio"
+	"github.com/BBleae/whisper-lrc/internal/input"
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// resolvedInput is an input resolved before any API call is made
+type resolvedInput struct {
+	src     *input.Source
+	err     error
+	elapsed time.Duration
+}
+
+// resolveInput resolves one input and times the download. Archive members
+// are already extracted.
+func resolveInput(handler *input.Handler, arg string) resolvedInput {
+	if m, ok := archiveMembers[arg]; ok {
+		return resolvedInput{src: &input.Source{Path: m.path}}
+	}
+	started := time.Now()
+	src, err := handler.Resolve(arg)
+	return resolvedInput{src: src, err: err, elapsed: time.Since(started)}
+}
+
+// resolveAll resolves every input up front so the batch can be reviewed
+func resolveAll(handler *input.Handler, args []string, w io.Writer) []resolvedInput {
+	resolved := make([]resolvedInput, len(args))
+	for i, arg := range args {
+		fmt.Fprintf(w, "Resolving %s...\n", arg)
+		resolved[i] = resolveInput(handler, arg)
+	}
+	return resolved
+}
+
+// cleanupResolved removes temporary files of inputs that will not be processed
+func cleanupResolved(resolved []resolvedInput) {
+	for _, r := range resolved {
+		if r.src != nil {
+			r.src.Cleanup()
+		}
+	}
+}
+
+// confirmBatch prints the resolved inputs with their durations and estimated
+// cost, then asks whether to start. Inputs over --max-audio-duration are
+// left out of the totals and the user is asked whether to transcribe them
+// too (includeLong). With assumeYes it starts without asking and leaves the
+// long inputs out.
+func confirmBatch(args []string, resolved []resolvedInput, assumeYes bool, w io.Writer) (start, includeLong bool, err error) {
+	var total, longTotal time.Duration
+	unknown, long := 0, 0
+	fmt.Fprintln(w)
+	for i, arg := range args {
+		r := resolved[i]
+		if r.err != nil {
+			fmt.Fprintf(w, "  ✗ %s: %v\n", arg, r.err)
+			continue
+		}
+
+		length, err := audio.Duration(r.src.Path)
+		if err != nil {
+			unknown++
+			fmt.Fprintf(w, "  %s (duration unknown)\n", arg)
+			continue
+		}
+		if preview > 0 && length > preview {
+			length = preview
+		}
+		if tooLong(length) {
+			long++
+			longTotal += length
+			fmt.Fprintf(w, "  ! %s (%s, over --max-audio-duration)\n", arg, length.Round(time.Second))
+			continue
+		}
+		total += length
+		fmt.Fprintf(w, "  %s (%s)\n", arg, length.Round(time.Second))
+	}
+
+	fmt.Fprintf(w, "\nTotal duration: %s", total.Round(time.Second))
+	if unknown > 0 {
+		fmt.Fprintf(w, " plus %d file(s) of unknown length", unknown)
+	}
+	fmt.Fprintf(w, "\nEstimated cost: $%.2f (OpenAI whisper-1 pricing)\n", whisper.EstimateCost(total))
+
+	if assumeYes {
+		if long > 0 {
+			fmt.Fprintf(w, "Skipping %d file(s) longer than %s\n", long, maxAudioLength)
+		}
+		return true, false, nil
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	if long > 0 {
+		question := fmt.Sprintf("Also transcribe the %d file(s) longer than %s (%s, $%.2f more)?",
+			long, maxAudioLength, longTotal.Round(time.Second), whisper.EstimateCost(longTotal))
+		if includeLong, err = ask(reader, w, question); err != nil {
+			return false, false, err
+		}
+	}
+	start, err = ask(reader, w, "Start transcription?")
+	return start, includeLong, err
+}
+
+// ask prints a yes/no question and reads the answer, defaulting to no
+func ask(reader *bufio.Reader, w io.Writer, question string) (bool, error) {
+	fmt.Fprintf(w, "%s [y/N] ", question)
+	answer, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("failed to read confirmation: %w", err)
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// tooLong reports whether audio of the given length exceeds
+// --max-audio-duration. Previews are short by design and never are.
+func tooLong(length time.Duration) bool {
+	return maxAudioLength > 0 && preview == 0 && length > maxAudioLength
+}
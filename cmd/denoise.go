@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// compareDenoise transcribes the audio from before --denoise as well and
+// describes how confident Whisper was in each transcription, so that -v
+// shows whether denoising helps a recording. It returns the seconds of audio
+// billed for the extra transcription.
+func compareDenoise(client *whisper.Client, noisyPath string, denoised *whisper.TranscriptionResult) (float64, string) {
+	after, ok := denoised.Confidence()
+	if !ok {
+		return 0, "confidence not compared, the server does not report it"
+	}
+	original, err := client.TranscribeAudio(whisper.Audio{Path: noisyPath}, language, effectivePrompt())
+	if err != nil {
+		return 0, fmt.Sprintf("confidence not compared: %v", err)
+	}
+	before, _ := original.Confidence()
+	denoised.Timing.Add(original.Timing)
+	return original.Duration, fmt.Sprintf("confidence %.2f before denoising, %.2f after (average log probability, higher is better)", before, after)
+}
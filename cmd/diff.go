@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/BBleae/whisper-lrc/internal/lyrics"
+	"github.com/spf13/cobra"
+)
+
+var diffSummary bool
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <reference> <file>",
+	Short: "Compare a lyric file against a reference",
+	Long: `Compare an LRC or SRT file against a reference (e.g. a hand-checked golden
+copy) and show changed, missing and extra lines with their timing
+differences, followed by the word error rate (WER) and timing statistics.
+
+Case and punctuation are ignored. Chinese and Japanese text is compared
+character by character.
+
+Examples:
+  whisper-lrc diff golden/song.lrc song.lrc
+  whisper-lrc diff --summary golden/song.srt song.srt`,
+	Args: cobra.ExactArgs(2),
+	RunE: runDiff,
+}
+
+func init() {
+	diffCmd.Flags().BoolVar(&diffSummary, "summary", false, "Only print the WER and timing statistics")
+	rootCmd.AddCommand(diffCmd)
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	ref, err := lyrics.Load(args[0])
+	if err != nil {
+		return err
+	}
+	hyp, err := lyrics.Load(args[1])
+	if err != nil {
+		return err
+	}
+
+	d := lyrics.Compare(ref, hyp)
+
+	var same, changed, removed, added, timed int
+	var totalDelta, maxDelta float64
+	for _, line := range d.Lines {
+		switch line.Op {
+		case lyrics.Same:
+			same++
+		case lyrics.Changed:
+			changed++
+		case lyrics.Removed:
+			removed++
+		case lyrics.Added:
+			added++
+		}
+		if line.Ref != nil && line.Hyp != nil {
+			timed++
+			totalDelta += math.Abs(line.Delta())
+			maxDelta = math.Max(maxDelta, math.Abs(line.Delta()))
+		}
+
+		if !diffSummary {
+			fmt.Print(formatLineDiff(line))
+		}
+	}
+
+	if !diffSummary {
+		fmt.Println()
+	}
+	fmt.Printf("WER: %.1f%% (%d substitution(s), %d deletion(s), %d insertion(s) over %d reference word(s))\n",
+		d.WER()*100, d.Substitutions, d.Deletions, d.Insertions, d.RefWords)
+	fmt.Printf("Lines: %d same, %d changed, %d removed, %d added\n", same, changed, removed, added)
+	if timed > 0 {
+		fmt.Printf("Timing: mean |Δ| %.2fs, max |Δ| %.2fs over %d aligned line(s)\n", totalDelta/float64(timed), maxDelta, timed)
+	}
+	return nil
+}
+
+// formatLineDiff renders an aligned line pair in unified-diff style
+func formatLineDiff(d lyrics.LineDiff) string {
+	line := func(prefix string, l *lyrics.Line, suffix string) string {
+		return fmt.Sprintf("%s [%s] %s%s\n", prefix, formatClock(l.Start), l.Text, suffix)
+	}
+
+	delta := ""
+	if d.Ref != nil && d.Hyp != nil && math.Abs(d.Delta()) >= 0.005 {
+		delta = fmt.Sprintf("  (%+.2fs)", d.Delta())
+	}
+
+	switch d.Op {
+	case lyrics.Same:
+		return line(" ", d.Hyp, delta)
+	case lyrics.Changed:
+		return line("-", d.Ref, "") + line("+", d.Hyp, delta)
+	case lyrics.Removed:
+		return line("-", d.Ref, "")
+	default:
+		return line("+", d.Hyp, "")
+	}
+}
+
+// formatClock renders seconds as mm:ss.xx
+func formatClock(seconds float64) string {
+	cs := int(math.Round(seconds * 100))
+	return fmt.Sprintf("%02d:%02d.%02d", cs/6000, cs/100%60, cs%100)
+}
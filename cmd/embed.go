@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/BBleae/whisper-lrc/internal/audio"
+	"github.com/spf13/cobra"
+)
+
+var embedCmd = &cobra.Command{
+	Use:   "embed <audio file> <lyrics file>",
+	Short: "Embed an existing LRC file into an audio file's tags",
+	Long: `Write lyrics generated earlier (or obtained elsewhere) into the tags of an
+audio file, the same way --embed does after transcription. No API call is made.
+
+MP3 (ID3), FLAC, Ogg and M4A/MP4 files are supported; ffmpeg is required.
+
+Examples:
+  whisper-lrc embed song.mp3 song.lrc
+  whisper-lrc embed album/01.flac lyrics/01.lrc`,
+	Args: cobra.ExactArgs(2),
+	RunE: runEmbed,
+}
+
+func init() {
+	rootCmd.AddCommand(embedCmd)
+}
+
+func runEmbed(cmd *cobra.Command, args []string) error {
+	audioPath, lyricsPath := args[0], args[1]
+
+	data, err := os.ReadFile(lyricsPath)
+	if err != nil {
+		return fmt.Errorf("failed to read lyrics: %w", err)
+	}
+	if !utf8.Valid(data) {
+		return fmt.Errorf("%s is not UTF-8 encoded", lyricsPath)
+	}
+	lyrics := strings.TrimPrefix(string(data), "\ufeff")
+
+	if err := audio.EmbedLyrics(audioPath, lyrics); err != nil {
+		return err
+	}
+	fmt.Printf("✓ %s -> %s\n", lyricsPath, audioPath)
+	return nil
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// envPrefix starts the environment variable of every flag, e.g.
+// WHISPER_LRC_FORMAT for --format
+const envPrefix = "WHISPER_LRC_"
+
+func init() {
+	// The command line wins over the environment, and both over --profile.
+	// Variables shared with other OpenAI clients come last.
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := applyEnv(cmd); err != nil {
+			return err
+		}
+		if err := applyProfile(cmd); err != nil {
+			return err
+		}
+		resolveAPIBase()
+		return nil
+	}
+}
+
+// envName returns the environment variable for a flag
+func envName(flag string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// applyEnv sets the flags of cmd that were not given on the command line
+// from their environment variables. List flags take comma-separated values,
+// except the repeatable flags whose values may contain commas (StringArray,
+// such as --replace), which take the whole variable as one value.
+func applyEnv(cmd *cobra.Command) error {
+	var err error
+	apply := func(f *pflag.Flag) {
+		if err != nil || f.Changed || f.Name == "help" {
+			return
+		}
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok {
+			return
+		}
+		if setErr := f.Value.Set(value); setErr != nil {
+			err = fmt.Errorf("invalid %s: %w", envName(f.Name), setErr)
+			return
+		}
+		f.Changed = true
+	}
+	cmd.Flags().VisitAll(apply)
+	cmd.InheritedFlags().VisitAll(apply)
+	return err
+}
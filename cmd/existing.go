@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BBleae/whisper-lrc/internal/audio"
+	"github.com/BBleae/whisper-lrc/internal/input"
+	"github.com/BBleae/whisper-lrc/internal/storage"
+)
+
+// existingLyrics describes the lyrics an input already has for --if-missing:
+// the output file, an LRC file next to a local input, or lyrics in the audio
+// file's tags. It returns "" when there are none.
+func existingLyrics(arg string, src *input.Source, outPath string) (string, error) {
+	if existing := existingOutput(outPath); existing != "" {
+		return existing, nil
+	}
+
+	_, member := archiveMembers[arg]
+	if !member && !strings.Contains(arg, "://") {
+		sidecar := strings.TrimSuffix(arg, filepath.Ext(arg)) + ".lrc"
+		if _, err := os.Stat(sidecar); err == nil {
+			return sidecar, nil
+		}
+	}
+
+	if src.Path == "" {
+		return "", nil
+	}
+	embedded, err := audio.HasEmbeddedLyrics(src.Path)
+	if err != nil || !embedded {
+		return "", err
+	}
+	return "in its tags", nil
+}
+
+// existingOutput returns the location of an output file written by an
+// earlier run, or "". With --lang-suffix and a detected language the code is
+// not known yet, so an output with any language code counts.
+func existingOutput(outPath string) string {
+	if outputExists(outPath) {
+		return outputs.Location(outPath)
+	}
+	if langSuffix && language == "" {
+		return languageOutput(outPath)
+	}
+	return ""
+}
+
+// languageOutput finds song.ja.lrc and the like for an output path of
+// song.lrc. Only local outputs can be listed; remote ones return "".
+func languageOutput(outPath string) string {
+	if _, local := outputs.(storage.Local); !local {
+		return ""
+	}
+	path := outputs.Location(outPath)
+	stem := strings.TrimSuffix(filepath.Base(path), outputExt())
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		code, ok := strings.CutPrefix(entry.Name(), stem)
+		if !ok {
+			continue
+		}
+		code, ok = strings.CutSuffix(code, "."+outputExt())
+		// ISO 639 codes, which also keeps song.preview.lrc from matching
+		if ok && len(code) >= 2 && len(code) <= 3 && strings.Trim(code, "abcdefghijklmnopqrstuvwxyz") == "" {
+			return filepath.Join(filepath.Dir(path), entry.Name())
+		}
+	}
+	return ""
+}
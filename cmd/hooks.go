@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"github.com/BBleae/whisper-lrc/internal/hook"
+)
+
+// runPreHook runs --pre-hook before an input is transcribed. The input is
+// skipped when the hook fails.
+func runPreHook(arg, outPath, audioPath string) (string, error) {
+	if preHook == "" {
+		return "", nil
+	}
+	return hook.Run(preHook, map[string]string{
+		"WHISPER_LRC_INPUT":  arg,
+		"WHISPER_LRC_OUTPUT": outputs.Location(outPath),
+		"WHISPER_LRC_AUDIO":  audioPath,
+	})
+}
+
+// runPostHook runs --post-hook after an input is done, with its status
+// ("ok" or "failed") and error. Its failure is only reported.
+func runPostHook(arg, outPath string, failure error) (string, error) {
+	if postHook == "" {
+		return "", nil
+	}
+	env := map[string]string{
+		"WHISPER_LRC_INPUT":  arg,
+		"WHISPER_LRC_STATUS": "ok",
+	}
+	if outPath != "" {
+		env["WHISPER_LRC_OUTPUT"] = outputs.Location(outPath)
+	}
+	if failure != nil {
+		env["WHISPER_LRC_STATUS"] = "failed"
+		env["WHISPER_LRC_ERROR"] = failure.Error()
+	}
+	return hook.Run(postHook, env)
+}
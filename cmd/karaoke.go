@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BBleae/whisper-lrc/internal/audio"
+	"github.com/BBleae/whisper-lrc/internal/input"
+	"github.com/BBleae/whisper-lrc/internal/output"
+	"github.com/BBleae/whisper-lrc/internal/postprocess"
+	"github.com/BBleae/whisper-lrc/internal/storage"
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// karaokeManifest describes the files of a karaoke pack
+type karaokeManifest struct {
+	Title      string  `json:"title"`
+	Artist     string  `json:"artist"`
+	Audio      string  `json:"audio"`
+	Subtitles  string  `json:"subtitles"`
+	Duration   float64 `json:"duration"`
+	Resolution string  `json:"resolution"`
+	Command    string  `json:"command"`
+}
+
+// writeKaraokePack writes a directory with ASS karaoke subtitles, a copy of
+// the song audio and a manifest, and returns the command that renders the
+// video from inside it. Words without timing are estimated first.
+func writeKaraokePack(dir string, result *whisper.TranscriptionResult, audioPath, artist, title string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create karaoke pack: %w", err)
+	}
+
+	if err := storage.WriteFile(filepath.Join(dir, "lyrics.ass"), []byte(karaokeSubtitles(result, title)), 0644); err != nil {
+		return "", fmt.Errorf("failed to write karaoke subtitles: %w", err)
+	}
+
+	ext := filepath.Ext(audioPath)
+	if ext == "" {
+		ext = ".mp3"
+	}
+	audioName := "audio" + ext
+	if err := copyFile(audioPath, filepath.Join(dir, audioName)); err != nil {
+		return "", fmt.Errorf("failed to copy karaoke audio: %w", err)
+	}
+
+	video := strings.TrimSuffix(filepath.Base(dir), ".karaoke") + ".mp4"
+	command := fmt.Sprintf("ffmpeg -f lavfi -i color=c=black:s=%dx%d:r=30 -i %s -vf ass=lyrics.ass -map 0:v -map 1:a -shortest -c:v libx264 -pix_fmt yuv420p -c:a aac %s",
+		output.ASSWidth, output.ASSHeight, shellQuote(audioName), shellQuote(video))
+
+	data, err := json.MarshalIndent(karaokeManifest{
+		Title:      title,
+		Artist:     artist,
+		Audio:      audioName,
+		Subtitles:  "lyrics.ass",
+		Duration:   result.Duration,
+		Resolution: fmt.Sprintf("%dx%d", output.ASSWidth, output.ASSHeight),
+		Command:    command,
+	}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode karaoke manifest: %w", err)
+	}
+	if err := storage.WriteFile(filepath.Join(dir, "manifest.json"), append(data, '\n'), 0644); err != nil {
+		return "", fmt.Errorf("failed to write karaoke manifest: %w", err)
+	}
+
+	return fmt.Sprintf("cd %s && %s", shellQuote(dir), command), nil
+}
+
+// renderLyricVideo renders the lyrics over the background into a video. With
+// useCover, the cover art of the source replaces the background if it has any.
+func renderLyricVideo(result *whisper.TranscriptionResult, src *input.Source, title, outPath, background string, useCover bool) error {
+	if useCover {
+		if cover, remove, err := coverArt(src); err == nil {
+			defer remove()
+			background = cover
+		}
+	}
+
+	subtitles, err := os.CreateTemp("", "whisper-lrc-*.ass")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(subtitles.Name())
+
+	_, err = subtitles.WriteString(karaokeSubtitles(result, title))
+	if closeErr := subtitles.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write karaoke subtitles: %w", err)
+	}
+
+	return audio.RenderVideo(src.Path, subtitles.Name(), background, outPath, output.ASSWidth, output.ASSHeight)
+}
+
+// karaokeSubtitles renders ASS karaoke subtitles, estimating the timing of
+// words the backend did not time
+func karaokeSubtitles(result *whisper.TranscriptionResult, title string) string {
+	timed := *result
+	timed.Segments = append([]whisper.Segment(nil), result.Segments...)
+	postprocess.NewKaraokeEstimator().Process(&timed)
+
+	formatter := output.NewASSFormatter()
+	formatter.Title = title
+	return formatter.Format(&timed)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	part := dst + storage.PartSuffix
+	out, err := os.Create(part)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(part, dst)
+	}
+	if err != nil {
+		os.Remove(part)
+	}
+	return err
+}
+
+// shellQuote quotes s for POSIX shells when it contains special characters
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
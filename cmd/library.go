@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/BBleae/whisper-lrc/internal/library"
+	"github.com/BBleae/whisper-lrc/internal/notify"
+	"github.com/BBleae/whisper-lrc/internal/redact"
+	"github.com/BBleae/whisper-lrc/internal/tlsconfig"
+)
+
+// libraryRefreshers returns the media servers to refresh after the batch,
+// with their tokens from the environment
+func libraryRefreshers() ([]library.Refresher, error) {
+	if refreshJellyfin == "" && refreshPlex == "" {
+		return nil, nil
+	}
+	cfg, err := tlsconfig.Load(tlsOpts)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: 30 * time.Second, Transport: tlsconfig.Transport(cfg)}
+
+	var refreshers []library.Refresher
+	if refreshJellyfin != "" {
+		key := os.Getenv("JELLYFIN_API_KEY")
+		if key == "" {
+			return nil, fmt.Errorf("--refresh-jellyfin needs an API key in JELLYFIN_API_KEY")
+		}
+		redact.Register(key)
+		refreshers = append(refreshers, &library.Jellyfin{URL: refreshJellyfin, APIKey: key, Client: client})
+	}
+	if refreshPlex != "" {
+		token := os.Getenv("PLEX_TOKEN")
+		if token == "" {
+			return nil, fmt.Errorf("--refresh-plex needs a token in PLEX_TOKEN")
+		}
+		redact.Register(token)
+		refreshers = append(refreshers, &library.Plex{URL: refreshPlex, Token: token, Client: client})
+	}
+	return refreshers, nil
+}
+
+// refreshLibraries tells the media servers about the folders that received
+// lyrics. Failures are reported as warnings.
+func refreshLibraries(refreshers []library.Refresher, results []notify.Result, status io.Writer) {
+	seen := map[string]bool{}
+	var dirs []string
+	for _, r := range results {
+		if r.Output == "" {
+			continue
+		}
+		dir, err := filepath.Abs(filepath.Dir(r.Output))
+		if err == nil && !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	if len(dirs) == 0 {
+		return
+	}
+	sort.Strings(dirs)
+
+	refreshed := false
+	for _, refresher := range refreshers {
+		if err := refresher.Refresh(dirs); err != nil {
+			fmt.Fprintf(status, "Warning: %s\n", redact.String(err.Error()))
+			continue
+		}
+		refreshed = true
+	}
+	if refreshed {
+		fmt.Fprintf(status, "Library refresh requested for %d folder(s)\n", len(dirs))
+	}
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/BBleae/whisper-lrc/internal/lyrics"
+	"github.com/BBleae/whisper-lrc/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var lintFix bool
+
+var lintCmd = &cobra.Command{
+	Use:   "lint [files...]",
+	Short: "Validate LRC and SRT files",
+	Long: `Check existing LRC and SRT files for malformed or out-of-order timestamps,
+duplicate timestamps, encoding problems, unknown or misplaced LRC header tags,
+and misnumbered or overlapping SRT cues.
+
+With --fix, lines are sorted by time, lines sharing a timestamp are merged,
+headers are moved to the top, cues are renumbered and byte order marks are
+removed. Other problems are reported for manual repair.
+
+Examples:
+  whisper-lrc lint *.lrc
+  whisper-lrc lint --fix lyrics/*.lrc subtitles/*.srt`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runLint,
+}
+
+func init() {
+	lintCmd.Flags().BoolVar(&lintFix, "fix", false, "Rewrite files to fix the problems that can be fixed automatically")
+	rootCmd.AddCommand(lintCmd)
+}
+
+func runLint(cmd *cobra.Command, args []string) error {
+	failed := 0
+	for _, path := range args {
+		f, err := lyrics.Load(path)
+		if err != nil {
+			fmt.Printf("✗ %v\n", err)
+			failed++
+			continue
+		}
+		f.Lint()
+
+		if lintFix {
+			fixed, err := f.Fix()
+			if err != nil {
+				fmt.Printf("✗ %s: %v\n", path, err)
+			} else if fixed > 0 {
+				if err := storage.WriteFile(path, []byte(f.String()), 0644); err != nil {
+					fmt.Printf("✗ %s: failed to write: %v\n", path, err)
+					failed++
+					continue
+				}
+				fmt.Printf("✓ %s: fixed %d problem(s)\n", path, fixed)
+			}
+		}
+
+		for _, issue := range f.Issues {
+			fmt.Printf("%s: %s\n", path, issue)
+		}
+		if len(f.Issues) > 0 {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d file(s) have problems", failed, len(args))
+	}
+	fmt.Printf("%d file(s) OK\n", len(args))
+	return nil
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/BBleae/whisper-lrc/internal/audio"
+	"github.com/BBleae/whisper-lrc/internal/output"
+	"github.com/BBleae/whisper-lrc/internal/redact"
+	"github.com/spf13/cobra"
+)
+
+var (
+	liveDevice string
+	liveChunk  time.Duration
+	liveSave   string
+)
+
+var liveCmd = &cobra.Command{
+	Use:   "live [stream URL]",
+	Short: "Transcribe microphone input or an audio stream in real time",
+	Long: `Capture audio with ffmpeg and transcribe it in rolling chunks, printing
+timed lines as each chunk completes. Press Ctrl+C to stop.
+
+Without arguments the microphone is captured, using PulseAudio on Linux,
+AVFoundation on macOS and DirectShow on Windows (where --device is required).
+Given a URL, an internet radio (Icecast/Shoutcast) or HLS stream is captioned
+continuously instead.
+
+With --save, lines are appended to an LRC file, or to a WebVTT file when the
+path ends in .vtt.
+
+Examples:
+  whisper-lrc live
+  whisper-lrc live --chunk 5s -l en
+  whisper-lrc live --save rehearsal.lrc
+  whisper-lrc live --device "Microphone (USB Audio)"
+  whisper-lrc live https://radio.example.com/stream.mp3 --save radio.vtt`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runLive,
+}
+
+func init() {
+	liveCmd.Flags().StringVar(&liveDevice, "device", "", "Capture device (default: system default microphone)")
+	liveCmd.Flags().DurationVar(&liveChunk, "chunk", 10*time.Second, "Length of each transcribed chunk")
+	liveCmd.Flags().StringVar(&liveSave, "save", "", "Append timed lines to this LRC file (or WebVTT file if it ends in .vtt)")
+	rootCmd.AddCommand(liveCmd)
+}
+
+func runLive(cmd *cobra.Command, args []string) error {
+	key, err := resolveAPIKey()
+	if err != nil {
+		return err
+	}
+	if liveChunk < time.Second {
+		return fmt.Errorf("--chunk must be at least 1s")
+	}
+	client, err := newClient(key)
+	if err != nil {
+		return err
+	}
+	store, err := openHistory()
+	if err != nil {
+		return err
+	}
+
+	var inputArgs []string
+	if len(args) == 1 {
+		inputArgs = audio.StreamInput(args[0])
+	} else if inputArgs, err = audio.MicrophoneInput(liveDevice); err != nil {
+		return err
+	}
+
+	var save *os.File
+	saveVTT := strings.EqualFold(filepath.Ext(liveSave), ".vtt")
+	if liveSave != "" {
+		save, err = os.OpenFile(liveSave, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", liveSave, err)
+		}
+		defer save.Close()
+
+		// A new WebVTT file needs its header before the first cue
+		if info, err := save.Stat(); err == nil && info.Size() == 0 && saveVTT {
+			if _, err := save.WriteString("WEBVTT\n\n"); err != nil {
+				return fmt.Errorf("failed to write %s: %w", liveSave, err)
+			}
+		}
+	}
+
+	recorder, err := audio.StartRecorder(inputArgs, liveChunk)
+	if err != nil {
+		return err
+	}
+	defer recorder.Close()
+
+	// Stop recording on Ctrl+C; chunks already recorded are still transcribed
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		if _, ok := <-signals; ok {
+			recorder.Stop()
+		}
+	}()
+
+	stderr := redact.Writer(os.Stderr)
+	fmt.Fprintln(stderr, "Listening... press Ctrl+C to stop")
+	for chunk := range recorder.Chunks() {
+		started := time.Now()
+		result, err := client.Transcribe(chunk.Path, language, effectivePrompt())
+		os.Remove(chunk.Path)
+		billed := 0.0
+		if err == nil {
+			billed = result.Duration
+		}
+		if err := recordUsage(store, billed, time.Since(started), err != nil); err != nil && verbose {
+			fmt.Fprintf(stderr, "Warning: %v\n", err)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "✗ chunk %d: %v\n", chunk.Index+1, err)
+			continue
+		}
+
+		for _, seg := range result.Segments {
+			if strings.TrimSpace(seg.Text) == "" {
+				continue
+			}
+			seg.Start += chunk.Offset.Seconds()
+			seg.End += chunk.Offset.Seconds()
+			line := output.FormatLRCLine(seg)
+			fmt.Print(line)
+			if save != nil {
+				if saveVTT {
+					line = output.FormatVTTCue(seg)
+				}
+				if _, err := save.WriteString(line); err != nil {
+					return fmt.Errorf("failed to write %s: %w", liveSave, err)
+				}
+			}
+		}
+	}
+
+	return recorder.Err()
+}
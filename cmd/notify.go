@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BBleae/whisper-lrc/internal/notify"
+	"github.com/BBleae/whisper-lrc/internal/redact"
+	"github.com/BBleae/whisper-lrc/internal/tlsconfig"
+)
+
+// maxAttachment is the largest output attached to the completion email
+const maxAttachment = 100 << 10
+
+// wantAttachments reports whether outputs are kept for the completion email
+func wantAttachments() bool {
+	return len(emailTo) > 0 && emailAttach
+}
+
+// addAttachment keeps a small output for the completion email under a name
+// not used yet
+func addAttachment(attachments map[string]string, path, content string) {
+	if len(content) > maxAttachment {
+		return
+	}
+	name := filepath.Base(path)
+	ext := filepath.Ext(name)
+	for i := 2; attachments[name] != ""; i++ {
+		name = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(filepath.Base(path), ext), i, ext)
+	}
+	attachments[name] = content
+}
+
+// parseWebhooks checks the --notify-webhook URLs and registers them for
+// redaction, as they carry the channel's secret
+func parseWebhooks() ([]notify.Webhook, error) {
+	var hooks []notify.Webhook
+	for _, raw := range notifyHooks {
+		hook, err := notify.ParseWebhook(raw)
+		if err != nil {
+			return nil, fmt.Errorf("--notify-webhook: %w", err)
+		}
+		redact.Register(hook.URL)
+		hooks = append(hooks, hook)
+	}
+	return hooks, nil
+}
+
+// notifyCompletion sends the batch summary to the configured destinations.
+// Failures are reported as warnings and do not fail the run.
+func notifyCompletion(summary *notify.Summary, hooks []notify.Webhook, attachments map[string]string, status io.Writer) {
+	if len(hooks) > 0 {
+		cfg, err := tlsconfig.Load(tlsOpts)
+		if err != nil {
+			fmt.Fprintf(status, "Warning: %v\n", err)
+			return
+		}
+		client := &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: tlsconfig.Transport(cfg),
+		}
+		for _, hook := range hooks {
+			if err := notify.SendWebhook(client, hook, summary); err != nil {
+				fmt.Fprintf(status, "Warning: %s\n", redact.String(err.Error()))
+			}
+		}
+	}
+	if len(emailTo) > 0 {
+		sendEmail(summary, attachments, status)
+	}
+}
+
+// sendEmail mails the batch summary for --email-on-complete
+func sendEmail(summary *notify.Summary, attachments map[string]string, status io.Writer) {
+
+	from := emailFrom
+	if from == "" {
+		from = smtpUser
+	}
+	if from == "" {
+		from = "whisper-lrc@" + summary.Host
+	}
+	password := os.Getenv("SMTP_PASSWORD")
+	redact.Register(password)
+
+	cfg := notify.SMTP{
+		Addr:     smtpServer,
+		Username: smtpUser,
+		Password: password,
+		From:     from,
+		To:       emailTo,
+	}
+	if err := notify.SendEmail(cfg, summary, attachments); err != nil {
+		fmt.Fprintf(status, "Warning: %v\n", err)
+		return
+	}
+	fmt.Fprintf(status, "Summary emailed to %s\n", strings.Join(emailTo, ", "))
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// phaseTimes is where the time for one input went, logged with -v to show
+// what slows a batch down
+type phaseTimes struct {
+	download, preprocess, upload, wait, postprocess, write time.Duration
+}
+
+// addRequests adds the time of the API requests behind the results; a
+// translation is nil unless it was made separately
+func (p *phaseTimes) addRequests(results ...*whisper.TranscriptionResult) {
+	for _, result := range results {
+		if result != nil {
+			p.upload += result.Timing.Upload
+			p.wait += result.Timing.Wait
+		}
+	}
+}
+
+func (p phaseTimes) String() string {
+	phases := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"download", p.download},
+		{"preprocess", p.preprocess},
+		{"upload", p.upload},
+		{"API wait", p.wait},
+		{"post-process", p.postprocess},
+		{"write", p.write},
+	}
+	parts := make([]string, len(phases))
+	for i, phase := range phases {
+		parts[i] = fmt.Sprintf("%s %.1f s", phase.name, phase.d.Seconds())
+	}
+	return strings.Join(parts, ", ")
+}
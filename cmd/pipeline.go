@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/BBleae/whisper-lrc/internal/config"
+	"github.com/BBleae/whisper-lrc/internal/output"
+	"github.com/BBleae/whisper-lrc/internal/postprocess"
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// stages are the post-processing pipeline and formatter of one worker. The
+// onset snapper, --post-process commands, reading speed check and UltraStar
+// formatter hold the state of the file being processed, so files in
+// progress at the same time each need their own.
+type stages struct {
+	pipeline postprocess.Pipeline
+	// stream is the part of the pipeline that --stream applies to each
+	// part of a transcription as it arrives
+	stream       postprocess.Pipeline
+	plugins      []*postprocess.Exec
+	snapper      *postprocess.OnsetSnapper
+	readingSpeed *postprocess.ReadingSpeed
+	formatter    output.Formatter
+	ultraStar    *output.UltraStarFormatter
+}
+
+// newStages builds the stages selected by flags around a shared wordlist
+func newStages(wordlist *postprocess.Wordlist) (*stages, error) {
+	st := &stages{}
+	if snapOnsets > 0 {
+		st.snapper = postprocess.NewOnsetSnapper(snapOnsets)
+	}
+	for _, command := range postProcess {
+		st.plugins = append(st.plugins, postprocess.NewExec(command))
+	}
+	var err error
+	st.pipeline, st.readingSpeed, err = buildPipeline(wordlist, st.snapper, st.plugins)
+	if err != nil {
+		return nil, err
+	}
+	if stream {
+		if st.stream, err = streamPipeline(wordlist); err != nil {
+			return nil, err
+		}
+	}
+	st.formatter, st.ultraStar = newFormatter()
+	return st, nil
+}
+
+// newFormatter returns the formatter for --format, and the same formatter as
+// an UltraStar formatter when that is the format
+func newFormatter() (formatter output.Formatter, ultraStar *output.UltraStarFormatter) {
+	switch outputFormat {
+	case "lrc", "elrc":
+		lrcFormatter := output.NewLRCFormatter()
+		lrcFormatter.MarkSections = sections
+		lrcFormatter.MarkLanguages = markLanguages
+		lrcFormatter.WordTimes = karaoke || outputFormat == "elrc"
+		if offsetTag {
+			// A positive tag shows lyrics earlier, the opposite of --offset
+			lrcFormatter.Offset = -int(timeOffset.Milliseconds())
+		}
+		formatter = lrcFormatter
+	case "vtt":
+		vttFormatter := output.NewVTTFormatter()
+		vttFormatter.Line = vttLine
+		vttFormatter.Position = vttPosition
+		vttFormatter.Align = vttAlign
+		vttFormatter.Note = vttNote
+		vttFormatter.MarkLanguages = markLanguages
+		formatter = vttFormatter
+	case "jsonl":
+		formatter = output.NewJSONLFormatter()
+	case "ultrastar":
+		ultraStar = output.NewUltraStarFormatter()
+		formatter = ultraStar
+	default:
+		srtFormatter := output.NewSRTFormatter()
+		srtFormatter.MarkLanguages = markLanguages
+		formatter = srtFormatter
+	}
+
+	return formatter, ultraStar
+}
+
+// buildPipeline assembles the post-processing stages selected by flags.
+// Missing timestamps are always repaired first; text rewriting runs before
+// timing adjustments so that later stages see the final text. The reading
+// speed stage is returned separately (nil if disabled) so callers can report
+// cues that could not be brought under the limit. The onset snapper (nil if
+// disabled) and the --post-process commands are supplied by the caller, which
+// sets the onsets and input of each file.
+func buildPipeline(wordlist *postprocess.Wordlist, snapper *postprocess.OnsetSnapper, plugins []*postprocess.Exec) (postprocess.Pipeline, *postprocess.ReadingSpeed, error) {
+	pipeline := postprocess.Pipeline{postprocess.NewGapFiller()}
+
+	if snapper != nil {
+		pipeline = append(pipeline, snapper)
+	}
+	text, err := textStages(wordlist)
+	if err != nil {
+		return nil, nil, err
+	}
+	pipeline = append(pipeline, text...)
+	// User commands run after the text stages and before word and cue timing
+	for _, plugin := range plugins {
+		pipeline = append(pipeline, plugin)
+	}
+
+	// Long LRC lines are split before word timing is estimated for each line
+	if lrcOutput() && maxLineLength > 0 {
+		pipeline = append(pipeline, &postprocess.LineSplitter{MaxChars: maxLineLength})
+	}
+
+	// Word timing is estimated from the final text where the API sent none
+	// or it no longer spells the text (LRC and UltraStar only, so no cue
+	// timing stages follow)
+	if karaoke || outputFormat == "elrc" || outputFormat == "ultrastar" {
+		pipeline = append(pipeline, postprocess.NewKaraokeEstimator())
+	}
+
+	// A global offset moves the final times, unless it is written as an LRC
+	// tag instead
+	if timeOffset != 0 && !offsetTag {
+		pipeline = append(pipeline, &postprocess.Shift{Offset: timeOffset})
+	}
+
+	var readingSpeed *postprocess.ReadingSpeed
+	if outputFormat == "srt" || outputFormat == "vtt" {
+		if minDuration > 0 || maxDuration > 0 || minGap > 0 {
+			pipeline = append(pipeline, &postprocess.CueTiming{
+				MinDuration: minDuration,
+				MaxDuration: maxDuration,
+				MinGap:      minGap,
+			})
+		}
+		if maxCPS > 0 {
+			readingSpeed = &postprocess.ReadingSpeed{MaxCPS: maxCPS, MinGap: minGap}
+			pipeline = append(pipeline, readingSpeed)
+		}
+	}
+
+	return pipeline, readingSpeed, nil
+}
+
+// streamPipeline returns the stages --stream applies to each part of a
+// transcription before writing it: the text stages, which work on one
+// segment at a time, and --offset. The rest need the whole transcription
+// and only apply to the output file: filling missing timestamps and onset
+// snapping (from neighboring segments and the whole audio), --refine,
+// --post-process commands, line splitting, word timing and cue timing.
+func streamPipeline(wordlist *postprocess.Wordlist) (postprocess.Pipeline, error) {
+	pipeline, err := textStages(wordlist)
+	if err != nil {
+		return nil, err
+	}
+	if timeOffset != 0 && !offsetTag {
+		pipeline = append(pipeline, &postprocess.Shift{Offset: timeOffset})
+	}
+	return pipeline, nil
+}
+
+// textStages returns the stages that rewrite or drop the text of segments
+func textStages(wordlist *postprocess.Wordlist) (postprocess.Pipeline, error) {
+	var pipeline postprocess.Pipeline
+	if len(onlyLanguages) > 0 {
+		pipeline = append(pipeline, postprocess.NewLanguageFilter(onlyLanguages))
+	}
+	if len(sanitize) > 0 {
+		sanitizer, err := postprocess.NewSanitizer(sanitize)
+		if err != nil {
+			return nil, err
+		}
+		pipeline = append(pipeline, sanitizer)
+	}
+	if len(normalize) > 0 {
+		normalizer, err := postprocess.NewNormalizer(normalize)
+		if err != nil {
+			return nil, err
+		}
+		pipeline = append(pipeline, normalizer)
+	}
+	if chineseVar != "" {
+		converter, err := postprocess.NewChineseConverter(chineseVar)
+		if err != nil {
+			return nil, err
+		}
+		pipeline = append(pipeline, converter)
+	}
+	if casing != postprocess.CaseKeep {
+		casingStage, err := postprocess.NewCasing(casing)
+		if err != nil {
+			return nil, err
+		}
+		pipeline = append(pipeline, casingStage)
+	}
+	if stripTrailing {
+		pipeline = append(pipeline, postprocess.NewTrailingPunctuation())
+	}
+	// User replacements see the text the other stages produced, so they are
+	// written as they should appear. The corrections file applies first so
+	// that --replace can adjust its results for one run.
+	dictionary, err := loadCorrections()
+	if err != nil {
+		return nil, err
+	}
+	if dictionary != nil {
+		pipeline = append(pipeline, dictionary)
+	}
+	if len(replacements) > 0 {
+		replacer, err := postprocess.NewReplacer(replacements)
+		if err != nil {
+			return nil, err
+		}
+		pipeline = append(pipeline, replacer)
+	}
+	if censor || len(censorLists) > 0 {
+		pipeline = append(pipeline, postprocess.NewCensor(wordlist))
+	}
+	return pipeline, nil
+}
+
+// runPipeline post-processes the result of an input and returns the first
+// failure of the --post-process commands
+func runPipeline(pipeline postprocess.Pipeline, plugins []*postprocess.Exec, arg string, result *whisper.TranscriptionResult) error {
+	for _, plugin := range plugins {
+		plugin.Input = arg
+	}
+	pipeline.Process(result)
+	for _, plugin := range plugins {
+		if plugin.Err != nil {
+			return plugin.Err
+		}
+	}
+	return nil
+}
+
+// loadCorrections reads the --corrections file, or corrections.txt in the
+// config directory when it exists. It returns nil with --no-corrections or
+// when there is no default file.
+func loadCorrections() (*postprocess.Replacer, error) {
+	if noCorrections {
+		return nil, nil
+	}
+	if corrections != "" {
+		return postprocess.LoadReplacer(corrections)
+	}
+	path, err := config.CorrectionsPath()
+	if err != nil {
+		return nil, nil
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+	return postprocess.LoadReplacer(path)
+}
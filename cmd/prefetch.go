@@ -0,0 +1,54 @@
+package cmd
+
+import "github.com/BBleae/whisper-lrc/internal/input"
+
+// prefetcher resolves inputs in the background so downloads overlap with
+// the transcription of earlier files. At most depth inputs are held resolved
+// ahead of the one being processed.
+type prefetcher struct {
+	results chan resolvedInput
+	done    chan struct{}
+}
+
+// prefetch starts resolving args in order
+func prefetch(handler *input.Handler, args []string, depth int) *prefetcher {
+	// The goroutine holds one more result while it waits to hand it over
+	p := &prefetcher{
+		results: make(chan resolvedInput, depth-1),
+		done:    make(chan struct{}),
+	}
+	go func() {
+		defer close(p.results)
+		for _, arg := range args {
+			r := resolveInput(handler, arg)
+			select {
+			case p.results <- r:
+			case <-p.done:
+				if r.src != nil {
+					r.src.Cleanup()
+				}
+				return
+			}
+		}
+	}()
+	return p
+}
+
+// next returns the next resolved input, waiting for its download if needed
+func (p *prefetcher) next() resolvedInput {
+	return <-p.results
+}
+
+// stop abandons the remaining inputs and removes the temporary files of those
+// already resolved. A download in progress is allowed to finish first.
+func (p *prefetcher) stop() {
+	if p == nil {
+		return
+	}
+	close(p.done)
+	for r := range p.results {
+		if r.src != nil {
+			r.src.Cleanup()
+		}
+	}
+}
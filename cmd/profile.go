@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/BBleae/whisper-lrc/internal/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// applyProfile sets the flags of cmd that were not given on the command line
+// or in the environment from the --profile section of the config file.
+// Settings for flags that only other commands have are skipped.
+func applyProfile(cmd *cobra.Command) error {
+	if profileName == "" {
+		return nil
+	}
+	path := configPath
+	if path == "" {
+		var err error
+		if path, err = config.DefaultPath(); err != nil {
+			return err
+		}
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+	profile, ok := cfg.Profiles[profileName]
+	if !ok {
+		return fmt.Errorf("profile %q not found in %s (available: %s)", profileName, path, strings.Join(cfg.Names(), ", "))
+	}
+
+	for _, name := range profile.Flags() {
+		if name == "profile" || name == "config" {
+			return fmt.Errorf("profile %q: profiles cannot set --%s", profileName, name)
+		}
+		f := cmd.Flags().Lookup(name)
+		if f == nil {
+			if !anyCommandHasFlag(name) {
+				return fmt.Errorf("profile %q: unknown flag --%s", profileName, name)
+			}
+			continue
+		}
+		if f.Changed {
+			continue
+		}
+		values, err := profile.Values(name)
+		if err != nil {
+			return fmt.Errorf("profile %q: %w", profileName, err)
+		}
+		// List flags replace their default with the first value and
+		// append the rest
+		for _, value := range values {
+			if err := f.Value.Set(value); err != nil {
+				return fmt.Errorf("profile %q: invalid --%s: %w", profileName, name, err)
+			}
+		}
+		f.Changed = true
+	}
+	return nil
+}
+
+// anyCommandHasFlag reports whether the root command or a subcommand
+// defines a flag
+func anyCommandHasFlag(name string) bool {
+	found := false
+	var visit func(c *cobra.Command)
+	visit = func(c *cobra.Command) {
+		c.Flags().VisitAll(func(f *pflag.Flag) {
+			if f.Name == name {
+				found = true
+			}
+		})
+		for _, sub := range c.Commands() {
+			visit(sub)
+		}
+	}
+	visit(rootCmd)
+	return found
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/BBleae/whisper-lrc/internal/audio"
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// refinePadding is the audio around a line that is sent along with it, so
+// that words at its edges are not cut
+const refinePadding = time.Second
+
+// refineContext is the number of preceding lines passed as the prompt of a
+// refined line, so that it continues the lyrics the way Whisper continues
+// its own transcription
+const refineContext = 2
+
+// refineLines re-transcribes the lines of result whose confidence is below
+// --refine-below from clips of the audio, with the language fixed and the
+// preceding lines as context. The clips are sent with client, which uses
+// --refine-model when it is set. A line is replaced by the lines transcribed
+// from its clip when those are more confident, and the text of the result
+// is rebuilt from the lines. It returns the seconds of audio billed and the
+// number of lines replaced; an error stops refining and leaves the remaining
+// lines as they are.
+func refineLines(client *whisper.Client, result *whisper.TranscriptionResult, audioPath string, status func(string)) (billed float64, refined int, err error) {
+	var low []int
+	for i, seg := range result.Segments {
+		if seg.AvgLogprob < refineBelow {
+			low = append(low, i)
+		}
+	}
+
+	replacements := make(map[int][]whisper.Segment)
+	defer func() {
+		if refined == 0 {
+			return
+		}
+		var segments []whisper.Segment
+		var texts []string
+		for i, seg := range result.Segments {
+			lines := []whisper.Segment{seg}
+			if better, ok := replacements[i]; ok {
+				lines = better
+			}
+			for _, line := range lines {
+				segments = append(segments, line)
+				texts = append(texts, strings.TrimSpace(line.Text))
+			}
+		}
+		result.Segments = segments
+		result.Text = strings.Join(texts, " ")
+	}()
+
+	for n, i := range low {
+		status(fmt.Sprintf("Refining line %d of %d...", n+1, len(low)))
+		seg := result.Segments[i]
+		start := max(seg.Start-refinePadding.Seconds(), 0)
+		end := seg.End + refinePadding.Seconds()
+		clip, err := audio.Clip(audioPath, toDuration(start), toDuration(end-start))
+		if err != nil {
+			return billed, refined, err
+		}
+		retry, err := client.TranscribeAudio(whisper.Audio{Path: clip}, result.LanguageOf(seg), refinePrompt(result.Segments[:i]))
+		os.Remove(clip)
+		if err != nil {
+			return billed, refined, err
+		}
+		billed += retry.Duration
+		result.Timing.Add(retry.Timing)
+		if better := moreConfident(seg, retry, start); better != nil {
+			replacements[i] = better
+			refined++
+		}
+	}
+	return billed, refined, nil
+}
+
+// refinePrompt returns the prompt for a line: the usual prompt followed by
+// the lines before it
+func refinePrompt(before []whisper.Segment) string {
+	lines := []string{effectivePrompt()}
+	for _, seg := range before[max(len(before)-refineContext, 0):] {
+		lines = append(lines, strings.TrimSpace(seg.Text))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// moreConfident returns the segments of a clip transcription that fall
+// within seg, moved to its timeline and kept within its bounds, when their
+// average confidence weighted by length beats that of seg. It returns nil
+// when the clip held no text for seg or was no better.
+func moreConfident(seg whisper.Segment, retry *whisper.TranscriptionResult, offset float64) []whisper.Segment {
+	var kept []whisper.Segment
+	var logprob, length float64
+	for _, s := range retry.Segments {
+		s.Start, s.End = s.Start+offset, s.End+offset
+		if middle := (s.Start + s.End) / 2; middle < seg.Start || middle > seg.End || strings.TrimSpace(s.Text) == "" {
+			continue
+		}
+		s.Start, s.End = max(s.Start, seg.Start), min(s.End, seg.End)
+		if s.Words != nil {
+			words := make([]whisper.Word, len(s.Words))
+			for j, w := range s.Words {
+				w.Start, w.End = w.Start+offset, w.End+offset
+				words[j] = w
+			}
+			s.Words = words
+		}
+		if s.Language == "" {
+			s.Language = seg.Language
+		}
+		logprob += s.AvgLogprob * (s.End - s.Start)
+		length += s.End - s.Start
+		kept = append(kept, s)
+	}
+	if len(kept) == 0 || length <= 0 || logprob/length <= seg.AvgLogprob {
+		return nil
+	}
+	return kept
+}
+
+// toDuration converts seconds to a duration
+func toDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -1,28 +1,149 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
+	"github.com/BBleae/whisper-lrc/internal/audio"
+	"github.com/BBleae/whisper-lrc/internal/filename"
 	"github.com/BBleae/whisper-lrc/internal/input"
+	"github.com/BBleae/whisper-lrc/internal/melody"
+	"github.com/BBleae/whisper-lrc/internal/metrics"
+	"github.com/BBleae/whisper-lrc/internal/notify"
 	"github.com/BBleae/whisper-lrc/internal/output"
+	"github.com/BBleae/whisper-lrc/internal/postprocess"
 	"github.com/BBleae/whisper-lrc/internal/progress"
+	"github.com/BBleae/whisper-lrc/internal/redact"
+	"github.com/BBleae/whisper-lrc/internal/report"
+	"github.com/BBleae/whisper-lrc/internal/storage"
+	"github.com/BBleae/whisper-lrc/internal/structure"
+	"github.com/BBleae/whisper-lrc/internal/summary"
+	"github.com/BBleae/whisper-lrc/internal/tlsconfig"
+	"github.com/BBleae/whisper-lrc/internal/translate"
 	"github.com/BBleae/whisper-lrc/internal/whisper"
 	"github.com/spf13/cobra"
 )
 
 var (
-	outputFormat string
-	outputDir    string
-	language     string
-	apiKey       string
-	prompt       string
-	useYtDlp     bool
-	verbose      bool
+	outputFormat    string
+	outputDir       string
+	langSuffix      bool
+	language        string
+	apiKey          string
+	apiBase         string
+	provider        string
+	model           string
+	azureDeployment string
+	azureVersion    string
+	prompt          string
+	useYtDlp        bool
+	assumeURL       bool
+	noYtDlpFallback bool
+	verbose         bool
+	normalize       []string
+	sanitize        []string
+	casing          string
+	stripTrailing   bool
+	replacements    []string
+	corrections     string
+	noCorrections   bool
+	chineseVar      string
+	censor          bool
+	censorLists     []string
+	postProcess     []string
+	preHook         string
+	postHook        string
+	refreshJellyfin string
+	refreshPlex     string
+	explicit        bool
+	sections        bool
+	structureOut    bool
+	dumpRaw         string
+	minDuration     time.Duration
+	maxDuration     time.Duration
+	minGap          time.Duration
+	maxCPS          float64
+	maxLineLength   int
+	timeOffset      time.Duration
+	offsetTag       bool
+	vttLine         string
+	vttPosition     string
+	vttAlign        string
+	vttNote         string
+	stream          bool
+	summaryFormat   string
+	reportPath      string
+	limitRate       string
+	skipUnchanged   bool
+	downloadCache   string
+	ifMissing       bool
+	syncManifest    string
+	tlsOpts         tlsconfig.Options
+	preview         time.Duration
+	confirm         bool
+	assumeYes       bool
+	maxAudioLength  time.Duration
+	budgetLimit     string
+	budgetPeriod    string
+	noHistory       bool
+	configPath      string
+	profileName     string
+	emailTo         []string
+	notifyHooks     []string
+	emailFrom       string
+	emailAttach     bool
+	smtpServer      string
+	smtpUser        string
+	archiveOutput   bool
+	memoryLimit     string
+	compressUploads bool
+	keepAlive       time.Duration
+	noHTTP2         bool
+	prefetchDepth   int
+	concurrency     int
+	chunkMin        time.Duration
+	chunkMax        time.Duration
+	schedule        string
+	recordMetrics   bool
+	embedLyrics     bool
+	markLanguages   bool
+	onlyLanguages   []string
+	translateAll    bool
+	translateIf     []string
+	alsoTranslate   bool
+	translateTo     string
+	translateModel  string
+	karaoke         bool
+	snapOnsets      time.Duration
+	refine          bool
+	refineBelow     float64
+	refineModel     string
+	denoise         bool
+	denoiseModel    string
+	channel         string
+	melodyOut       bool
+	karaokePack     bool
+	renderVideo     string
+	videoBackground string
+	coverOut        bool
+	errorReport     string
 )
 
+// outputs is where output files are written; names are paths from
+// getOutputPath
+var outputs storage.Storage = storage.Local{}
+
 var rootCmd = &cobra.Command{
 	Use:   "whisper-lrc [files or URLs...]",
 	Short: "Extract lyrics from audio files using OpenAI Whisper",
@@ -36,6 +157,11 @@ Supported inputs:
 Supported output formats:
   - LRC (synchronized lyrics format)
   - SRT (subtitle format)
+  - VTT (WebVTT subtitle format)
+  - JSONL (one JSON object per segment)
+
+Every flag can also be set with a WHISPER_LRC_ environment variable, such as
+WHISPER_LRC_FORMAT=srt for --format; the command line takes precedence.
 
 Examples:
   whisper-lrc song.mp3
@@ -48,130 +174,1294 @@ Examples:
 }
 
 func Execute() {
+	// Errors may quote API responses; keep keys out of the terminal
+	rootCmd.SetErr(redact.Writer(os.Stderr))
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
 	}
 }
 
 func init() {
-	rootCmd.Flags().StringVarP(&outputFormat, "format", "f", "lrc", "Output format: lrc or srt")
-	rootCmd.Flags().StringVarP(&outputDir, "output", "o", "", "Output directory (default: same as input)")
-	rootCmd.Flags().StringVarP(&language, "language", "l", "", "Language code (e.g., en, zh, ja). Auto-detect if not specified")
-	rootCmd.Flags().StringVar(&apiKey, "api-key", "", "OpenAI API key (or set OPENAI_API_KEY env)")
-	rootCmd.Flags().StringVarP(&prompt, "prompt", "p", "", "Custom prompt for Whisper (overrides default anti-hallucination prompt)")
+	rootCmd.Flags().StringVarP(&outputFormat, "format", "f", "lrc", "Output format: lrc, elrc (enhanced LRC with word timing), srt, vtt, jsonl or ultrastar (UltraStar Deluxe .txt)")
+	rootCmd.Flags().StringVarP(&outputDir, "output", "o", "", "Output directory, or an s3://bucket/prefix, webdav(s)://host/path or sftp://user@host/path URL (default: same as input)")
+	rootCmd.Flags().BoolVar(&langSuffix, "lang-suffix", false, "Add the given or detected language code to output names, e.g. song.ja.lrc")
+	rootCmd.PersistentFlags().StringVarP(&language, "language", "l", "", "Language code (e.g., en, zh, ja). Auto-detect if not specified")
+	rootCmd.PersistentFlags().StringVar(&apiKey, "api-key", "", "OpenAI API key (or set OPENAI_API_KEY env)")
+	rootCmd.PersistentFlags().StringVar(&apiBase, "api-base", "", "Base URL of an OpenAI-compatible API, e.g. http://localhost:8000/v1 or unix:///run/whisper.sock (or set OPENAI_BASE_URL env)")
+	rootCmd.PersistentFlags().StringVar(&provider, "provider", "openai", "API provider: openai (or any compatible server), azure (Azure OpenAI; --api-base is the resource endpoint) or groq")
+	rootCmd.PersistentFlags().StringVar(&model, "model", "", "Transcription model (default: whisper-1, or whisper-large-v3 with --provider groq)")
+	rootCmd.PersistentFlags().StringVar(&azureDeployment, "azure-deployment", "", "Azure OpenAI deployment of the Whisper model")
+	rootCmd.PersistentFlags().StringVar(&azureVersion, "azure-api-version", whisper.DefaultAzureVersion, "Azure OpenAI API version")
+	rootCmd.PersistentFlags().BoolVar(&compressUploads, "compress-uploads", false, "Gzip audio uploads for servers that accept compressed request bodies (falls back to plain uploads if refused)")
+	rootCmd.PersistentFlags().DurationVar(&keepAlive, "keep-alive", whisper.DefaultIdleTimeout, "How long to keep an idle API connection open for the next file (0 to reconnect for every request)")
+	rootCmd.PersistentFlags().BoolVar(&noHTTP2, "no-http2", false, "Use HTTP/1.1 for the API even if the server supports HTTP/2")
+	rootCmd.PersistentFlags().StringVarP(&prompt, "prompt", "p", "", "Custom prompt for Whisper (overrides default anti-hallucination prompt)")
+	rootCmd.Flags().BoolVar(&assumeURL, "assume-url", false, "Download host/path inputs without a scheme, such as example.com/song.mp3, over https when no such local file exists")
 	rootCmd.Flags().BoolVar(&useYtDlp, "yt-dlp", false, "Use yt-dlp for YouTube/video URLs")
-	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
+	rootCmd.Flags().BoolVar(&noYtDlpFallback, "no-yt-dlp-fallback", false, "Fail URLs that return a web page or other non-audio content instead of retrying them with yt-dlp (when installed)")
+	rootCmd.Flags().BoolVar(&skipUnchanged, "skip-unchanged", false, "Cache URL downloads and skip inputs whose remote audio is unchanged (ETag/Last-Modified) and already has output")
+	rootCmd.Flags().StringVar(&downloadCache, "download-cache", "", "Keep downloaded URL audio in this directory between runs, so the same URL is not downloaded again (also used by --skip-unchanged)")
+	rootCmd.Flags().StringVar(&syncManifest, "sync", "", "Record the audio hash of each input in this manifest file and only process inputs that are new or whose audio changed since the last run")
+	rootCmd.Flags().BoolVar(&ifMissing, "if-missing", false, "Skip inputs that already have lyrics: the output file, an .lrc file next to the audio, or lyrics in its tags (USLT/SYLT in MP3, LYRICS in FLAC/Ogg/M4A)")
+	rootCmd.Flags().StringVar(&memoryLimit, "max-memory-download", "10M", "Keep direct downloads up to this size in memory instead of a temp file when no step needs the file on disk (0 to always use a temp file)")
+	rootCmd.Flags().StringVar(&limitRate, "limit-rate", "", "Maximum download rate in bytes per second, e.g. 500K or 2M (direct downloads and yt-dlp)")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Apply a named profile of flag settings from the config file (flags on the command line take precedence)")
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Config file with --profile settings (default: whisper-lrc/config.json in the user config directory)")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
+	rootCmd.PersistentFlags().BoolVar(&tlsOpts.InsecureSkipVerify, "insecure-skip-verify", false, "Skip TLS certificate verification for downloads and the API (unsafe)")
+	rootCmd.PersistentFlags().StringVar(&tlsOpts.CACert, "ca-cert", "", "PEM file with extra CA certificates to trust for downloads and the API")
+	rootCmd.PersistentFlags().StringVar(&tlsOpts.ClientCert, "client-cert", "", "PEM client certificate for mutual TLS (requires --client-key)")
+	rootCmd.PersistentFlags().StringVar(&tlsOpts.ClientKey, "client-key", "", "PEM private key for --client-cert")
+	rootCmd.Flags().StringSliceVar(&normalize, "normalize", nil, "Text normalization rules: auto, width, quotes (auto picks rules by detected language)")
+	rootCmd.Flags().StringVar(&casing, "casing", "keep", "Letter case of lyrics: keep, sentence (capitalize the first letter of each line, lowercase the rest) or lower")
+	rootCmd.Flags().BoolVar(&stripTrailing, "strip-trailing-punctuation", false, "Remove periods, commas and similar marks from the end of each line, as lyric sheets do (? and ! are kept)")
+	rootCmd.Flags().StringArrayVar(&replacements, "replace", nil, "Rewrite line text with a regular expression, as 'pattern=>replacement' or 'ja:pattern=>replacement' for one language (repeatable)")
+	rootCmd.Flags().StringVar(&corrections, "corrections", "", "File of --replace rules applied to every transcription (default: whisper-lrc/corrections.txt in the user config directory, if it exists)")
+	rootCmd.Flags().BoolVar(&noCorrections, "no-corrections", false, "Do not apply the corrections file")
+	rootCmd.Flags().StringSliceVar(&sanitize, "sanitize", nil, "Remove transcription artifacts: annotations ([Music], (applause), ♪), whitespace, punctuation (stray leading/trailing marks), or all")
+	rootCmd.Flags().StringVar(&chineseVar, "chinese-variant", "", "Convert Chinese lyrics to a script: s2t (simplified to traditional) or t2s")
+	rootCmd.Flags().BoolVar(&censor, "censor", false, "Mask profanity in the output")
+	rootCmd.Flags().StringArrayVar(&censorLists, "censor-list", nil, "Additional profanity wordlist file, optionally per language (e.g. ja:words.txt); implies --censor")
+	rootCmd.Flags().StringArrayVar(&postProcess, "post-process", nil, "Pipe the transcription as JSON through this command before formatting; it prints the changed JSON (repeatable, runs in order)")
+	rootCmd.Flags().StringVar(&preHook, "pre-hook", "", "Run this shell command before each file is transcribed (WHISPER_LRC_INPUT, _OUTPUT and _AUDIO are set); the file is skipped if it fails")
+	rootCmd.Flags().StringVar(&postHook, "post-hook", "", "Run this shell command after each file (WHISPER_LRC_INPUT, _OUTPUT, _STATUS=ok or failed, and _ERROR are set)")
+	rootCmd.Flags().BoolVar(&explicit, "detect-explicit", false, "Report files whose lyrics contain explicit content (uses the --censor wordlists)")
+	rootCmd.Flags().BoolVar(&sections, "sections", false, "Annotate LRC output with verse/chorus/bridge comment markers")
+	rootCmd.Flags().BoolVar(&translateAll, "translate", false, "Translate the lyrics (to English with the Whisper translation endpoint unless --translate-to is set)")
+	rootCmd.Flags().StringSliceVar(&translateIf, "translate-if", nil, "Translate only when the detected language is one of these (e.g. ja,ko)")
+	rootCmd.Flags().BoolVar(&melodyOut, "melody", false, "Also write the vocal pitch of each line as <name>.melody.json, for karaoke scoring (requires ffmpeg)")
+	rootCmd.Flags().BoolVar(&karaokePack, "karaoke-pack", false, "Also write <name>.karaoke/ with ASS karaoke subtitles, the audio and a manifest, and print the ffmpeg command that renders the video")
+	rootCmd.Flags().StringVar(&renderVideo, "render-video", "", "Also render a lyric video (e.g. out.mp4) with the lyrics burned in (single input, requires ffmpeg)")
+	rootCmd.Flags().StringVar(&videoBackground, "video-background", "black", "Background of --render-video: an image file or a color (e.g. #202040); defaults to the cover art if there is any")
+	rootCmd.Flags().BoolVar(&coverOut, "cover", false, "Also save the cover art as <name>.jpg: the picture embedded in the audio, or the video thumbnail with --yt-dlp")
+	rootCmd.Flags().BoolVar(&archiveOutput, "archive-output", false, "For .zip/.tar.gz inputs, also bundle the lyrics written for their audio files into <name>.lyrics.zip")
+	rootCmd.Flags().StringVar(&refreshJellyfin, "refresh-jellyfin", "", "After the batch, ask this Jellyfin/Emby server (e.g. http://nas:8096) to rescan the folders that got lyrics (API key from JELLYFIN_API_KEY)")
+	rootCmd.Flags().StringVar(&refreshPlex, "refresh-plex", "", "After the batch, ask this Plex server (e.g. http://nas:32400) to scan the folders that got lyrics (token from PLEX_TOKEN)")
+	rootCmd.Flags().StringArrayVar(&notifyHooks, "notify-webhook", nil, "Post the batch summary to a Slack, Discord or Telegram webhook URL when the run finishes (repeatable)")
+	rootCmd.Flags().StringSliceVar(&emailTo, "email-on-complete", nil, "Email the batch summary to these addresses when the run finishes")
+	rootCmd.Flags().StringVar(&smtpServer, "smtp-server", "localhost:25", "SMTP server for --email-on-complete as host:port (port 465 uses TLS; the password is read from SMTP_PASSWORD)")
+	rootCmd.Flags().StringVar(&smtpUser, "smtp-user", "", "SMTP user name for --email-on-complete")
+	rootCmd.Flags().StringVar(&emailFrom, "email-from", "", "Sender address for --email-on-complete (default: the SMTP user)")
+	rootCmd.Flags().BoolVar(&emailAttach, "email-attach", false, "Attach the lyrics files (up to 100 KB each) to the --email-on-complete summary")
+	rootCmd.Flags().StringVar(&errorReport, "error-report", "", "If any file fails, write a zip with the log, errors, request IDs and environment info for filing an issue (API keys are redacted)")
+	rootCmd.Flags().BoolVar(&karaoke, "karaoke", false, "Add per-word timing tags to LRC lines (enhanced LRC), estimated from line timing")
+	rootCmd.Flags().DurationVar(&snapOnsets, "snap-onsets", 0, "Move line starts to the nearest vocal onset in the audio within this distance (e.g. 300ms, requires ffmpeg)")
+	rootCmd.Flags().BoolVar(&refine, "refine", false, "Transcribe low-confidence lines again from clips of the audio and keep the more confident version (requires ffmpeg)")
+	rootCmd.Flags().Float64Var(&refineBelow, "refine-below", -1, "Average log probability under which --refine transcribes a line again (0 is certain; Whisper itself retries below -1)")
+	rootCmd.Flags().StringVar(&refineModel, "refine-model", "", "Model that --refine transcribes lines again with, such as a larger one than --model; it must report confidence like whisper-1 (implies --refine)")
+	rootCmd.Flags().BoolVar(&denoise, "denoise", false, "Reduce background noise before upload, for live recordings and old rips; with -v, also transcribe the original to compare confidence (requires ffmpeg)")
+	rootCmd.Flags().StringVar(&denoiseModel, "denoise-model", "", "RNNoise model file (.rnnn) for --denoise instead of ffmpeg's FFT denoiser; implies --denoise")
+	rootCmd.Flags().StringVar(&channel, "channel", "mix", "Audio to transcribe from stereo input: mix, left, right (vocals on one side) or vocal-center (the center of the mix, where lead vocals usually sit; requires ffmpeg)")
+	rootCmd.Flags().StringVar(&translateTo, "translate-to", "", "Translate into this language with a chat model, keeping timestamps (e.g. zh); implies --translate")
+	rootCmd.Flags().StringVar(&translateModel, "translate-model", translate.DefaultModel, "Chat model used by --translate-to")
+	rootCmd.Flags().BoolVar(&alsoTranslate, "also-translate", false, "Also write a translation as <name>.<lang>.<format>, English unless --translate-to is set (with --translate-if, only for those languages)")
+	rootCmd.Flags().BoolVar(&markLanguages, "mark-languages", false, "Annotate each line with its language code (VTT uses <lang> spans); per-segment languages need a backend that reports them")
+	rootCmd.Flags().StringSliceVar(&onlyLanguages, "only-language", nil, "Keep only segments in these languages (e.g. ja,en)")
+	rootCmd.Flags().BoolVar(&structureOut, "structure", false, "Also write the detected song structure as <name>.structure.json")
+	rootCmd.Flags().StringVar(&dumpRaw, "dump-raw", "", "Also save each raw API response (verbose_json) in this directory as <name>.json, for debugging or re-deriving outputs later")
+	rootCmd.Flags().DurationVar(&minDuration, "min-duration", 0, "Minimum subtitle cue duration for SRT/VTT output (e.g. 833ms)")
+	rootCmd.Flags().DurationVar(&maxDuration, "max-duration", 0, "Maximum subtitle cue duration for SRT/VTT output (e.g. 7s)")
+	rootCmd.Flags().DurationVar(&minGap, "min-gap", 0, "Minimum gap between subtitle cues for SRT/VTT output (e.g. 80ms)")
+	rootCmd.Flags().Float64Var(&maxCPS, "max-cps", 0, "Maximum reading speed in characters per second for SRT/VTT cues (e.g. 17)")
+	rootCmd.Flags().DurationVar(&timeOffset, "offset", 0, "Shift every timestamp by this much (e.g. 250ms to show lyrics later, -1.5s to show them earlier)")
+	rootCmd.Flags().BoolVar(&offsetTag, "offset-tag", false, "Write --offset as an LRC [offset:] tag instead of changing the timestamps")
+	rootCmd.Flags().IntVar(&maxLineLength, "max-line-length", 0, "Split LRC lines longer than this many characters into several lines with interpolated timestamps")
+	rootCmd.Flags().StringVar(&vttLine, "vtt-line", "", "WebVTT cue line setting (e.g. -1 or 90%)")
+	rootCmd.Flags().StringVar(&vttPosition, "vtt-position", "", "WebVTT cue position setting (e.g. 50%)")
+	rootCmd.Flags().StringVar(&vttAlign, "vtt-align", "", "WebVTT cue text alignment: start, center, end, left or right")
+	rootCmd.Flags().StringVar(&vttNote, "vtt-note", "", "Extra text for the WebVTT NOTE header block")
+	rootCmd.Flags().DurationVar(&preview, "preview", 0, "Only transcribe the first part of each file (e.g. 30s) and write <name>.preview.<format> (requires ffmpeg)")
+	rootCmd.Flags().IntVar(&prefetchDepth, "prefetch", 1, "Number of inputs to download ahead while the current one is transcribed (0 to download each just before transcribing it)")
+	rootCmd.Flags().IntVar(&concurrency, "concurrency", 1, "Number of inputs to download and transcribe at the same time")
+	rootCmd.Flags().DurationVar(&chunkMin, "chunk-min", time.Minute, "Shortest part to cut audio over the 25 MB upload limit into; part lengths adapt to the measured upload speed and API latency")
+	rootCmd.Flags().DurationVar(&chunkMax, "chunk-max", audio.SplitLength, "Longest part to cut audio over the 25 MB upload limit into")
+	rootCmd.Flags().StringVar(&schedule, "schedule", "input", "Batch order: input (as given) or shortest-first (local files by duration, or size without ffprobe; URLs last) so short files finish first")
+	rootCmd.Flags().BoolVar(&confirm, "confirm", false, "Resolve all inputs, show their total duration and estimated cost, and ask before transcribing")
+	rootCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Answer yes to the --confirm prompt (for scripts)")
+	rootCmd.Flags().DurationVar(&maxAudioLength, "max-audio-duration", 0, "Skip inputs longer than this (e.g. 30m) to avoid surprise bills; with --confirm, ask whether to transcribe them (requires ffprobe)")
+	rootCmd.Flags().StringVar(&budgetLimit, "budget", "", "Stop before transcribing more than this much audio, in minutes (e.g. 90m) or estimated dollars (e.g. $5)")
+	rootCmd.Flags().StringVar(&budgetPeriod, "budget-period", "run", "What --budget covers: run, or day/month to include earlier runs from the usage history")
+	rootCmd.PersistentFlags().BoolVar(&noHistory, "no-history", false, "Do not record anonymous usage (audio minutes, cost, timing) in the history file")
+	rootCmd.Flags().BoolVar(&recordMetrics, "metrics", false, "Record run timing and failure categories in a local metrics file shown by the stats command (never sent anywhere)")
+	rootCmd.Flags().BoolVar(&embedLyrics, "embed", false, "Also embed the lyrics (as LRC) into the tags of local MP3/FLAC/Ogg/M4A inputs (requires ffmpeg)")
+	rootCmd.Flags().BoolVar(&stream, "stream", false, "Write segments to stdout as JSON Lines as soon as they are transcribed (progress goes to stderr)")
+	rootCmd.Flags().StringVar(&summaryFormat, "summary", "text", "End-of-run summary: text, or json on stdout for scripts (progress goes to stderr)")
+	rootCmd.Flags().StringVar(&reportPath, "report", "", "Write a CSV report of every file (status, audio length, language, segments, output and error) for review in a spreadsheet")
 }
 
-func runExtract(cmd *cobra.Command, args []string) error {
-	// Get API key
+// resolveAPIKey returns the API key from --api-key or the environment
+func resolveAPIKey() (string, error) {
 	key := apiKey
+	if key == "" && provider == "azure" {
+		key = os.Getenv("AZURE_OPENAI_API_KEY")
+		if key == "" {
+			return "", fmt.Errorf("Azure OpenAI API key required. Set --api-key or AZURE_OPENAI_API_KEY environment variable")
+		}
+	}
+	if key == "" && provider == "groq" {
+		key = os.Getenv("GROQ_API_KEY")
+		if key == "" {
+			return "", fmt.Errorf("Groq API key required. Set --api-key or GROQ_API_KEY environment variable")
+		}
+	}
 	if key == "" {
 		key = os.Getenv("OPENAI_API_KEY")
 	}
 	if key == "" {
-		return fmt.Errorf("OpenAI API key required. Set --api-key or OPENAI_API_KEY environment variable")
+		return "", fmt.Errorf("OpenAI API key required. Set --api-key or OPENAI_API_KEY environment variable")
+	}
+	redact.Register(key)
+	return key, nil
+}
+
+// resolveAPIBase falls back to OPENAI_BASE_URL, which the OpenAI SDKs and
+// many proxies document, when --api-base is not set, to
+// AZURE_OPENAI_ENDPOINT for Azure and to the Groq API for Groq
+func resolveAPIBase() {
+	if apiBase != "" {
+		return
+	}
+	switch provider {
+	case "azure":
+		apiBase = os.Getenv("AZURE_OPENAI_ENDPOINT")
+	case "groq":
+		apiBase = whisper.GroqBaseURL
+	default:
+		apiBase = os.Getenv("OPENAI_BASE_URL")
+	}
+}
+
+// newClient creates a Whisper client using the --api-base, TLS and
+// connection flags. Options in extra apply last, such as another model.
+func newClient(key string, extra ...whisper.Option) (*whisper.Client, error) {
+	if keepAlive < 0 {
+		return nil, fmt.Errorf("--keep-alive cannot be negative")
+	}
+	cfg, err := tlsconfig.Load(tlsOpts)
+	if err != nil {
+		return nil, err
+	}
+	opts := []whisper.Option{
+		whisper.WithBaseURL(apiBase),
+		whisper.WithModel(model),
+		whisper.WithTLSConfig(cfg),
+		whisper.WithCompression(compressUploads),
+		whisper.WithIdleTimeout(keepAlive),
+		whisper.WithHTTP2(!noHTTP2),
+		whisper.WithWordTimestamps(wordTimes()),
+	}
+	switch provider {
+	case "openai":
+	case "groq":
+		if model == "" {
+			opts = append(opts, whisper.WithModel(whisper.GroqModel))
+		}
+	case "azure":
+		if apiBase == "" {
+			return nil, fmt.Errorf("--provider azure needs the resource endpoint as --api-base (or AZURE_OPENAI_ENDPOINT), e.g. https://NAME.openai.azure.com")
+		}
+		if azureDeployment == "" {
+			return nil, fmt.Errorf("--provider azure needs --azure-deployment, the deployment name of the Whisper model")
+		}
+		opts = append(opts, whisper.WithAzure(azureDeployment, azureVersion))
+	default:
+		return nil, fmt.Errorf("invalid --provider %q. Use 'openai', 'azure' or 'groq'", provider)
+	}
+	return whisper.NewClient(key, append(opts, extra...)...), nil
+}
+
+// effectivePrompt returns the --prompt value or the default anti-hallucination prompt
+func effectivePrompt() string {
+	if prompt != "" {
+		return prompt
+	}
+	return whisper.DefaultPrompt
+}
+
+func runExtract(cmd *cobra.Command, args []string) error {
+	// Get API key
+	key, err := resolveAPIKey()
+	if err != nil {
+		return err
 	}
 
 	// Validate output format
 	outputFormat = strings.ToLower(outputFormat)
-	if outputFormat != "lrc" && outputFormat != "srt" {
-		return fmt.Errorf("invalid output format: %s. Use 'lrc' or 'srt'", outputFormat)
+	switch outputFormat {
+	case "lrc", "elrc", "srt", "jsonl", "ultrastar":
+	case "vtt":
+		if err := output.ValidateVTTSettings(vttLine, vttPosition, vttAlign); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid output format: %s. Use 'lrc', 'elrc', 'srt', 'vtt', 'jsonl' or 'ultrastar'", outputFormat)
+	}
+
+	if err := validateTranslation(); err != nil {
+		return err
+	}
+	if refineModel != "" {
+		refine = true
+	}
+	// Clips are transcribed, so a translated result would be mixed with the
+	// original language
+	if refine && translatesInPlace() {
+		return fmt.Errorf("--refine improves transcriptions only; add --also-translate to translate next to them")
+	}
+	switch channel {
+	case "mix", "left", "right", "vocal-center":
+	default:
+		return fmt.Errorf("invalid --channel %q. Use 'mix', 'left', 'right' or 'vocal-center'", channel)
+	}
+	if denoiseModel != "" {
+		if _, err := os.Stat(denoiseModel); err != nil {
+			return fmt.Errorf("--denoise-model: %w", err)
+		}
+		denoise = true
+	}
+	if karaoke && !lrcOutput() {
+		return fmt.Errorf("--karaoke requires LRC output")
+	}
+	if embedLyrics && preview > 0 {
+		return fmt.Errorf("--embed cannot be combined with --preview")
+	}
+	if renderVideo != "" {
+		if len(args) != 1 {
+			return fmt.Errorf("--render-video takes a single input")
+		}
+		if preview > 0 {
+			return fmt.Errorf("--render-video cannot be combined with --preview")
+		}
+	}
+	if storage.IsRemote(outputDir) && (karaokePack || coverOut) {
+		return fmt.Errorf("--karaoke-pack and --cover need a local output directory")
+	}
+	if storage.IsRemote(outputDir) && (refreshJellyfin != "" || refreshPlex != "") {
+		return fmt.Errorf("--refresh-jellyfin and --refresh-plex need a local output directory")
+	}
+	if offsetTag && !lrcOutput() {
+		return fmt.Errorf("--offset-tag only applies to LRC output")
+	}
+	if maxAudioLength < 0 {
+		return fmt.Errorf("--max-audio-duration cannot be negative")
+	}
+	if maxLineLength < 0 {
+		return fmt.Errorf("--max-line-length cannot be negative")
+	}
+	if prefetchDepth < 0 {
+		return fmt.Errorf("--prefetch cannot be negative")
+	}
+	if concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+	if chunkMin <= 0 || chunkMin > chunkMax {
+		return fmt.Errorf("--chunk-min must be positive and no longer than --chunk-max")
+	}
+	if longest := audio.MaxChunkLength(whisper.MaxUploadBytes); chunkMax > longest {
+		return fmt.Errorf("--chunk-max %s makes parts over the %d MB upload limit; use at most %s", chunkMax, whisper.MaxUploadBytes>>20, longest)
+	}
+	if err := validateSchedule(); err != nil {
+		return err
+	}
+	switch summaryFormat {
+	case "text":
+	case "json":
+		if stream {
+			return fmt.Errorf("--summary json cannot be combined with --stream, which also writes to stdout")
+		}
+	default:
+		return fmt.Errorf("invalid --summary %q. Use 'text' or 'json'", summaryFormat)
+	}
+	webhooks, err := parseWebhooks()
+	if err != nil {
+		return err
+	}
+	refreshers, err := libraryRefreshers()
+	if err != nil {
+		return err
+	}
+
+	// Accept file:// URIs, //host/path and, with --assume-url, host/path
+	for i, arg := range args {
+		if args[i], err = input.NormalizeInput(arg, assumeURL); err != nil {
+			return err
+		}
+	}
+
+	args, cleanupArchives, err := expandArchives(args)
+	if err != nil {
+		return err
+	}
+	defer cleanupArchives()
+	args = scheduleInputs(args)
+	if archiveOutput {
+		archived = map[string]string{}
 	}
 
 	// Initialize components
-	client := whisper.NewClient(key)
-	inputHandler := input.NewHandler(useYtDlp)
-	var formatter output.Formatter
-	if outputFormat == "lrc" {
-		formatter = output.NewLRCFormatter()
-	} else {
-		formatter = output.NewSRTFormatter()
+	var outputRoot string
+	outputs, outputRoot, err = openOutputs()
+	if err != nil {
+		return err
+	}
+	client, err := newClient(key)
+	if err != nil {
+		return err
+	}
+	refiner := client
+	if refineModel != "" {
+		if refiner, err = newClient(key, whisper.WithModel(refineModel)); err != nil {
+			return err
+		}
+	}
+
+	translator := newTranslator(client)
+
+	inputOpts := []input.Option{input.WithTLS(tlsOpts)}
+	if noYtDlpFallback {
+		inputOpts = append(inputOpts, input.WithoutYtDlpFallback())
+	}
+	if limitRate != "" {
+		rate, err := input.ParseRate(limitRate)
+		if err != nil {
+			return err
+		}
+		inputOpts = append(inputOpts, input.WithRateLimit(rate))
+	}
+	if coverOut || renderVideo != "" {
+		inputOpts = append(inputOpts, input.WithCoverArt())
+	}
+	if memoryLimit != "" && !needsAudioFile() {
+		limit, err := input.ParseSize(memoryLimit)
+		if err != nil {
+			return err
+		}
+		inputOpts = append(inputOpts, input.WithMemoryLimit(limit))
+	}
+	if downloadCache != "" {
+		inputOpts = append(inputOpts, input.WithDownloadCache(downloadCache), input.WithYtDlpCache(downloadCache))
+	} else if skipUnchanged {
+		cacheDir, err := input.DefaultCacheDir()
+		if err != nil {
+			return err
+		}
+		inputOpts = append(inputOpts, input.WithDownloadCache(cacheDir))
+	}
+
+	inputHandler := input.NewHandler(useYtDlp, inputOpts...)
+	// Load profanity wordlists shared by censoring and explicit-content detection
+	wordlist := postprocess.NewWordlist()
+	for _, spec := range censorLists {
+		if err := wordlist.LoadSpec(spec); err != nil {
+			return err
+		}
+	}
+
+	workers := make(chan *stages, concurrency)
+	for range concurrency {
+		st, err := newStages(wordlist)
+		if err != nil {
+			return err
+		}
+		workers <- st
+	}
+
+	store, err := openHistory()
+	if err != nil {
+		return err
+	}
+	spend, err := loadBudget(store)
+	if err != nil {
+		return err
+	}
+	synced, err := openManifest()
+	if err != nil {
+		return err
+	}
+	run := &metrics.Run{Time: time.Now()}
+
+	// Keep stdout free for streamed segments and the JSON summary
+	var status io.Writer = os.Stdout
+	if stream || summaryFormat == "json" {
+		status = os.Stderr
+	}
+	status = redact.Writer(status)
+
+	// Resolve everything first and let the user review the batch before any
+	// API call is made
+	var resolved []resolvedInput
+	var includeLong bool
+	if confirm {
+		resolved = resolveAll(inputHandler, args, status)
+		var ok bool
+		ok, includeLong, err = confirmBatch(args, resolved, assumeYes, status)
+		if err != nil || !ok {
+			cleanupResolved(resolved)
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("aborted")
+		}
+	}
+
+	// Download upcoming inputs while earlier ones are transcribed. Workers
+	// running side by side each download their own input instead.
+	var queue *prefetcher
+	if resolved == nil && prefetchDepth > 0 && concurrency == 1 {
+		queue = prefetch(inputHandler, args, prefetchDepth)
+		defer queue.stop()
 	}
 
 	// Create progress tracker
 	tracker := progress.NewTracker(len(args))
+	tracker.SetOutput(status)
+	var diagnostics *report.Report
+	if errorReport != "" {
+		diagnostics = report.New(os.Args[1:])
+		tracker.SetLog(diagnostics)
+	}
 	tracker.Start()
 	defer tracker.Stop()
 
+	// On SIGINT/SIGTERM (e.g. docker stop), finish the file in progress and
+	// skip the rest; a second signal terminates immediately
+	var stopping atomic.Bool
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		if _, ok := <-signals; ok {
+			signal.Stop(signals)
+			stopping.Store(true)
+			tracker.Log("Stopping after the current file (interrupt again to abort)")
+		}
+	}()
+
 	// Process each input
 	var errors []string
-	for i, arg := range args {
+	var explicitFiles []string
+	var skipped []string
+	var unchanged []string
+	var overBudget []string
+	var renderCommands []string
+	var notAuthorized []string
+	var hookSkipped []string
+	var hasLyrics []string
+	var longInputs []string
+	var completed int
+	var results []notify.Result
+	attachments := map[string]string{}
+	batch := &summary.Batch{}
+	var halt string
+	// mu guards the batch state above and the budget, history, manifest and
+	// run totals, which files in progress at the same time all update
+	var mu sync.Mutex
+
+	// skip records an input that is not transcribed in list
+	skip := func(list *[]string, arg, reason string) {
+		tracker.Skip(arg, reason)
+		mu.Lock()
+		defer mu.Unlock()
+		*list = append(*list, arg)
+		batch.Add(summary.File{Input: arg, Status: summary.StatusSkipped, Reason: reason})
+	}
+
+	// postProcessHook runs --post-hook for a finished input
+	postProcessHook := func(arg, outPath string, failure error) {
+		output, err := runPostHook(arg, outPath, failure)
+		if err != nil {
+			tracker.Log(fmt.Sprintf("Warning: %s: post-hook failed: %v", arg, err))
+		} else if verbose && output != "" {
+			tracker.Log(fmt.Sprintf("%s: post-hook: %s", arg, output))
+		}
+	}
+
+	// failed records a file that could not be processed
+	failed := func(current *summary.File, arg string, err error) {
+		tracker.Error(arg, err)
+		mu.Lock()
+		errors = append(errors, fmt.Sprintf("%s: %v", arg, err))
+		results = append(results, notify.Result{Input: arg, Error: redact.String(err.Error())})
+		run.Files++
+		run.AddFailure(failureCategory(err))
+		current.Status, current.Reason = summary.StatusFailed, redact.String(err.Error())
+		batch.Add(*current)
+		mu.Unlock()
+		postProcessHook(arg, "", err)
+	}
+
+	// process transcribes one input with the stages of a free worker
+	process := func(i int) {
+		arg := args[i]
+		st := <-workers
+		defer func() { workers <- st }()
 		tracker.SetCurrent(i+1, filepath.Base(arg))
+		defer tracker.Finish(i + 1)
+		current := summary.File{Input: arg}
+		fail := func(arg string, err error) {
+			failed(&current, arg, err)
+		}
 
 		// Resolve input to local file
-		audioPath, cleanup, err := inputHandler.Resolve(arg)
+		var r resolvedInput
+		switch {
+		case resolved != nil:
+			r = resolved[i]
+		case queue != nil:
+			r = queue.next()
+		default:
+			r = resolveInput(inputHandler, arg)
+		}
+		src, err := r.src, r.err
+		mu.Lock()
+		run.DownloadSeconds += r.elapsed.Seconds()
+		mu.Unlock()
+		current.DownloadSeconds = r.elapsed.Seconds()
+		phases := phaseTimes{download: r.elapsed}
 		if err != nil {
-			errors = append(errors, fmt.Sprintf("%s: %v", arg, err))
-			tracker.Error(arg, err)
-			continue
+			fail(arg, err)
+			return
 		}
+		current.DownloadBytes = src.Downloaded
 
-		tracker.SetStatus("Transcribing...")
-		effectivePrompt := prompt
-		if effectivePrompt == "" {
-			effectivePrompt = whisper.DefaultPrompt
+		// Determine output path; with --lang-suffix and a detected language
+		// the lyrics path is only final after transcription
+		outPath := getOutputPath(arg, src.Title, outputRoot, outputExt())
+		if preview > 0 {
+			outPath = getOutputPath(arg, src.Title, outputRoot, "preview."+outputExt())
+		}
+		lyricsPath := outPath
+		if langSuffix && language != "" {
+			lyricsPath = languagePath(outPath, lyricsLanguage(language))
+		}
+
+		// Skip remote audio that has not changed since its lyrics were written
+		if skipUnchanged && src.Unchanged && existingOutput(lyricsPath) != "" {
+			src.Cleanup()
+			skip(&unchanged, arg, "unchanged since last run")
+			return
+		}
+
+		// Skip audio that was transcribed by an earlier --sync run
+		var hash string
+		if synced != nil {
+			if hash, err = audioHash(src); err != nil {
+				src.Cleanup()
+				fail(arg, err)
+				return
+			}
+			mu.Lock()
+			done := synced.Unchanged(syncKey(arg), hash)
+			mu.Unlock()
+			if done && existingOutput(lyricsPath) != "" {
+				src.Cleanup()
+				skip(&unchanged, arg, "unchanged since last sync")
+				return
+			}
+		}
+
+		if ifMissing {
+			existing, err := existingLyrics(arg, src, lyricsPath)
+			if err != nil {
+				tracker.Log(fmt.Sprintf("Warning: %s: could not check for existing lyrics: %v", arg, err))
+			}
+			if existing != "" {
+				src.Cleanup()
+				skip(&hasLyrics, arg, "already has lyrics ("+existing+")")
+				return
+			}
+		}
+
+		// Guard against transcribing a long recording picked up by accident
+		if maxAudioLength > 0 && preview == 0 && !includeLong {
+			if length, err := audio.Duration(src.Path); err != nil {
+				tracker.Log(fmt.Sprintf("Warning: %s: length not checked against --max-audio-duration: %v", arg, err))
+			} else if tooLong(length) {
+				src.Cleanup()
+				skip(&longInputs, arg, fmt.Sprintf("%s long, over --max-audio-duration", length.Round(time.Second)))
+				return
+			}
+		}
+
+		if output, err := runPreHook(arg, lyricsPath, src.Path); err != nil {
+			src.Cleanup()
+			skip(&hookSkipped, arg, fmt.Sprintf("pre-hook failed: %v", err))
+			return
+		} else if verbose && output != "" {
+			tracker.Log(fmt.Sprintf("%s: pre-hook: %s", arg, output))
+		}
+
+		prepared := time.Now()
+		audioPath := src.Path
+		// noisyPath is the audio before --denoise, kept for the -v comparison
+		noisyPath := audioPath
+		removeAudio := func() {
+			for _, path := range []string{audioPath, noisyPath} {
+				if path != src.Path {
+					os.Remove(path)
+				}
+			}
+		}
+		if preview > 0 {
+			tracker.SetStatus(i+1, "Trimming preview...")
+			audioPath, err = audio.Trim(src.Path, preview)
+			if err != nil {
+				src.Cleanup()
+				fail(arg, err)
+				return
+			}
+			noisyPath = audioPath
+		}
+		if channel != "mix" {
+			tracker.SetStatus(i+1, "Selecting channel...")
+			selected, err := audio.SelectChannel(noisyPath, channel)
+			removeAudio()
+			if err != nil {
+				src.Cleanup()
+				fail(arg, err)
+				return
+			}
+			audioPath, noisyPath = selected, selected
+		}
+		if denoise {
+			tracker.SetStatus(i+1, "Reducing noise...")
+			audioPath, err = audio.Denoise(noisyPath, denoiseModel)
+			if err != nil {
+				audioPath = noisyPath
+				removeAudio()
+				src.Cleanup()
+				fail(arg, err)
+				return
+			}
+		}
+
+		// Stop before a file that would overrun the budget; files of unknown
+		// length are transcribed and counted afterwards
+		if spend != nil {
+			if length, err := audio.Duration(audioPath); err == nil {
+				mu.Lock()
+				allowed := spend.Allows(length.Seconds(), usageCost(length.Seconds()))
+				if !allowed {
+					overBudget = append(overBudget, arg)
+					halt = haltBudget
+				}
+				mu.Unlock()
+				if !allowed {
+					removeAudio()
+					src.Cleanup()
+					return
+				}
+			}
+		}
+
+		phases.preprocess = time.Since(prepared)
+		tracker.SetStatus(i+1, "Transcribing...")
+		started := time.Now()
+		upload := whisper.Audio{Path: audioPath}
+		if src.Data != nil {
+			upload = whisper.Audio{Name: src.Name, Data: src.Data}
+		}
+		// --stream writes each part as soon as it is transcribed, through
+		// the stages that do not need the whole transcription
+		var emit func(part *whisper.TranscriptionResult, offset float64)
+		if stream {
+			emit = func(part *whisper.TranscriptionResult, offset float64) {
+				streamed := &whisper.TranscriptionResult{}
+				streamed.Append(part, offset)
+				st.stream.Process(streamed)
+				mu.Lock()
+				for _, seg := range streamed.Segments {
+					fmt.Print(output.FormatJSONLSegment(arg, seg))
+				}
+				mu.Unlock()
+			}
+		}
+		result, translation, billed, err := transcribeSplit(client, translator, upload, func(status string) {
+			tracker.SetStatus(i+1, status)
+		}, emit)
+		if denoise && verbose && err == nil && !translatesInPlace() {
+			tracker.SetStatus(i+1, "Comparing with the original audio...")
+			extra, comparison := compareDenoise(client, noisyPath, result)
+			billed += extra
+			tracker.Log(fmt.Sprintf("%s: %s", arg, comparison))
+		}
+		if refine && err == nil {
+			extra, refined, refineErr := refineLines(refiner, result, audioPath, func(status string) {
+				tracker.SetStatus(i+1, status)
+			})
+			billed += extra
+			if refineErr != nil {
+				tracker.Log(fmt.Sprintf("Warning: %s: refining stopped: %v", arg, refineErr))
+			} else if verbose && refined > 0 {
+				tracker.Log(fmt.Sprintf("%s: --refine improved %d low-confidence line(s)", arg, refined))
+			}
+		}
+		transcribed := time.Now()
+		if st.snapper != nil && err == nil {
+			tracker.SetStatus(i+1, "Detecting onsets...")
+			var onsetErr error
+			if st.snapper.Onsets, onsetErr = audio.Onsets(audioPath); onsetErr != nil {
+				tracker.Log(fmt.Sprintf("Warning: %s: onset detection failed, timestamps not snapped: %v", arg, onsetErr))
+			}
+		}
+		var pitch []float64
+		var pitchErr error
+		if (melodyOut || st.ultraStar != nil) && err == nil {
+			tracker.SetStatus(i+1, "Extracting melody...")
+			pitch, pitchErr = audio.Pitch(audioPath)
+			if pitchErr != nil && !melodyOut {
+				tracker.Log(fmt.Sprintf("Warning: %s: pitch detection failed, writing freestyle notes: %v", arg, pitchErr))
+			}
+		}
+		removeAudio()
+		current.AudioSeconds = billed
+		current.TranscribeSeconds = time.Since(started).Seconds()
+		mu.Lock()
+		if spend != nil {
+			spend.Add(billed, usageCost(billed))
+		}
+		run.AudioSeconds += billed
+		run.TranscribeSeconds += time.Since(started).Seconds()
+		usageErr := recordUsage(store, billed, time.Since(started), err != nil)
+		mu.Unlock()
+		if usageErr != nil && verbose {
+			tracker.Log(fmt.Sprintf("Warning: %v", usageErr))
 		}
-		result, err := client.Transcribe(audioPath, language, effectivePrompt)
 		if err != nil {
-			if cleanup != nil {
-				cleanup()
+			src.Cleanup()
+			fail(arg, err)
+			var apiErr *whisper.APIError
+			if diagnostics != nil && asAPIError(err, &apiErr) && apiErr.RequestID != "" {
+				diagnostics.AddRequestID(apiErr.RequestID)
+			}
+			// Every other file would fail the same way
+			if isAuthError(err) {
+				mu.Lock()
+				halt = haltAuth
+				mu.Unlock()
+			}
+			return
+		}
+
+		if preview > 0 {
+			tracker.Log(fmt.Sprintf("%s: detected language %s, %d segment(s) in the first %s", arg, result.Language, len(result.Segments), preview))
+		}
+		if dumpRaw != "" {
+			if err := dumpResponses(result, translation, outPath); err != nil {
+				tracker.Log(fmt.Sprintf("Warning: %s: %v", arg, err))
 			}
-			errors = append(errors, fmt.Sprintf("%s: %v", arg, err))
-			tracker.Error(arg, err)
-			continue
 		}
 
-		// Format output
-		content := formatter.Format(result)
+		// Detect explicit content before censoring masks it
+		if explicit && wordlist.IsExplicit(result) {
+			mu.Lock()
+			explicitFiles = append(explicitFiles, arg)
+			mu.Unlock()
+		}
 
-		// Determine output path
-		outPath := getOutputPath(arg, outputDir, outputFormat)
+		// Post-process and format output
+		if err := runPipeline(st.pipeline, st.plugins, arg, result); err != nil {
+			src.Cleanup()
+			fail(arg, err)
+			return
+		}
+		current.Language, current.Segments = whisper.LanguageCode(result.Language), len(result.Segments)
+		if language != "" {
+			current.Language = whisper.LanguageCode(language)
+		}
+		if verbose && st.readingSpeed != nil && st.readingSpeed.Violations > 0 {
+			tracker.Log(fmt.Sprintf("%s: %d cue(s) still exceed %.1f characters per second", arg, st.readingSpeed.Violations, maxCPS))
+		}
+		if st.ultraStar != nil {
+			st.ultraStar.Artist, st.ultraStar.Title, st.ultraStar.Audio = songInfo(arg, outPath)
+			st.ultraStar.Pitch, st.ultraStar.PitchHop = pitch, audio.PitchHop
+		}
+		content := st.formatter.Format(result)
 
 		// Write output file
-		if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
-			if cleanup != nil {
-				cleanup()
+		phases.postprocess = time.Since(transcribed)
+		writing := time.Now()
+		if langSuffix && language == "" {
+			lyricsPath = languagePath(outPath, lyricsLanguage(result.Language))
+		}
+		if err := writeOutput(lyricsPath, content); err != nil {
+			src.Cleanup()
+			fail(arg, err)
+			return
+		}
+		if wantAttachments() {
+			mu.Lock()
+			addAttachment(attachments, lyricsPath, content)
+			mu.Unlock()
+		}
+
+		// Write the translation next to the original
+		if translation != nil {
+			if err := runPipeline(st.pipeline, st.plugins, arg, translation); err != nil {
+				src.Cleanup()
+				fail(arg, err)
+				return
+			}
+			translationPath := strings.TrimSuffix(outPath, outputExt()) + translationTarget() + "." + outputExt()
+			if err := writeOutput(translationPath, st.formatter.Format(translation)); err != nil {
+				src.Cleanup()
+				fail(arg, err)
+				return
 			}
-			errors = append(errors, fmt.Sprintf("%s: %v", arg, err))
-			tracker.Error(arg, err)
-			continue
 		}
 
-		if err := os.WriteFile(outPath, []byte(content), 0644); err != nil {
-			if cleanup != nil {
-				cleanup()
+		if structureOut {
+			if err := writeStructure(result, strings.TrimSuffix(outPath, outputExt())+"structure.json"); err != nil {
+				src.Cleanup()
+				fail(arg, err)
+				return
+			}
+		}
+
+		if melodyOut {
+			if pitchErr == nil {
+				pitchErr = writeMelody(melody.Extract(result, pitch, audio.PitchHop), strings.TrimSuffix(outPath, outputExt())+"melody.json")
+			}
+			if pitchErr != nil {
+				src.Cleanup()
+				fail(arg, pitchErr)
+				return
+			}
+		}
+
+		if karaokePack {
+			artist, title, _ := songInfo(arg, outPath)
+			command, err := writeKaraokePack(strings.TrimSuffix(outPath, outputExt())+"karaoke", result, src.Path, artist, title)
+			if err != nil {
+				src.Cleanup()
+				fail(arg, err)
+				return
+			}
+			mu.Lock()
+			renderCommands = append(renderCommands, command)
+			mu.Unlock()
+		}
+
+		if coverOut {
+			if err := saveCover(src, strings.TrimSuffix(outPath, outputExt())+"jpg"); err != nil {
+				src.Cleanup()
+				fail(arg, err)
+				return
+			}
+		}
+
+		if renderVideo != "" {
+			tracker.SetStatus(i+1, "Rendering video...")
+			_, title, _ := songInfo(arg, outPath)
+			useCover := !cmd.Flags().Changed("video-background")
+			if err := renderLyricVideo(result, src, title, renderVideo, videoBackground, useCover); err != nil {
+				src.Cleanup()
+				fail(arg, err)
+				return
+			}
+		}
+
+		if embedLyrics {
+			if err := embedResult(arg, src, result, content); err != nil {
+				src.Cleanup()
+				fail(arg, err)
+				return
 			}
-			errors = append(errors, fmt.Sprintf("%s: %v", arg, err))
-			tracker.Error(arg, err)
-			continue
 		}
 
 		// Cleanup temp files
-		if cleanup != nil {
-			cleanup()
+		src.Cleanup()
+		phases.write = time.Since(writing)
+		if verbose {
+			phases.addRequests(result, translation)
+			tracker.Log(fmt.Sprintf("%s: %s", arg, phases))
 		}
 
-		tracker.Complete(arg, outPath)
+		current.Status, current.Output = summary.StatusOK, outputs.Location(lyricsPath)
+		mu.Lock()
+		run.Files++
+		completed++
+		batch.Add(current)
+		results = append(results, notify.Result{Input: arg, Output: outputs.Location(lyricsPath)})
+		var syncErr error
+		if synced != nil {
+			synced.Record(syncKey(arg), hash, outputs.Location(lyricsPath))
+			syncErr = synced.Save()
+		}
+		mu.Unlock()
+		tracker.Complete(arg, outputs.Location(lyricsPath))
+		postProcessHook(arg, lyricsPath, nil)
+		if syncErr != nil {
+			tracker.Log(fmt.Sprintf("Warning: %v", syncErr))
+		}
+	}
+
+	// next decides whether to start another input
+	next := func(i int) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case halt != "":
+		case stopping.Load():
+			halt = haltInterrupted
+		case spend != nil && spend.Exceeded():
+			halt = haltBudget
+		}
+		return halt == ""
+	}
+
+	started := runInputs(len(args), concurrency, next, process)
+	if resolved != nil {
+		cleanupResolved(resolved[started:])
+	}
+	switch halt {
+	case haltInterrupted:
+		skipped = args[started:]
+	case haltBudget:
+		overBudget = append(overBudget, args[started:]...)
+	case haltAuth:
+		notAuthorized = args[started:]
 	}
 
 	tracker.Stop()
+	for _, f := range skipped {
+		batch.Add(summary.File{Input: f, Status: summary.StatusSkipped, Reason: "interrupted"})
+	}
+	for _, f := range overBudget {
+		batch.Add(summary.File{Input: f, Status: summary.StatusSkipped, Reason: "budget exceeded"})
+	}
+	for _, f := range notAuthorized {
+		batch.Add(summary.File{Input: f, Status: summary.StatusSkipped, Reason: "not authorized"})
+	}
+	batch.Finish(time.Since(run.Time))
+	if err := recordRun(run); err != nil && verbose {
+		fmt.Fprintf(status, "Warning: %v\n", err)
+	}
+	if archived != nil {
+		written, err := writeResultArchives(outputRoot)
+		for _, path := range written {
+			fmt.Fprintf(status, "Result archive written to %s\n", path)
+		}
+		if err != nil {
+			errors = append(errors, err.Error())
+		}
+	}
+
+	if len(refreshers) > 0 {
+		refreshLibraries(refreshers, results, status)
+	}
+
+	host, _ := os.Hostname()
+	notifyCompletion(&notify.Summary{
+		Host:         host,
+		Duration:     time.Since(run.Time),
+		Results:      results,
+		NotProcessed: len(skipped) + len(overBudget) + len(notAuthorized) + len(unchanged) + len(hookSkipped) + len(hasLyrics) + len(longInputs),
+	}, webhooks, attachments, status)
 
 	// Print summary
-	fmt.Println()
+	fmt.Fprintln(status)
+	if explicit {
+		if len(explicitFiles) > 0 {
+			fmt.Fprintf(status, "Explicit content detected in %d file(s):\n", len(explicitFiles))
+			for _, f := range explicitFiles {
+				fmt.Fprintf(status, "  - %s\n", f)
+			}
+		} else {
+			fmt.Fprintln(status, "No explicit content detected")
+		}
+	}
+	if len(unchanged) > 0 {
+		fmt.Fprintf(status, "Skipped %d unchanged file(s)\n", len(unchanged))
+	}
+	if len(hasLyrics) > 0 {
+		fmt.Fprintf(status, "Skipped %d file(s) that already have lyrics\n", len(hasLyrics))
+	}
+	if len(hookSkipped) > 0 {
+		fmt.Fprintf(status, "Skipped %d file(s) by --pre-hook\n", len(hookSkipped))
+	}
+	if len(longInputs) > 0 {
+		fmt.Fprintf(status, "Skipped %d file(s) longer than --max-audio-duration %s:\n", len(longInputs), maxAudioLength)
+		for _, f := range longInputs {
+			fmt.Fprintf(status, "  - %s\n", f)
+		}
+	}
+	if len(renderCommands) > 0 {
+		fmt.Fprintln(status, "Render the karaoke video(s) with:")
+		for _, command := range renderCommands {
+			fmt.Fprintf(status, "  %s\n", command)
+		}
+	}
+	if spend != nil {
+		fmt.Fprintf(status, "Budget: %s\n", spend.Summary())
+	}
+	if len(overBudget) > 0 {
+		fmt.Fprintf(status, "Budget exceeded, %d file(s) not processed:\n", len(overBudget))
+		for _, f := range overBudget {
+			fmt.Fprintf(status, "  - %s\n", f)
+		}
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintf(status, "Interrupted, %d file(s) not processed:\n", len(skipped))
+		for _, f := range skipped {
+			fmt.Fprintf(status, "  - %s\n", f)
+		}
+	}
+	if len(notAuthorized) > 0 {
+		fmt.Fprintf(status, "Stopped after an authentication error, %d file(s) not processed:\n", len(notAuthorized))
+		for _, f := range notAuthorized {
+			fmt.Fprintf(status, "  - %s\n", f)
+		}
+	}
+	if summaryFormat == "json" {
+		if err := batch.WriteJSON(os.Stdout); err != nil {
+			return err
+		}
+	} else {
+		batch.WriteText(status, summary.LocaleFromEnv())
+	}
+	if reportPath != "" {
+		if err := writeReport(batch, reportPath); err != nil {
+			fmt.Fprintf(status, "Warning: could not write report: %v\n", err)
+		} else {
+			fmt.Fprintf(status, "Report written to %s\n", reportPath)
+		}
+	}
 	if len(errors) > 0 {
-		fmt.Printf("Completed with %d error(s):\n", len(errors))
+		fmt.Fprintf(status, "Completed with %d error(s):\n", len(errors))
 		for _, e := range errors {
-			fmt.Printf("  - %s\n", e)
+			fmt.Fprintf(status, "  - %s\n", e)
+		}
+		if diagnostics != nil {
+			for _, e := range errors {
+				diagnostics.AddError(e)
+			}
+			if err := diagnostics.Save(errorReport); err != nil {
+				fmt.Fprintf(status, "Warning: %v\n", err)
+			} else {
+				fmt.Fprintf(status, "Error report written to %s\n", errorReport)
+			}
 		}
 		return fmt.Errorf("some files failed to process")
 	}
+	if len(skipped) > 0 {
+		return fmt.Errorf("interrupted")
+	}
+	if len(overBudget) > 0 {
+		return fmt.Errorf("budget exceeded")
+	}
+
+	fmt.Fprintf(status, "Successfully processed %d file(s)\n", completed)
+	return nil
+}
+
+// writeReport writes the --report CSV file
+func writeReport(batch *summary.Batch, path string) error {
+	var buf bytes.Buffer
+	if err := batch.WriteCSV(&buf); err != nil {
+		return err
+	}
+	return storage.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// dumpResponses saves the raw API responses of a transcription and its
+// translation for --dump-raw, named after the output file. Translations made
+// by a chat model have no response of this kind.
+func dumpResponses(result, translation *whisper.TranscriptionResult, outPath string) error {
+	if err := os.MkdirAll(dumpRaw, 0755); err != nil {
+		return fmt.Errorf("failed to create --dump-raw directory: %w", err)
+	}
+	stem := strings.TrimSuffix(filepath.Base(outPath), "."+outputExt())
+	if result.Raw != nil {
+		if err := storage.WriteFile(filepath.Join(dumpRaw, stem+".json"), result.Raw, 0644); err != nil {
+			return err
+		}
+	}
+	if translation != nil && translation.Raw != nil {
+		return storage.WriteFile(filepath.Join(dumpRaw, stem+"."+translationTarget()+".json"), translation.Raw, 0644)
+	}
+	return nil
+}
+
+// needsAudioFile reports whether the flags run ffmpeg or ffprobe on the
+// downloaded audio, or pass it to --pre-hook, which needs it on disk
+func needsAudioFile() bool {
+	return confirm || preview > 0 || budgetLimit != "" || maxAudioLength > 0 || snapOnsets > 0 || refine || denoise || channel != "mix" || melodyOut ||
+		outputFormat == "ultrastar" || karaokePack || coverOut || renderVideo != "" || embedLyrics || ifMissing || preHook != ""
+}
+
+// asAPIError finds an API error in the error chain
+func asAPIError(err error, target **whisper.APIError) bool {
+	return errors.As(err, target)
+}
 
-	fmt.Printf("Successfully processed %d file(s)\n", len(args))
+// isAuthError reports whether the API rejected the credentials
+func isAuthError(err error) bool {
+	return errors.Is(err, whisper.ErrAuth)
+}
+
+// writeOutput writes an output file to the output storage, keeping a copy
+// for --archive-output
+func writeOutput(path, content string) error {
+	if err := outputs.Write(path, []byte(content)); err != nil {
+		return err
+	}
+	if archived != nil {
+		archivedMu.Lock()
+		archived[path] = content
+		archivedMu.Unlock()
+	}
+	return nil
+}
+
+// openOutputs opens the --output storage and returns the directory to pass
+// to getOutputPath. Local outputs are named by their full path so they can
+// sit next to their inputs; remote ones are named relative to the URL.
+func openOutputs() (storage.Storage, string, error) {
+	if !storage.IsRemote(outputDir) {
+		return storage.Local{}, outputDir, nil
+	}
+	cfg, err := tlsconfig.Load(tlsOpts)
+	if err != nil {
+		return nil, "", err
+	}
+	s, err := storage.Open(outputDir, cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	return s, ".", nil
+}
+
+// writeStructure saves the detected song structure as JSON
+func writeStructure(result *whisper.TranscriptionResult, path string) error {
+	data, err := json.MarshalIndent(structure.Song{Sections: structure.Detect(result)}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode song structure: %w", err)
+	}
+	if err := writeOutput(path, string(data)+"\n"); err != nil {
+		return fmt.Errorf("failed to write song structure: %w", err)
+	}
+	return nil
+}
+
+// writeMelody writes the melody document as compact JSON, since contours
+// have a hundred values per second
+func writeMelody(m *melody.Melody, path string) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to encode melody: %w", err)
+	}
+	if err := writeOutput(path, string(data)+"\n"); err != nil {
+		return fmt.Errorf("failed to write melody: %w", err)
+	}
 	return nil
 }
 
-func getOutputPath(input, outputDir, format string) string {
+// coverArt returns the cover art of a source: the downloaded thumbnail, or
+// the picture embedded in the audio extracted to a temp file that remove
+// deletes
+func coverArt(src *input.Source) (path string, remove func(), err error) {
+	if src.Cover != "" {
+		return src.Cover, func() {}, nil
+	}
+	path, err = audio.ExtractCover(src.Path)
+	if err != nil {
+		return "", nil, err
+	}
+	return path, func() { os.Remove(path) }, nil
+}
+
+// saveCover writes the cover art of a source to path
+func saveCover(src *input.Source, path string) error {
+	cover, remove, err := coverArt(src)
+	if err != nil {
+		return err
+	}
+	defer remove()
+	if err := copyFile(cover, path); err != nil {
+		return fmt.Errorf("failed to write cover art: %w", err)
+	}
+	return nil
+}
+
+// embedResult writes the lyrics into the tags of a local input file
+func embedResult(arg string, src *input.Source, result *whisper.TranscriptionResult, content string) error {
+	if src.Path != arg {
+		return fmt.Errorf("--embed only works with local files")
+	}
+	lyrics := content
+	if outputFormat != "lrc" {
+		lyrics = output.NewLRCFormatter().Format(result)
+	}
+	return audio.EmbedLyrics(arg, lyrics)
+}
+
+// outputExists reports whether an output file was already written
+func outputExists(path string) bool {
+	exists, err := outputs.Exists(path)
+	return err == nil && exists
+}
+
+// outputExt returns the file extension of the output format
+func outputExt() string {
+	switch outputFormat {
+	case "elrc":
+		return "lrc"
+	case "ultrastar":
+		return "txt"
+	}
+	return outputFormat
+}
+
+// lrcOutput reports whether the output format is LRC, plain or enhanced
+func lrcOutput() bool {
+	return outputFormat == "lrc" || outputFormat == "elrc"
+}
+
+// wordTimes reports whether the output times every word, so word
+// timestamps are requested from the API
+func wordTimes() bool {
+	return outputFormat == "elrc" || karaoke || outputFormat == "ultrastar" || karaokePack || renderVideo != ""
+}
+
+// languagePath inserts a language code before the extension of an output
+// path for --lang-suffix, e.g. song.lrc becomes song.ja.lrc. Outputs of an
+// unknown language keep their name.
+func languagePath(outPath, lang string) string {
+	code := filename.Sanitize(whisper.LanguageCode(lang))
+	if code == "" {
+		return outPath
+	}
+	return strings.TrimSuffix(outPath, outputExt()) + code + "." + outputExt()
+}
+
+// songInfo derives the UltraStar artist and title from an "Artist - Title"
+// file name and names the audio file for the #MP3 header. Downloads are
+// expected to be saved next to the song file as <name>.mp3.
+func songInfo(input, outPath string) (artist, title, audioFile string) {
+	stem := strings.TrimSuffix(filepath.Base(outPath), "."+outputExt())
+	stem = strings.TrimSuffix(stem, ".preview")
+
+	audioFile = filepath.Base(input)
+	if strings.HasPrefix(input, "http://") || strings.HasPrefix(input, "https://") {
+		audioFile = stem + ".mp3"
+	}
+
+	artist, title = "Unknown", stem
+	if a, t, ok := strings.Cut(stem, " - "); ok && strings.TrimSpace(a) != "" && strings.TrimSpace(t) != "" {
+		artist, title = strings.TrimSpace(a), strings.TrimSpace(t)
+	}
+	return artist, title, audioFile
+}
+
+// getOutputPath names the output of an input. URL inputs are named by
+// title, the readable name of the download, when there is one, and by the
+// last part of the URL otherwise.
+func getOutputPath(input, title, outputDir, format string) string {
+	if m, ok := archiveMembers[input]; ok {
+		return memberOutputPath(m, outputDir, format)
+	}
+
 	// Get base name without extension
 	base := filepath.Base(input)
 	ext := filepath.Ext(base)
@@ -179,8 +1469,11 @@ func getOutputPath(input, outputDir, format string) string {
 
 	// Handle URLs
 	if strings.HasPrefix(input, "http://") || strings.HasPrefix(input, "https://") {
-		// Use a sanitized version of URL as filename
-		name = sanitizeFilename(name)
+		// Use a sanitized version of the title or URL as filename
+		if title != "" {
+			name = title
+		}
+		name = filename.Sanitize(name)
 		if name == "" {
 			name = "output"
 		}
@@ -198,13 +1491,3 @@ func getOutputPath(input, outputDir, format string) string {
 
 	return filepath.Join(dir, name+"."+format)
 }
-
-func sanitizeFilename(name string) string {
-	// Remove invalid characters for filenames
-	invalid := []string{"/", "\\", ":", "*", "?", "\"", "<", ">", "|"}
-	result := name
-	for _, char := range invalid {
-		result = strings.ReplaceAll(result, char, "_")
-	}
-	return result
-}
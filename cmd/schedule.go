@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/BBleae/whisper-lrc/internal/audio"
+)
+
+// Batch orders for --schedule
+const (
+	scheduleInput         = "input"
+	scheduleShortestFirst = "shortest-first"
+)
+
+// validateSchedule checks the --schedule value
+func validateSchedule() error {
+	switch schedule {
+	case scheduleInput, scheduleShortestFirst:
+		return nil
+	default:
+		return fmt.Errorf("invalid --schedule %q. Use 'input' or 'shortest-first'", schedule)
+	}
+}
+
+// scheduleInputs orders the inputs for --schedule. Shortest-first sorts
+// local files by duration when ffprobe is available and by size otherwise;
+// URLs are not known until downloaded and keep their order after them.
+func scheduleInputs(args []string) []string {
+	if schedule != scheduleShortestFirst {
+		return args
+	}
+
+	_, err := exec.LookPath("ffprobe")
+	useDuration := err == nil
+	length := func(arg string) (int64, bool) {
+		path := arg
+		if m, ok := archiveMembers[arg]; ok {
+			path = m.path
+		} else if strings.Contains(arg, "://") {
+			return 0, false
+		}
+		if useDuration {
+			if d, err := audio.Duration(path); err == nil {
+				return int64(d), true
+			}
+			return 0, false
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return 0, false
+		}
+		return info.Size(), true
+	}
+
+	type entry struct {
+		arg    string
+		length int64
+		known  bool
+	}
+	entries := make([]entry, len(args))
+	for i, arg := range args {
+		n, ok := length(arg)
+		entries[i] = entry{arg: arg, length: n, known: ok}
+	}
+	// Inputs of unknown length (URLs, unreadable files) go last
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].known != entries[j].known {
+			return entries[i].known
+		}
+		return entries[i].length < entries[j].length
+	})
+
+	ordered := make([]string, len(entries))
+	for i, e := range entries {
+		ordered[i] = e.arg
+	}
+	return ordered
+}
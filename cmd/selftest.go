@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BBleae/whisper-lrc/internal/audio"
+	"github.com/BBleae/whisper-lrc/internal/postprocess"
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+	"github.com/spf13/cobra"
+)
+
+var (
+	selftestAudio  string
+	selftestExpect string
+)
+
+// selftestLength is the length of the generated test clip
+const selftestLength = 3 * time.Second
+
+var selftestCmd = &cobra.Command{
+	Use:   "selftest",
+	Short: "Check the configured API end to end with a short test clip",
+	Long: `Send a short generated clip (a tone followed by silence) to the configured
+API and check the response: that the server accepts the key, returns
+verbose JSON with a duration and ordered timestamps, and that the result
+passes the post-processing pipeline and formatter. Optional tools such as
+ffmpeg and yt-dlp are listed as well. This is a quick way to verify a new
+server deployment, proxy or --provider setting.
+
+A tone has no words, so only the plumbing is tested. To check recognition
+too, pass a short recording of speech with --audio and words it contains
+with --expect.
+
+Examples:
+  whisper-lrc selftest
+  whisper-lrc selftest --api-base http://localhost:8000/v1
+  whisper-lrc selftest --audio hello.wav --expect "hello world"`,
+	Args: cobra.NoArgs,
+	RunE: runSelftest,
+}
+
+func init() {
+	selftestCmd.Flags().StringVar(&selftestAudio, "audio", "", "Audio file to send instead of the generated tone, e.g. a short recording of speech")
+	selftestCmd.Flags().StringVar(&selftestExpect, "expect", "", "Words the transcription must contain (case-insensitive), to check recognition with --audio")
+	rootCmd.AddCommand(selftestCmd)
+}
+
+func runSelftest(cmd *cobra.Command, args []string) error {
+	failed := 0
+	check := func(err error, ok string) bool {
+		if err != nil {
+			fmt.Printf("✗ %v\n", err)
+			failed++
+			return false
+		}
+		fmt.Printf("✓ %s\n", ok)
+		return true
+	}
+
+	key, err := resolveAPIKey()
+	if !check(err, "API key found") {
+		return fmt.Errorf("self-test failed")
+	}
+	client, err := newClient(key)
+	if !check(err, "client configured for "+backendName()) {
+		return fmt.Errorf("self-test failed")
+	}
+
+	upload := whisper.Audio{Name: "selftest.wav", Data: audio.TestTone(selftestLength)}
+	if selftestAudio != "" {
+		upload = whisper.Audio{Path: selftestAudio, Name: filepath.Base(selftestAudio)}
+	}
+	started := time.Now()
+	result, err := client.TranscribeAudio(upload, language, effectivePrompt())
+	if err != nil {
+		fmt.Printf("✗ transcription failed: %v\n", err)
+		return fmt.Errorf("self-test failed")
+	}
+	check(nil, fmt.Sprintf("transcribed %s in %.1f s: language %q, %d segment(s)",
+		upload.Name, time.Since(started).Seconds(), result.Language, len(result.Segments)))
+
+	check(checkResponse(result), "response has a duration and ordered timestamps")
+	if selftestExpect != "" {
+		var err error
+		if missing := missingWords(result, selftestExpect); len(missing) > 0 {
+			err = fmt.Errorf("transcription %q lacks %s", strings.TrimSpace(result.Text), strings.Join(missing, ", "))
+		}
+		check(err, fmt.Sprintf("transcription contains %q", selftestExpect))
+	}
+
+	st, err := newStages(postprocess.NewWordlist())
+	if err == nil {
+		err = runPipeline(st.pipeline, st.plugins, upload.Name, result)
+	}
+	if check(err, "post-processing pipeline ran") {
+		content := st.formatter.Format(result)
+		var err error
+		if content == "" && len(result.Segments) > 0 {
+			err = fmt.Errorf("%s formatter wrote nothing", outputFormat)
+		}
+		check(err, fmt.Sprintf("%s formatter wrote %d byte(s)", outputFormat, len(content)))
+	}
+
+	// Optional tools only limit some features
+	for _, tool := range []struct{ name, use string }{
+		{"ffmpeg", "previews, files over 25 MB, --denoise, --channel, live"},
+		{"ffprobe", "durations for --confirm, --budget and --schedule"},
+		{"yt-dlp", "YouTube and other video sites"},
+	} {
+		if _, err := exec.LookPath(tool.name); err != nil {
+			fmt.Printf("- %s not found (needed for %s)\n", tool.name, tool.use)
+		} else {
+			fmt.Printf("✓ %s found\n", tool.name)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("self-test failed: %d check(s)", failed)
+	}
+	fmt.Println("Self-test passed")
+	return nil
+}
+
+// checkResponse checks that a result has what the rest of whisper-lrc
+// relies on: a duration for usage and budgets, and segments in order
+// within it
+func checkResponse(result *whisper.TranscriptionResult) error {
+	if result.Duration <= 0 {
+		return fmt.Errorf("response has no duration; usage, budgets and summaries will count no audio")
+	}
+	last := 0.0
+	for i, seg := range result.Segments {
+		if seg.End < seg.Start || seg.Start < last {
+			return fmt.Errorf("segment %d has out-of-order timestamps (%.2f-%.2f)", i+1, seg.Start, seg.End)
+		}
+		if seg.Start > result.Duration+1 {
+			return fmt.Errorf("segment %d starts at %.2f, after the end of the audio (%.2f)", i+1, seg.Start, result.Duration)
+		}
+		last = seg.Start
+	}
+	return nil
+}
+
+// missingWords returns the words of expect that the transcription lacks
+func missingWords(result *whisper.TranscriptionResult, expect string) []string {
+	var text strings.Builder
+	text.WriteString(result.Text)
+	for _, seg := range result.Segments {
+		text.WriteString(" " + seg.Text)
+	}
+	have := strings.ToLower(text.String())
+	var missing []string
+	for _, word := range strings.Fields(strings.ToLower(expect)) {
+		if !strings.Contains(have, word) {
+			missing = append(missing, word)
+		}
+	}
+	return missing
+}
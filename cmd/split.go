@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/BBleae/whisper-lrc/internal/audio"
+	"github.com/BBleae/whisper-lrc/internal/summary"
+	"github.com/BBleae/whisper-lrc/internal/translate"
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// transcribeSplit is transcribe for audio of any size. Audio over the API's
+// upload limit is cut into parts that are transcribed two at a time and
+// stitched back into one result; status reports which part is being sent.
+// Parts are cut as they are needed, with lengths chosen by a chunkSizer
+// between --chunk-min and --chunk-max; without ffprobe to measure the
+// audio, it is cut up front into parts of --chunk-max. If emit is not nil,
+// it is called with each part, in order, as soon as the part is joined,
+// together with the offset of the part in the audio.
+func transcribeSplit(client *whisper.Client, translator *translate.Translator, upload whisper.Audio, status func(string), emit func(part *whisper.TranscriptionResult, offset float64)) (result, translation *whisper.TranscriptionResult, billed float64, err error) {
+	size := int64(len(upload.Data))
+	if upload.Data == nil {
+		info, err := os.Stat(upload.Path)
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("failed to read audio: %w", err)
+		}
+		size = info.Size()
+	}
+	if size <= whisper.MaxUploadBytes {
+		result, translation, billed, err = transcribe(client, translator, upload)
+		if emit != nil && err == nil {
+			emit(result, 0)
+		}
+		return result, translation, billed, err
+	}
+
+	path := upload.Path
+	if upload.Data != nil {
+		if path, err = spillAudio(upload); err != nil {
+			return nil, nil, 0, err
+		}
+		defer os.Remove(path)
+	}
+	splitFailed := func(err error) error {
+		return fmt.Errorf("audio is %.1f MB, over the %d MB upload limit, and could not be split: %w",
+			float64(size)/(1<<20), whisper.MaxUploadBytes>>20, err)
+	}
+
+	sizer := &chunkSizer{shortest: chunkMin, longest: chunkMax}
+	total, probeErr := audio.Duration(path)
+	var fixed []audio.Chunk
+	if probeErr != nil {
+		status("Splitting audio...")
+		chunks, dir, err := audio.Split(path, chunkMax)
+		if err != nil {
+			return nil, nil, 0, splitFailed(err)
+		}
+		defer os.RemoveAll(dir)
+		fixed = chunks
+	}
+	next := func(index int, offset time.Duration) (chunk audio.Chunk, length time.Duration, ok bool, err error) {
+		if fixed != nil {
+			if index >= len(fixed) {
+				return chunk, 0, false, nil
+			}
+			return fixed[index], chunkMax, true, nil
+		}
+		if offset >= total {
+			return chunk, 0, false, nil
+		}
+		length = sizer.next(total - offset)
+		clipPath, err := audio.Clip(path, offset, length)
+		return audio.Chunk{Index: index, Path: clipPath, Offset: offset}, length, true, err
+	}
+	label := func(index int) string {
+		if fixed != nil {
+			return fmt.Sprintf("part %d of %d", index+1, len(fixed))
+		}
+		return fmt.Sprintf("part %d", index+1)
+	}
+
+	// One part uploads while the server works on the one before it
+	type splitPart struct {
+		chunk               audio.Chunk
+		length              time.Duration
+		done                chan struct{}
+		result, translation *whisper.TranscriptionResult
+		billed              float64
+		err                 error
+	}
+	var queue []*splitPart
+	collect := func() error {
+		p := queue[0]
+		queue = queue[1:]
+		<-p.done
+		billed += p.billed
+		if p.err != nil {
+			return fmt.Errorf("%s: %w", label(p.chunk.Index), p.err)
+		}
+		if fixed == nil {
+			sizer.observe(p.length, p.result.Timing)
+		}
+		if emit != nil {
+			emit(p.result, p.chunk.Offset.Seconds())
+		}
+		if result == nil {
+			result, translation = p.result, p.translation
+			return nil
+		}
+		offset := p.chunk.Offset.Seconds()
+		result.Append(p.result, offset)
+		if translation != nil && p.translation != nil {
+			translation.Append(p.translation, offset)
+		}
+		return nil
+	}
+	fail := func(err error) (*whisper.TranscriptionResult, *whisper.TranscriptionResult, float64, error) {
+		for _, p := range queue {
+			<-p.done
+			billed += p.billed
+		}
+		return nil, nil, billed, err
+	}
+
+	var offset time.Duration
+	for index := 0; ; index++ {
+		chunk, length, ok, err := next(index, offset)
+		if err != nil {
+			return fail(splitFailed(err))
+		}
+		if !ok {
+			break
+		}
+		offset += length
+		if fixed == nil {
+			status(fmt.Sprintf("Transcribing part %d (%s of %s)...", index+1, summary.Clock(chunk.Offset), summary.Clock(total)))
+		} else {
+			status(fmt.Sprintf("Transcribing %s...", label(index)))
+		}
+		p := &splitPart{chunk: chunk, length: length, done: make(chan struct{})}
+		go func() {
+			defer close(p.done)
+			defer os.Remove(p.chunk.Path)
+			p.result, p.translation, p.billed, p.err = transcribe(client, translator, whisper.Audio{Path: p.chunk.Path})
+		}()
+		queue = append(queue, p)
+		if len(queue) == 2 {
+			if err := collect(); err != nil {
+				return fail(err)
+			}
+		}
+	}
+	for len(queue) > 0 {
+		if err := collect(); err != nil {
+			return fail(err)
+		}
+	}
+	return result, translation, billed, nil
+}
+
+// spillAudio writes audio held in memory to a temporary file for ffmpeg
+func spillAudio(upload whisper.Audio) (string, error) {
+	tmpFile, err := os.CreateTemp("", "whisper-lrc-*"+filepath.Ext(upload.Name))
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	_, err = tmpFile.Write(upload.Data)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+	return tmpFile.Name(), nil
+}
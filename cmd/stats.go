@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/BBleae/whisper-lrc/internal/history"
+	"github.com/BBleae/whisper-lrc/internal/metrics"
+	"github.com/spf13/cobra"
+)
+
+var statsDays int
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Summarize transcription usage from the history",
+	Long: `Summarize the usage history recorded by earlier runs: files and audio
+minutes per day, estimated cost per backend, failure rates and the average
+realtime factor (audio length divided by transcription time).
+
+Runs made with --metrics add where the time went (downloading versus
+transcribing) and why files failed. The metrics file is local only and is
+never sent anywhere.
+
+Examples:
+  whisper-lrc stats
+  whisper-lrc stats --days 7`,
+	Args: cobra.NoArgs,
+	RunE: runStats,
+}
+
+func init() {
+	statsCmd.Flags().IntVar(&statsDays, "days", 30, "Number of days to include (0 for all history)")
+	rootCmd.AddCommand(statsCmd)
+}
+
+// usageTotals aggregates history records
+type usageTotals struct {
+	files      int
+	failed     int
+	audio      float64
+	cost       float64
+	processing float64
+}
+
+func (t *usageTotals) add(r history.Record) {
+	t.files++
+	if r.Failed {
+		t.failed++
+		return
+	}
+	t.audio += r.AudioSeconds
+	t.cost += r.Cost
+	t.processing += r.ProcessingSeconds
+}
+
+func (t *usageTotals) failureRate() float64 {
+	if t.files == 0 {
+		return 0
+	}
+	return float64(t.failed) / float64(t.files) * 100
+}
+
+// realtimeFactor returns how many seconds of audio were transcribed per
+// second of waiting
+func (t *usageTotals) realtimeFactor() float64 {
+	if t.processing == 0 {
+		return 0
+	}
+	return t.audio / t.processing
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	path, err := history.DefaultPath()
+	if err != nil {
+		return err
+	}
+	records, err := history.Open(path).Load()
+	if err != nil {
+		return err
+	}
+	metricsPath, err := metrics.DefaultPath()
+	if err != nil {
+		return err
+	}
+	runs, err := metrics.Open(metricsPath).Load()
+	if err != nil {
+		return err
+	}
+	if statsDays > 0 {
+		now := time.Now()
+		start := time.Date(now.Year(), now.Month(), now.Day()-statsDays+1, 0, 0, 0, 0, now.Location())
+		records = history.Since(records, start)
+		runs = metrics.Since(runs, start)
+	}
+	if len(records) == 0 && len(runs) == 0 {
+		fmt.Println("No usage recorded yet")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if len(records) > 0 {
+		printUsage(w, records)
+	}
+	if len(runs) > 0 {
+		if len(records) > 0 {
+			fmt.Fprintln(w)
+		}
+		printRuns(w, runs)
+	}
+	return w.Flush()
+}
+
+// printUsage prints usage per day and backend from the history
+func printUsage(w io.Writer, records []history.Record) {
+	var total usageTotals
+	days := map[string]*usageTotals{}
+	backends := map[string]*usageTotals{}
+	for _, r := range records {
+		total.add(r)
+
+		day := r.Time.Local().Format("2006-01-02")
+		if days[day] == nil {
+			days[day] = &usageTotals{}
+		}
+		days[day].add(r)
+
+		if backends[r.Backend] == nil {
+			backends[r.Backend] = &usageTotals{}
+		}
+		backends[r.Backend].add(r)
+	}
+
+	fmt.Fprintln(w, "DAY\tFILES\tMINUTES\tCOST\tFAILED")
+	for _, day := range sortedKeys(days) {
+		t := days[day]
+		fmt.Fprintf(w, "%s\t%d\t%.1f\t$%.2f\t%d\n", day, t.files, t.audio/60, t.cost, t.failed)
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(w, "BACKEND\tFILES\tMINUTES\tCOST\tFAILURE RATE\tREALTIME")
+	for _, name := range sortedKeys(backends) {
+		t := backends[name]
+		fmt.Fprintf(w, "%s\t%d\t%.1f\t$%.2f\t%.1f%%\t%.1fx\n", name, t.files, t.audio/60, t.cost, t.failureRate(), t.realtimeFactor())
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "Total:\t%d file(s), %.1f minutes, $%.2f estimated\n", total.files, total.audio/60, total.cost)
+	fmt.Fprintf(w, "Failure rate:\t%.1f%%\n", total.failureRate())
+	fmt.Fprintf(w, "Realtime factor:\t%.1fx\n", total.realtimeFactor())
+}
+
+// printRuns prints where batch time went and why files failed from the
+// metrics file
+func printRuns(w io.Writer, runs []metrics.Run) {
+	var files, failed int
+	var seconds, audioSeconds, download, transcribe float64
+	failures := map[string]int{}
+	for _, r := range runs {
+		files += r.Files
+		failed += r.Failed
+		seconds += r.Seconds
+		audioSeconds += r.AudioSeconds
+		download += r.DownloadSeconds
+		transcribe += r.TranscribeSeconds
+		for category, n := range r.Failures {
+			failures[category] += n
+		}
+	}
+
+	fmt.Fprintf(w, "Runs:\t%d, %d file(s), %.1f minutes of audio\n", len(runs), files, audioSeconds/60)
+	if files > 0 {
+		fmt.Fprintf(w, "Per file:\t%.1fs total, %.1fs downloading, %.1fs transcribing\n",
+			seconds/float64(files), download/float64(files), transcribe/float64(files))
+	}
+	if seconds > 0 {
+		fmt.Fprintf(w, "Throughput:\t%.1f minutes of audio per minute\n", audioSeconds/seconds)
+	}
+	if failed == 0 {
+		return
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "FAILURE\tFILES\tSHARE")
+	categories := make([]string, 0, len(failures))
+	for category := range failures {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	for _, category := range categories {
+		n := failures[category]
+		fmt.Fprintf(w, "%s\t%d\t%.1f%%\n", category, n, float64(n)/float64(failed)*100)
+	}
+}
+
+func sortedKeys(m map[string]*usageTotals) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/BBleae/whisper-lrc/internal/input"
+	"github.com/BBleae/whisper-lrc/internal/manifest"
+)
+
+// openManifest loads the --sync manifest, or returns nil without --sync
+func openManifest() (*manifest.Manifest, error) {
+	if syncManifest == "" {
+		return nil, nil
+	}
+	return manifest.Load(syncManifest)
+}
+
+// syncKey names an input in the manifest. Local paths are made absolute so
+// that runs from another directory, such as cron jobs, find them.
+func syncKey(arg string) string {
+	if strings.Contains(arg, "://") {
+		return arg
+	}
+	if abs, err := filepath.Abs(arg); err == nil {
+		return abs
+	}
+	return arg
+}
+
+// audioHash hashes the audio of a resolved input, in memory or on disk
+func audioHash(src *input.Source) (string, error) {
+	if src.Data != nil {
+		return manifest.Hash(src.Data), nil
+	}
+	return manifest.HashFile(src.Path)
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/BBleae/whisper-lrc/internal/translate"
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// validateTranslation checks the translation flags
+func validateTranslation() error {
+	if translateAll && len(translateIf) > 0 {
+		return fmt.Errorf("--translate and --translate-if cannot be combined")
+	}
+	if translateAll && alsoTranslate {
+		return fmt.Errorf("--translate and --also-translate cannot be combined")
+	}
+	return nil
+}
+
+// newTranslator returns the chat model translator for --translate-to, or nil
+// when translations use the Whisper endpoint
+func newTranslator(client *whisper.Client) *translate.Translator {
+	if translateTo == "" {
+		return nil
+	}
+	return translate.New(client, translateModel, translateTo)
+}
+
+// translationTarget returns the language code translations are made into
+func translationTarget() string {
+	if translateTo != "" {
+		return whisper.LanguageCode(translateTo)
+	}
+	return "en"
+}
+
+// wantTranslation reports whether audio in the given language is translated
+func wantTranslation(lang string) bool {
+	code := whisper.LanguageCode(lang)
+	if code == translationTarget() {
+		return false
+	}
+	if len(translateIf) > 0 {
+		for _, want := range translateIf {
+			if whisper.LanguageCode(want) == code {
+				return true
+			}
+		}
+		return false
+	}
+	return translateAll || alsoTranslate || translateTo != ""
+}
+
+// translatesInPlace reports whether the lyrics may be replaced by their
+// translation, so that they are not a transcription of the audio
+func translatesInPlace() bool {
+	return !alsoTranslate && (translateAll || len(translateIf) > 0 || translateTo != "")
+}
+
+// lyricsLanguage returns the language the lyrics of audio in lang are
+// written in, which is the translation target when they are translated in
+// place
+func lyricsLanguage(lang string) string {
+	if translateAll || (!alsoTranslate && wantTranslation(lang)) {
+		return translationTarget()
+	}
+	return lang
+}
+
+// transcribe transcribes or translates the audio as selected by the flags and
+// returns the result with the seconds of audio billed for it.
+//
+// English translations normally come from the Whisper translation endpoint.
+// When the language is not known upfront (--translate-if without --language)
+// the audio is transcribed first and sent again if it matches. With
+// --translate-to, the transcription is translated by a chat model instead and
+// timestamps are kept. With --also-translate, the original transcription is
+// returned along with the translation (nil when not wanted).
+func transcribe(client *whisper.Client, translator *translate.Translator, audio whisper.Audio) (result, translation *whisper.TranscriptionResult, billed float64, err error) {
+	// The Whisper endpoint can translate without a transcription first
+	if translator == nil && !alsoTranslate && (translateAll || (language != "" && wantTranslation(language))) {
+		translated, err := client.TranslateAudio(audio, effectivePrompt())
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		return translated, nil, translated.Duration, nil
+	}
+
+	result, err = client.TranscribeAudio(audio, language, effectivePrompt())
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	billed = result.Duration
+
+	detected := result.Language
+	if language != "" {
+		detected = language
+	}
+	if !wantTranslation(detected) {
+		return result, nil, billed, nil
+	}
+
+	var translated *whisper.TranscriptionResult
+	if translator != nil {
+		translated, err = translator.Translate(result)
+	} else {
+		translated, err = client.TranslateAudio(audio, effectivePrompt())
+		if err == nil {
+			billed += translated.Duration
+		}
+	}
+	if err != nil {
+		return nil, nil, billed, fmt.Errorf("translation failed: %w", err)
+	}
+
+	if alsoTranslate {
+		return result, translated, billed, nil
+	}
+	translated.Timing.Add(result.Timing)
+	return translated, nil, billed, nil
+}
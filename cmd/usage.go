@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/BBleae/whisper-lrc/internal/budget"
+	"github.com/BBleae/whisper-lrc/internal/history"
+	"github.com/BBleae/whisper-lrc/internal/input"
+	"github.com/BBleae/whisper-lrc/internal/metrics"
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// backendName identifies the transcription server in the usage history
+func backendName() string {
+	if apiBase == "" {
+		return "openai"
+	}
+	if strings.HasPrefix(apiBase, "unix://") {
+		return "unix"
+	}
+	if u, err := url.Parse(apiBase); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return apiBase
+}
+
+// usageCost returns the estimated cost of transcribing audio of the given
+// length; only the OpenAI API and Azure OpenAI, at the same price, are
+// billed
+func usageCost(seconds float64) float64 {
+	if apiBase != "" && provider != "azure" {
+		return 0
+	}
+	return whisper.EstimateCost(time.Duration(seconds * float64(time.Second)))
+}
+
+// openHistory returns the usage history store, or nil with --no-history
+func openHistory() (*history.Store, error) {
+	if noHistory {
+		return nil, nil
+	}
+	path, err := history.DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return history.Open(path), nil
+}
+
+// loadBudget parses --budget and counts earlier usage in the budget period
+func loadBudget(store *history.Store) (*budget.Budget, error) {
+	if budgetLimit == "" {
+		return nil, nil
+	}
+	b, err := budget.Parse(budgetLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	start, err := budget.PeriodStart(budgetPeriod, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if start.IsZero() {
+		return b, nil
+	}
+	if store == nil {
+		return nil, fmt.Errorf("--budget-period %s needs the usage history; remove --no-history", budgetPeriod)
+	}
+
+	records, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range history.Since(records, start) {
+		b.Add(r.AudioSeconds, r.Cost)
+	}
+	return b, nil
+}
+
+// recordUsage appends a transcription attempt to the usage history
+func recordUsage(store *history.Store, seconds float64, elapsed time.Duration, failed bool) error {
+	if store == nil {
+		return nil
+	}
+	return store.Append(history.Record{
+		Time:              time.Now(),
+		Backend:           backendName(),
+		AudioSeconds:      seconds,
+		Cost:              usageCost(seconds),
+		ProcessingSeconds: elapsed.Seconds(),
+		Failed:            failed,
+	})
+}
+
+// recordRun appends the timing of a batch to the local metrics file when
+// --metrics is set
+func recordRun(run *metrics.Run) error {
+	if !recordMetrics || run.Files == 0 {
+		return nil
+	}
+	path, err := metrics.DefaultPath()
+	if err != nil {
+		return err
+	}
+	run.Seconds = time.Since(run.Time).Seconds()
+	return metrics.Open(path).Append(*run)
+}
+
+// failureCategory classifies why a file failed for the metrics
+func failureCategory(err error) string {
+	switch {
+	case errors.Is(err, whisper.ErrAuth):
+		return metrics.FailureAuth
+	case errors.Is(err, whisper.ErrRateLimited):
+		return metrics.FailureRateLimited
+	case errors.Is(err, whisper.ErrTooLarge):
+		return metrics.FailureTooLarge
+	case errors.Is(err, input.ErrDownload):
+		return metrics.FailureDownload
+	case errors.Is(err, input.ErrUnsupportedFormat):
+		return metrics.FailureUnsupportedFormat
+	default:
+		return metrics.FailureOther
+	}
+}
@@ -0,0 +1,190 @@
+package audio
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MicrophoneInput returns the ffmpeg input arguments for capturing from a
+// microphone. An empty device selects the system default where one exists.
+func MicrophoneInput(device string) ([]string, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		if device == "" {
+			device = ":0"
+		}
+		return []string{"-f", "avfoundation", "-i", device}, nil
+	case "windows":
+		if device == "" {
+			return nil, fmt.Errorf("a capture device name is required on Windows (list them with: ffmpeg -list_devices true -f dshow -i dummy)")
+		}
+		return []string{"-f", "dshow", "-i", "audio=" + device}, nil
+	default:
+		if device == "" {
+			device = "default"
+		}
+		return []string{"-f", "pulse", "-i", device}, nil
+	}
+}
+
+// StreamInput returns the ffmpeg input arguments for reading a network audio
+// stream (Icecast/Shoutcast, HLS playlists, plain HTTP audio)
+func StreamInput(url string) []string {
+	var args []string
+	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+		// Ride out dropped connections on long-running streams
+		args = append(args, "-reconnect", "1", "-reconnect_streamed", "1", "-reconnect_delay_max", "10")
+	}
+	return append(args, "-i", url)
+}
+
+// Chunk is a finished piece of recorded audio
+type Chunk struct {
+	Index  int
+	Path   string
+	Offset time.Duration // start time of the chunk relative to the recording start
+}
+
+// Recorder captures audio with ffmpeg into fixed-length WAV chunks. A single
+// ffmpeg process writes all chunks, so no audio is lost between them.
+type Recorder struct {
+	dir    string
+	length time.Duration
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stderr bytes.Buffer
+	chunks chan Chunk
+	exited chan struct{}
+	err    error
+}
+
+// StartRecorder starts capturing from the given ffmpeg input arguments
+func StartRecorder(inputArgs []string, length time.Duration) (*Recorder, error) {
+	ffmpeg, err := lookFFmpeg()
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "whisper-lrc-live-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+
+	r := &Recorder{
+		dir:    dir,
+		length: length,
+		chunks: make(chan Chunk, 16),
+		exited: make(chan struct{}),
+	}
+
+	args := []string{"-hide_banner", "-loglevel", "error"}
+	args = append(args, inputArgs...)
+	args = append(args,
+		"-ac", "1", // Mono
+		"-ar", "16000", // Whisper's native sample rate
+		"-f", "segment",
+		"-segment_time", strconv.FormatFloat(length.Seconds(), 'f', 3, 64),
+		"-reset_timestamps", "1",
+		filepath.Join(dir, "chunk%06d.wav"),
+	)
+	r.cmd = exec.Command(ffmpeg, args...)
+	r.cmd.Stderr = &r.stderr
+	if r.stdin, err = r.cmd.StdinPipe(); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to open ffmpeg stdin: %w", err)
+	}
+	if err := r.cmd.Start(); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+
+	go func() {
+		if err := r.cmd.Wait(); err != nil {
+			r.err = fmt.Errorf("ffmpeg failed: %w\nOutput: %s", err, strings.TrimSpace(r.stderr.String()))
+		}
+		close(r.exited)
+	}()
+	go r.watch()
+
+	return r, nil
+}
+
+// Chunks returns finished chunks in recording order. The channel is closed
+// once recording has stopped and the last chunk has been delivered.
+func (r *Recorder) Chunks() <-chan Chunk {
+	return r.chunks
+}
+
+// Err returns the ffmpeg error, if any, once Chunks has been closed
+func (r *Recorder) Err() error {
+	return r.err
+}
+
+// Stop asks ffmpeg to finish the current chunk and exit
+func (r *Recorder) Stop() {
+	// "q" on stdin makes ffmpeg finalize its output files, on every platform
+	_, _ = io.WriteString(r.stdin, "q")
+	r.stdin.Close()
+}
+
+// Close stops recording and removes all chunk files
+func (r *Recorder) Close() {
+	r.Stop()
+	select {
+	case <-r.exited:
+	case <-time.After(5 * time.Second):
+		_ = r.cmd.Process.Kill()
+		<-r.exited
+	}
+	os.RemoveAll(r.dir)
+}
+
+func (r *Recorder) chunkPath(index int) string {
+	return filepath.Join(r.dir, fmt.Sprintf("chunk%06d.wav", index))
+}
+
+// watch delivers a chunk once ffmpeg has moved on to the next one, or once
+// ffmpeg has exited
+func (r *Recorder) watch() {
+	defer close(r.chunks)
+
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	next := 0
+	emit := func() {
+		r.chunks <- Chunk{
+			Index:  next,
+			Path:   r.chunkPath(next),
+			Offset: time.Duration(next) * r.length,
+		}
+		next++
+	}
+
+	for {
+		select {
+		case <-r.exited:
+			for fileExists(r.chunkPath(next)) {
+				emit()
+			}
+			return
+		case <-ticker.C:
+			for fileExists(r.chunkPath(next + 1)) {
+				emit()
+			}
+		}
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,28 @@
+package audio
+
+import "fmt"
+
+// channelFilters maps channels to the ffmpeg filters that extract them
+var channelFilters = map[string]string{
+	"left":  "pan=mono|c0=FL",
+	"right": "pan=mono|c0=FR",
+	// The surround upmixer steers what both channels share, usually the
+	// lead vocal, to the center channel and leaves the rest at the sides
+	"vocal-center": "surround=chl_out=3.0,pan=mono|c0=FC",
+}
+
+// SelectChannel writes one channel of a stereo audio file to a temporary
+// 16kHz mono WAV file and returns its path: left or right for recordings
+// with the vocals on one side, or vocal-center for the center of the mix,
+// where most songs place the lead vocal. "mix" returns path unchanged, as
+// the upload is mixed down anyway. The caller removes a new file when done.
+func SelectChannel(path, channel string) (string, error) {
+	if channel == "mix" {
+		return path, nil
+	}
+	filter, ok := channelFilters[channel]
+	if !ok {
+		return "", fmt.Errorf("unknown channel %q", channel)
+	}
+	return extract(path, "whisper-lrc-channel-*.wav", nil, []string{"-af", filter})
+}
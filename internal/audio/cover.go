@@ -0,0 +1,30 @@
+package audio
+
+import (
+	"fmt"
+	"os"
+)
+
+// ExtractCover writes the cover art embedded in an audio file to a temporary
+// JPEG file and returns its path. The caller removes the file when done.
+func ExtractCover(path string) (string, error) {
+	tmpFile, err := os.CreateTemp("", "whisper-lrc-cover-*.jpg")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	tmpFile.Close()
+
+	err = runFFmpeg(
+		"-i", path,
+		"-an",
+		"-map", "0:v:0", // The attached picture
+		"-frames:v", "1",
+		tmpPath,
+	)
+	if err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to extract cover art from %s: %w", path, err)
+	}
+	return tmpPath, nil
+}
@@ -0,0 +1,14 @@
+package audio
+
+// Denoise writes the audio file with background noise reduced to a
+// temporary 16kHz mono WAV file and returns its path. Without a model it
+// uses ffmpeg's FFT denoiser (afftdn), which suits steady hiss and hum; with
+// an RNNoise model file (.rnnn) it uses the arnndn filter, which also
+// handles crowd and room noise. The caller removes the file when done.
+func Denoise(path, model string) (string, error) {
+	filter := "afftdn"
+	if model != "" {
+		filter = "arnndn=m=" + filterPath(model)
+	}
+	return extract(path, "whisper-lrc-denoised-*.wav", nil, []string{"-af", filter})
+}
@@ -0,0 +1,31 @@
+package audio
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// lookFFmpeg returns the path of the ffmpeg binary
+func lookFFmpeg() (string, error) {
+	path, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return "", fmt.Errorf("ffmpeg not found. Please install it: https://ffmpeg.org/download.html")
+	}
+	return path, nil
+}
+
+// runFFmpeg runs ffmpeg to completion, including its log output in errors
+func runFFmpeg(args ...string) error {
+	ffmpeg, err := lookFFmpeg()
+	if err != nil {
+		return err
+	}
+
+	args = append([]string{"-hide_banner", "-loglevel", "error", "-nostdin", "-y"}, args...)
+	output, err := exec.Command(ffmpeg, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ffmpeg failed: %w\nOutput: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
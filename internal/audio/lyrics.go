@@ -0,0 +1,202 @@
+package audio
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+)
+
+// EmbeddedLyrics are lyrics found in the tags of an audio file
+type EmbeddedLyrics struct {
+	// Text is the unsynchronized lyrics text, which may itself be LRC
+	Text string
+	// Synced is set for an ID3 SYLT (synchronized lyrics) frame
+	Synced bool
+}
+
+// HasEmbeddedLyrics reports whether an audio file's tags hold lyrics
+func HasEmbeddedLyrics(path string) (bool, error) {
+	lyrics, err := ReadEmbeddedLyrics(path)
+	return lyrics != nil, err
+}
+
+// ReadEmbeddedLyrics returns the lyrics in an audio file's tags, or nil if
+// there are none: ID3 USLT (unsynchronized) and SYLT (synchronized) frames
+// in MP3 files, read directly, or a lyrics tag in FLAC, Ogg and M4A files,
+// read with ffprobe. Other formats have no lyrics tags.
+func ReadEmbeddedLyrics(path string) (*EmbeddedLyrics, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch {
+	case ext == ".mp3":
+		return id3Lyrics(path)
+	case lyricsContainers[ext]:
+		return probeLyrics(path)
+	default:
+		return nil, nil
+	}
+}
+
+// id3Lyrics reads the lyrics frames of an ID3v2 tag
+func id3Lyrics(path string) (*EmbeddedLyrics, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(f, header); err != nil || string(header[:3]) != "ID3" {
+		return nil, nil
+	}
+	version, flags := header[3], header[5]
+	tag := make([]byte, synchsafe(header[6:10]))
+	if _, err := io.ReadFull(f, tag); err != nil {
+		return nil, nil
+	}
+
+	// Skip the extended header; its size excludes itself in v2.3
+	if flags&0x40 != 0 && len(tag) >= 4 {
+		skip := int(binary.BigEndian.Uint32(tag)) + 4
+		if version >= 4 {
+			skip = synchsafe(tag[:4])
+		}
+		if skip > len(tag) {
+			return nil, nil
+		}
+		tag = tag[skip:]
+	}
+
+	// v2.2 frames have 3-byte IDs and sizes; later versions 4-byte ones and
+	// two flag bytes
+	idLen, headerLen := 4, 10
+	unsynced, synced := "USLT", "SYLT"
+	if version == 2 {
+		idLen, headerLen = 3, 6
+		unsynced, synced = "ULT", "SLT"
+	}
+	var lyrics *EmbeddedLyrics
+	for len(tag) >= headerLen && tag[0] != 0 {
+		id := string(tag[:idLen])
+		var size int
+		switch version {
+		case 2:
+			size = int(tag[3])<<16 | int(tag[4])<<8 | int(tag[5])
+		case 3:
+			size = int(binary.BigEndian.Uint32(tag[4:8]))
+		default:
+			size = synchsafe(tag[4:8])
+		}
+		if size < 0 || headerLen+size > len(tag) {
+			break
+		}
+		data := tag[headerLen : headerLen+size]
+		tag = tag[headerLen+size:]
+
+		// Encoding, language and an empty description take 5 bytes
+		if (id != unsynced && id != synced) || size <= 5 {
+			continue
+		}
+		if lyrics == nil {
+			lyrics = &EmbeddedLyrics{}
+		}
+		if id == synced {
+			lyrics.Synced = true
+		} else if lyrics.Text == "" {
+			lyrics.Text = usltText(data)
+		}
+	}
+	return lyrics, nil
+}
+
+// usltText decodes the text of a USLT frame: an encoding byte, a language,
+// a terminated description and the text
+func usltText(data []byte) string {
+	encoding, rest := data[0], data[4:]
+	terminator := []byte{0}
+	if encoding == 1 || encoding == 2 {
+		terminator = []byte{0, 0}
+	}
+	// The description ends at the first terminator on a character boundary
+	for i := 0; i+len(terminator) <= len(rest); i += len(terminator) {
+		if string(rest[i:i+len(terminator)]) == string(terminator) {
+			rest = rest[i+len(terminator):]
+			break
+		}
+	}
+
+	switch encoding {
+	case 0: // ISO-8859-1
+		runes := make([]rune, len(rest))
+		for i, b := range rest {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	case 1, 2: // UTF-16 with a byte order mark, or big endian
+		var order binary.ByteOrder = binary.BigEndian
+		if len(rest) >= 2 && rest[0] == 0xff && rest[1] == 0xfe {
+			order, rest = binary.LittleEndian, rest[2:]
+		} else if len(rest) >= 2 && rest[0] == 0xfe && rest[1] == 0xff {
+			rest = rest[2:]
+		}
+		units := make([]uint16, len(rest)/2)
+		for i := range units {
+			units[i] = order.Uint16(rest[2*i:])
+		}
+		return string(utf16.Decode(units))
+	default: // UTF-8
+		return string(rest)
+	}
+}
+
+// synchsafe decodes a 28-bit ID3 integer stored in 4 bytes of 7 bits
+func synchsafe(b []byte) int {
+	return int(b[0]&0x7f)<<21 | int(b[1]&0x7f)<<14 | int(b[2]&0x7f)<<7 | int(b[3]&0x7f)
+}
+
+// probeLyrics looks for a lyrics tag (LYRICS, UNSYNCEDLYRICS, ©lyr and the
+// like) in the container and stream tags
+func probeLyrics(path string) (*EmbeddedLyrics, error) {
+	ffprobe, err := exec.LookPath("ffprobe")
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe not found. Please install ffmpeg: https://ffmpeg.org/download.html")
+	}
+	output, err := exec.Command(ffprobe,
+		"-v", "error",
+		"-show_entries", "format_tags:stream_tags",
+		"-of", "json",
+		path,
+	).Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe failed: %w", err)
+	}
+
+	var probe struct {
+		Format struct {
+			Tags map[string]string `json:"tags"`
+		} `json:"format"`
+		Streams []struct {
+			Tags map[string]string `json:"tags"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return nil, fmt.Errorf("ffprobe returned invalid output for %s: %w", path, err)
+	}
+	tags := []map[string]string{probe.Format.Tags}
+	for _, s := range probe.Streams {
+		tags = append(tags, s.Tags)
+	}
+	for _, t := range tags {
+		for key, value := range t {
+			if strings.Contains(strings.ToLower(key), "lyrics") && strings.TrimSpace(value) != "" {
+				return &EmbeddedLyrics{Text: value}, nil
+			}
+		}
+	}
+	return nil, nil
+}
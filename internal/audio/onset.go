@@ -0,0 +1,150 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+const (
+	onsetRate   = 16000
+	onsetHop    = 160 // 10ms
+	onsetWindow = 512 // 32ms
+
+	// onsetFloor ignores onsets more than 40dB below the loudest frame
+	onsetFloor = 40.0
+)
+
+// Onsets returns the times in seconds where the vocal range of the audio gets
+// suddenly louder, such as a singer starting a line. The audio is band-passed
+// to the voice range and onsets are picked as peaks of the rise in frame
+// energy.
+func Onsets(path string) ([]float64, error) {
+	samples, err := decodePCM(path, onsetRate, "highpass=f=200,lowpass=f=3500")
+	if err != nil {
+		return nil, err
+	}
+	return detectOnsets(samples), nil
+}
+
+// decodePCM decodes the audio to mono samples at the given rate through an
+// ffmpeg filter
+func decodePCM(path string, rate int, filter string) ([]int16, error) {
+	ffmpeg, err := lookFFmpeg()
+	if err != nil {
+		return nil, err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(ffmpeg,
+		"-hide_banner", "-loglevel", "error", "-nostdin",
+		"-i", path,
+		"-vn",
+		"-af", filter,
+		"-ac", "1",
+		"-ar", strconv.Itoa(rate),
+		"-f", "s16le",
+		"-",
+	)
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run ffmpeg: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to run ffmpeg: %w", err)
+	}
+
+	// Convert while decoding rather than holding the raw output as well
+	var samples []int16
+	chunk := make([]byte, 64<<10)
+	var readErr error
+	for {
+		n, err := io.ReadFull(stdout, chunk)
+		for i := 0; i+1 < n; i += 2 {
+			samples = append(samples, int16(binary.LittleEndian.Uint16(chunk[i:])))
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			// ffmpeg would block on the full pipe
+			cmd.Process.Kill()
+			readErr = err
+			break
+		}
+	}
+	waitErr := cmd.Wait()
+	if readErr != nil {
+		return nil, fmt.Errorf("failed to read decoded audio: %w", readErr)
+	}
+	if waitErr != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %w\nOutput: %s", waitErr, strings.TrimSpace(stderr.String()))
+	}
+	return samples, nil
+}
+
+// detectOnsets picks peaks of the rise in log frame energy that stand out
+// from their surroundings
+func detectOnsets(samples []int16) []float64 {
+	if len(samples) < onsetWindow {
+		return nil
+	}
+
+	// Log energy per frame
+	frames := (len(samples)-onsetWindow)/onsetHop + 1
+	level := make([]float64, frames)
+	loudest := math.Inf(-1)
+	for i := range level {
+		sum := 0.0
+		for _, s := range samples[i*onsetHop : i*onsetHop+onsetWindow] {
+			v := float64(s) / 32768
+			sum += v * v
+		}
+		level[i] = 10 * math.Log10(sum/onsetWindow+1e-10)
+		loudest = max(loudest, level[i])
+	}
+
+	// Rise against the average of the previous three frames
+	rise := make([]float64, frames)
+	for i := 3; i < frames; i++ {
+		rise[i] = max(0, level[i]-(level[i-1]+level[i-2]+level[i-3])/3)
+	}
+
+	// Peaks within ±50ms that exceed the local mean over ±500ms
+	const peak, local = 5, 50
+	var onsets []float64
+	for i := range rise {
+		if rise[i] <= 0 || level[i] < loudest-onsetFloor {
+			continue
+		}
+		isPeak := true
+		for j := max(0, i-peak); j <= min(frames-1, i+peak); j++ {
+			if rise[j] > rise[i] || (rise[j] == rise[i] && j < i) {
+				isPeak = false
+				break
+			}
+		}
+		if !isPeak {
+			continue
+		}
+
+		lo, hi := max(0, i-local), min(frames-1, i+local)
+		mean := 0.0
+		for j := lo; j <= hi; j++ {
+			mean += rise[j]
+		}
+		mean /= float64(hi - lo + 1)
+		if rise[i] > mean+1.5 { // dB
+			// The rise peaks in the first frame reaching into the new sound,
+			// so the onset is near the end of that frame
+			onsets = append(onsets, float64(i*onsetHop+onsetWindow)/onsetRate)
+		}
+	}
+	return onsets
+}
@@ -0,0 +1,113 @@
+package audio
+
+import "math"
+
+const (
+	pitchRate   = 8000
+	pitchWindow = 256 // 32ms integration window
+
+	// PitchHop is the time between pitch frames in seconds
+	PitchHop = 0.01
+
+	// Sung fundamental frequency range in Hz
+	minPitch = 70
+	maxPitch = 1000
+
+	// yinThreshold is the largest normalized difference accepted as voiced
+	yinThreshold = 0.15
+)
+
+// Pitch estimates the fundamental frequency of the voice every PitchHop
+// seconds using the YIN algorithm. Unvoiced or silent frames are 0. Without
+// an isolated vocal stem the loudest melodic line wins, which for most mixes
+// is the singer.
+func Pitch(path string) ([]float64, error) {
+	samples, err := decodePCM(path, pitchRate, "highpass=f=60,lowpass=f=1200")
+	if err != nil {
+		return nil, err
+	}
+	return detectPitch(samples), nil
+}
+
+// detectPitch runs YIN over the samples
+func detectPitch(samples []int16) []float64 {
+	hop := int(PitchHop * pitchRate)
+	minLag := pitchRate / maxPitch
+	maxLag := pitchRate / minPitch
+	if len(samples) < pitchWindow+maxLag {
+		return nil
+	}
+
+	x := make([]float64, len(samples))
+	for i, s := range samples {
+		x[i] = float64(s) / 32768
+	}
+
+	// Frame i is centered on i*PitchHop seconds
+	frames := len(x) / hop
+	f0 := make([]float64, frames)
+	level := make([]float64, frames)
+	loudest := math.Inf(-1)
+	for i := range level {
+		level[i] = math.Inf(-1)
+		start := i*hop - pitchWindow/2
+		if start < 0 || start+pitchWindow+maxLag+1 >= len(x) {
+			continue
+		}
+		sum := 0.0
+		for _, v := range x[start : start+pitchWindow] {
+			sum += v * v
+		}
+		level[i] = 10 * math.Log10(sum/pitchWindow+1e-10)
+		loudest = max(loudest, level[i])
+	}
+
+	diff := make([]float64, maxLag+2)
+	for i := range f0 {
+		// Skip frames at the edges and far below the loudest part of the song
+		if math.IsInf(level[i], -1) || level[i] < loudest-onsetFloor {
+			continue
+		}
+		frame := x[i*hop-pitchWindow/2:]
+
+		// Cumulative mean normalized difference
+		diff[0] = 1
+		running := 0.0
+		for lag := 1; lag <= maxLag+1; lag++ {
+			d := 0.0
+			for j := 0; j < pitchWindow; j++ {
+				delta := frame[j] - frame[j+lag]
+				d += delta * delta
+			}
+			running += d
+			if running == 0 {
+				diff[lag] = 1
+			} else {
+				diff[lag] = d * float64(lag) / running
+			}
+		}
+
+		// First dip under the threshold, followed down to its minimum
+		for lag := minLag; lag <= maxLag; lag++ {
+			if diff[lag] >= yinThreshold {
+				continue
+			}
+			for lag < maxLag && diff[lag+1] < diff[lag] {
+				lag++
+			}
+			f0[i] = pitchRate / refineLag(diff, lag)
+			break
+		}
+	}
+	return f0
+}
+
+// refineLag interpolates the minimum of the difference function around lag
+func refineLag(diff []float64, lag int) float64 {
+	a, b, c := diff[lag-1], diff[lag], diff[lag+1]
+	denom := a - 2*b + c
+	if denom == 0 {
+		return float64(lag)
+	}
+	return float64(lag) + (a-c)/(2*denom)
+}
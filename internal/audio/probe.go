@@ -0,0 +1,33 @@
+package audio
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration returns the length of an audio file as reported by ffprobe
+func Duration(path string) (time.Duration, error) {
+	ffprobe, err := exec.LookPath("ffprobe")
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe not found. Please install ffmpeg: https://ffmpeg.org/download.html")
+	}
+
+	output, err := exec.Command(ffprobe,
+		"-v", "error",
+		"-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		path,
+	).Output()
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe failed: %w", err)
+	}
+
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(string(output)), 64)
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe returned no duration for %s", path)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
@@ -0,0 +1,61 @@
+package audio
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SplitLength is the default length of split chunks. At 16kHz mono a chunk
+// is about 19 MB, under the 25 MB upload limit of the OpenAI API.
+const SplitLength = 10 * time.Minute
+
+// wavByteRate is the data rate of the 16kHz mono 16-bit WAV files written
+// by Split and Clip
+const wavByteRate = 16000 * 2
+
+// MaxChunkLength returns the longest chunk Split or Clip can write within
+// limit bytes, leaving room for the WAV header
+func MaxChunkLength(limit int64) time.Duration {
+	return time.Duration((limit-1024)/wavByteRate) * time.Second
+}
+
+// Split cuts an audio file into consecutive 16kHz mono WAV chunks of length
+// in a temporary directory and returns them in order with the directory.
+// The caller removes the directory when done.
+func Split(path string, length time.Duration) ([]Chunk, string, error) {
+	dir, err := os.MkdirTemp("", "whisper-lrc-split-")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+
+	err = runFFmpeg(
+		"-i", path,
+		"-vn",
+		"-ac", "1", // Mono
+		"-ar", "16000", // Whisper's native sample rate
+		"-f", "segment",
+		"-segment_time", seconds(length),
+		"-reset_timestamps", "1",
+		filepath.Join(dir, "chunk%06d.wav"),
+	)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, "", err
+	}
+
+	var chunks []Chunk
+	for i := 0; ; i++ {
+		chunkPath := filepath.Join(dir, fmt.Sprintf("chunk%06d.wav", i))
+		if !fileExists(chunkPath) {
+			break
+		}
+		chunks = append(chunks, Chunk{Index: i, Path: chunkPath, Offset: time.Duration(i) * length})
+	}
+	if len(chunks) == 0 {
+		os.RemoveAll(dir)
+		return nil, "", fmt.Errorf("ffmpeg wrote no audio for %s", path)
+	}
+	return chunks, dir, nil
+}
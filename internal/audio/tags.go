@@ -0,0 +1,57 @@
+package audio
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// lyricsContainers lists the formats whose tags can hold lyrics: ID3 for
+// MP3, Vorbis comments for FLAC/Ogg and iTunes metadata for M4A/MP4
+var lyricsContainers = map[string]bool{
+	".mp3":  true,
+	".flac": true,
+	".ogg":  true,
+	".m4a":  true,
+	".mp4":  true,
+}
+
+// EmbedLyrics stores lyrics in the audio file's tags. The streams are copied
+// without re-encoding and existing tags are kept; the file is replaced only
+// once ffmpeg has succeeded.
+func EmbedLyrics(path, lyrics string) error {
+	ext := strings.ToLower(filepath.Ext(path))
+	if !lyricsContainers[ext] {
+		return fmt.Errorf("cannot embed lyrics in %s files (supported: mp3, flac, ogg, m4a, mp4)", ext)
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), ".whisper-lrc-*"+ext)
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	tmpFile.Close()
+
+	args := []string{
+		"-i", path,
+		"-map", "0",
+		"-c", "copy",
+		"-map_metadata", "0",
+		"-metadata", "lyrics=" + lyrics,
+	}
+	if ext == ".mp3" {
+		// ID3v2.3 is the version most players read
+		args = append(args, "-id3v2_version", "3")
+	}
+	if err := runFFmpeg(append(args, tmpPath)...); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
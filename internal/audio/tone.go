@@ -0,0 +1,46 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// toneRate is the sample rate of TestTone, Whisper's native rate
+const toneRate = 16000
+
+// TestTone returns a 16kHz mono WAV file of the given length: a 440 Hz tone
+// for the first half and silence after it. It is built without ffmpeg, so
+// that a server can be tested on a machine without it.
+func TestTone(length time.Duration) []byte {
+	samples := make([]int16, int(length.Seconds()*toneRate))
+	for i := range samples[:len(samples)/2] {
+		samples[i] = int16(8000 * math.Sin(2*math.Pi*440*float64(i)/toneRate))
+	}
+
+	size := uint32(len(samples) * 2)
+	header := struct {
+		Riff          [4]byte
+		RiffSize      uint32
+		Wave, Fmt     [4]byte
+		FmtSize       uint32
+		Format        uint16
+		Channels      uint16
+		SampleRate    uint32
+		ByteRate      uint32
+		BlockAlign    uint16
+		BitsPerSample uint16
+		Data          [4]byte
+		DataSize      uint32
+	}{
+		[4]byte{'R', 'I', 'F', 'F'}, 36 + size,
+		[4]byte{'W', 'A', 'V', 'E'}, [4]byte{'f', 'm', 't', ' '},
+		16, 1, 1, toneRate, toneRate * 2, 2, 16, // 16-bit mono PCM
+		[4]byte{'d', 'a', 't', 'a'}, size,
+	}
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, header)
+	binary.Write(&buf, binary.LittleEndian, samples)
+	return buf.Bytes()
+}
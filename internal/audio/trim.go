@@ -0,0 +1,51 @@
+package audio
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Trim writes the first length of the audio file to a temporary 16kHz mono
+// WAV file and returns its path. The caller removes the file when done.
+func Trim(path string, length time.Duration) (string, error) {
+	return extract(path, "whisper-lrc-preview-*.wav", []string{"-t", seconds(length)}, nil)
+}
+
+// Clip writes length of the audio file from start to a temporary 16kHz mono
+// WAV file and returns its path. The caller removes the file when done.
+func Clip(path string, start, length time.Duration) (string, error) {
+	return extract(path, "whisper-lrc-clip-*.wav", []string{"-ss", seconds(start), "-t", seconds(length)}, nil)
+}
+
+// extract converts the audio file to a temporary WAV file named after
+// pattern, with ffmpeg options for the input (such as the part to read) and
+// for the output (such as filters)
+func extract(path, pattern string, inputOptions, outputOptions []string) (string, error) {
+	tmpFile, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	tmpFile.Close()
+
+	args := append(inputOptions, "-i", path)
+	args = append(args, outputOptions...)
+	args = append(args,
+		"-vn",
+		"-ac", "1", // Mono
+		"-ar", "16000", // Whisper's native sample rate
+		tmpPath,
+	)
+	if err := runFFmpeg(args...); err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+	return tmpPath, nil
+}
+
+// seconds formats a duration as seconds for ffmpeg
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
@@ -0,0 +1,53 @@
+package audio
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RenderVideo renders a lyric video: the audio played over a background with
+// ASS subtitles burned in. The background is an image file when one exists
+// at that path, otherwise an ffmpeg color such as "black" or "#202040".
+func RenderVideo(audioPath, subtitlesPath, background, outPath string, width, height int) error {
+	size := fmt.Sprintf("%dx%d", width, height)
+	subtitles := "ass=" + filterPath(subtitlesPath)
+
+	var args []string
+	if info, err := os.Stat(background); err == nil && !info.IsDir() {
+		// Fit the image into the frame, padding the rest with black
+		args = []string{
+			"-loop", "1", "-framerate", "30", "-i", background,
+			"-i", audioPath,
+			"-vf", fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1,%s",
+				width, height, width, height, subtitles),
+		}
+	} else {
+		args = []string{
+			"-f", "lavfi", "-i", fmt.Sprintf("color=c=%s:s=%s:r=30", background, size),
+			"-i", audioPath,
+			"-vf", subtitles,
+		}
+	}
+	args = append(args,
+		"-map", "0:v", "-map", "1:a",
+		"-shortest",
+		"-c:v", "libx264", "-pix_fmt", "yuv420p",
+		"-c:a", "aac", "-b:a", "192k",
+		outPath,
+	)
+
+	if err := runFFmpeg(args...); err != nil {
+		return fmt.Errorf("failed to render video: %w", err)
+	}
+	return nil
+}
+
+// filterPath quotes a file path for use as an ffmpeg filter option. The
+// quotes protect it from the filtergraph parser and the escaped colons (as in
+// Windows drive letters) from the option parser. Paths containing quotes are
+// not supported.
+func filterPath(path string) string {
+	return "'" + strings.ReplaceAll(filepath.ToSlash(path), ":", `\:`) + "'"
+}
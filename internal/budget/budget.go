@@ -0,0 +1,100 @@
+package budget
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Budget caps the audio sent for transcription, either in minutes of audio or
+// in dollars of estimated cost
+type Budget struct {
+	limit   float64 // seconds of audio, or dollars
+	dollars bool
+	spent   float64
+}
+
+// Parse parses a budget such as "$5", "5usd", "90m", "2h" or "90" (minutes)
+func Parse(s string) (*Budget, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	b := &Budget{}
+
+	var amount string
+	switch {
+	case strings.HasPrefix(v, "$"):
+		b.dollars, amount = true, v[1:]
+	case strings.HasSuffix(v, "usd"):
+		b.dollars, amount = true, strings.TrimSpace(strings.TrimSuffix(v, "usd"))
+	}
+
+	if b.dollars {
+		limit, err := strconv.ParseFloat(amount, 64)
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("invalid budget: %s", s)
+		}
+		b.limit = limit
+		return b, nil
+	}
+
+	if minutes, err := strconv.ParseFloat(v, 64); err == nil {
+		b.limit = minutes * 60
+	} else if d, err := time.ParseDuration(v); err == nil {
+		b.limit = d.Seconds()
+	}
+	if b.limit <= 0 {
+		return nil, fmt.Errorf("invalid budget: %s (use minutes like 90m or dollars like $5)", s)
+	}
+	return b, nil
+}
+
+// PeriodStart returns when a budget period containing now began: the zero
+// time for "run" (nothing before this run counts), local midnight for "day"
+// and the first of the month for "month"
+func PeriodStart(period string, now time.Time) (time.Time, error) {
+	switch period {
+	case "", "run":
+		return time.Time{}, nil
+	case "day":
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()), nil
+	case "month":
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()), nil
+	default:
+		return time.Time{}, fmt.Errorf("invalid budget period: %s. Use 'run', 'day' or 'month'", period)
+	}
+}
+
+// Add records billed audio and its cost
+func (b *Budget) Add(seconds, cost float64) {
+	b.spent += b.amount(seconds, cost)
+}
+
+// Allows reports whether audio of the given length and cost still fits
+func (b *Budget) Allows(seconds, cost float64) bool {
+	return b.spent+b.amount(seconds, cost) <= b.limit
+}
+
+// Exceeded reports whether the budget is used up
+func (b *Budget) Exceeded() bool {
+	return b.spent >= b.limit
+}
+
+// Summary describes how much of the budget is used and what remains
+func (b *Budget) Summary() string {
+	remaining := max(b.limit-b.spent, 0)
+	if b.dollars {
+		return fmt.Sprintf("$%.2f of $%.2f used, $%.2f remaining", b.spent, b.limit, remaining)
+	}
+	return fmt.Sprintf("%s of %s audio used, %s remaining", audioLength(b.spent), audioLength(b.limit), audioLength(remaining))
+}
+
+func (b *Budget) amount(seconds, cost float64) float64 {
+	if b.dollars {
+		return cost
+	}
+	return seconds
+}
+
+func audioLength(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second)).Round(time.Second)
+}
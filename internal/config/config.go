@@ -0,0 +1,113 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// Config is the optional config file of named flag profiles:
+//
+//	{
+//	  "profiles": {
+//	    "karaoke": {"format": "lrc", "karaoke": true, "snap-onsets": "300ms"},
+//	    "podcast": {"format": "srt", "max-cps": 17, "normalize": ["width", "quotes"]}
+//	  }
+//	}
+type Config struct {
+	Profiles map[string]Profile `json:"profiles"`
+}
+
+// Profile maps flag names (without dashes) to values: strings, numbers,
+// booleans, or lists for flags that take several values
+type Profile map[string]any
+
+// DefaultPath returns config.json in the user config directory
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	return filepath.Join(dir, "whisper-lrc", "config.json"), nil
+}
+
+// CorrectionsPath returns corrections.txt in the user config directory
+func CorrectionsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	return filepath.Join(dir, "whisper-lrc", "corrections.txt"), nil
+}
+
+// Load reads the config file at path
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// Names returns the profile names in order
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Flags returns the flag names of the profile in order
+func (p Profile) Flags() []string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Values returns the value of a flag as command-line strings, one per
+// element for lists
+func (p Profile) Values(flag string) ([]string, error) {
+	switch v := p[flag].(type) {
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, elem := range v {
+			s, err := scalar(elem)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", flag, err)
+			}
+			values = append(values, s)
+		}
+		return values, nil
+	default:
+		s, err := scalar(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", flag, err)
+		}
+		return []string{s}, nil
+	}
+}
+
+func scalar(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", v)
+	}
+}
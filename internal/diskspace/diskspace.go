@@ -0,0 +1,55 @@
+package diskspace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Check verifies that the filesystem holding path has at least need bytes
+// available. path does not have to exist yet; its nearest existing ancestor
+// is checked. If free space cannot be determined the check passes, so that
+// an unusual filesystem never blocks work that might succeed.
+func Check(path string, need uint64) error {
+	dir := existingAncestor(path)
+	avail, err := free(dir)
+	if err != nil {
+		return nil
+	}
+	if avail < need {
+		return fmt.Errorf("not enough disk space in %s: need %s, only %s available", dir, FormatBytes(need), FormatBytes(avail))
+	}
+	return nil
+}
+
+// existingAncestor returns path or its closest parent that exists
+func existingAncestor(path string) string {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		dir = path
+	}
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// FormatBytes renders a byte count with a binary unit suffix
+func FormatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package diskspace
+
+import "errors"
+
+// errUnsupported is returned by free on platforms without a free-space query
+var errUnsupported = errors.New("free space query not supported on this platform")
+
+// free is not implemented on this platform
+func free(dir string) (uint64, error) {
+	return 0, errUnsupported
+}
@@ -0,0 +1,92 @@
+// Package filename turns titles and URL names into file names that are safe
+// on Windows, macOS and Linux
+package filename
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxBytes is the longest name Sanitize returns. Most filesystems allow 255
+// bytes; the rest is left for extensions such as ".structure.json" and the
+// ".part" suffix of files being written.
+const MaxBytes = 200
+
+// invalid are characters not allowed in file names on Windows, plus the
+// path separators
+const invalid = `/\:*?"<>|`
+
+// reserved are device names Windows does not allow as a file name, with or
+// without an extension
+var reserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// composed maps a base character and a combining mark to their composed form
+var composed = func() map[[2]rune]rune {
+	m := make(map[[2]rune]rune)
+	for _, entry := range strings.Fields(compositions) {
+		r := []rune(entry)
+		m[[2]rune{r[0], r[1]}] = r[2]
+	}
+	return m
+}()
+
+// Sanitize makes name usable as a file name: it composes decomposed accents
+// and kana, replaces characters that are invalid on Windows and control
+// characters with "_", trims spaces and the trailing dots Windows drops,
+// renames reserved device names such as CON and cuts the result to MaxBytes
+// without splitting a character. It returns "" when nothing usable is left.
+func Sanitize(name string) string {
+	name = compose(name)
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(invalid, r) || unicode.IsControl(r) || r == utf8.RuneError {
+			return '_'
+		}
+		return r
+	}, name)
+	name = trim(name)
+
+	stem, _, _ := strings.Cut(name, ".")
+	if reserved[strings.ToUpper(strings.TrimRight(stem, " "))] {
+		name = "_" + name
+	}
+
+	if len(name) > MaxBytes {
+		cut := MaxBytes
+		for cut > 0 && !utf8.RuneStart(name[cut]) {
+			cut--
+		}
+		name = trim(name[:cut])
+	}
+	return name
+}
+
+// trim removes surrounding spaces and trailing dots
+func trim(name string) string {
+	return strings.TrimRightFunc(strings.TrimSpace(name), func(r rune) bool {
+		return r == '.' || unicode.IsSpace(r)
+	})
+}
+
+// compose replaces base characters followed by combining marks with their
+// precomposed forms, as file names from macOS are decomposed (NFD) while the
+// same name typed elsewhere is usually composed (NFC)
+func compose(s string) string {
+	var out []rune
+	for _, r := range s {
+		if n := len(out); n > 0 {
+			if c, ok := composed[[2]rune{out[n-1], r}]; ok {
+				out[n-1] = c
+				continue
+			}
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
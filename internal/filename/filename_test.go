@@ -0,0 +1,146 @@
+package filename
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"plain", "Artist - Title", "Artist - Title"},
+		{"empty", "", ""},
+		{"invalid characters", `AC/DC: "Back" <in> Black?|*\`, "AC_DC_ _Back_ _in_ Black____"},
+		{"control characters", "line\nbreak\ttab\x00nul\x7fdel", "line_break_tab_nul_del"},
+		{"invalid UTF-8", "bad\xffbyte", "bad_byte"},
+		{"trailing dots", "Title...", "Title"},
+		{"trailing spaces", "Title   ", "Title"},
+		{"trailing dots and spaces", "Title . . ", "Title"},
+		{"leading spaces", "  Title", "Title"},
+		{"only dots", "...", ""},
+		{"inner dots kept", "Vol. 2.mp3", "Vol. 2.mp3"},
+		{"reserved CON", "CON", "_CON"},
+		{"reserved lowercase", "con", "_con"},
+		{"reserved with extension", "NUL.txt", "_NUL.txt"},
+		{"reserved COM1", "COM1", "_COM1"},
+		{"reserved LPT9 with extension", "lpt9.lrc", "_lpt9.lrc"},
+		{"reserved with space before dot", "AUX .lrc", "_AUX .lrc"},
+		{"not reserved COM0", "COM0", "COM0"},
+		{"not reserved prefix", "CONCERT", "CONCERT"},
+		{"not reserved suffix", "Live at CON", "Live at CON"},
+		{"NFD accent", "Beyonce\u0301", "Beyonc\u00e9"},
+		{"NFD umlaut", "Mo\u0308tley Cru\u0308e", "M\u00f6tley Cr\u00fce"},
+		{"NFD kana", "\u304b\u3099\u305f", "\u304c\u305f"},
+		{"NFD stacked marks", "e\u0302\u0301", "\u1ebf"},
+		{"NFC unchanged", "Beyonc\u00e9", "Beyonc\u00e9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sanitize(tt.in); got != tt.want {
+				t.Errorf("Sanitize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeLength(t *testing.T) {
+	tests := []struct {
+		name, in string
+		want     int // length in bytes
+	}{
+		{"ASCII", strings.Repeat("a", 300), MaxBytes},
+		{"exact", strings.Repeat("a", MaxBytes), MaxBytes},
+		// 3-byte runes: 66 fit in 198 bytes, the 67th would cross MaxBytes
+		{"CJK", strings.Repeat("歌", 100), 198},
+		// 4-byte runes: 50 fit exactly
+		{"emoji", strings.Repeat("🎵", 60), MaxBytes},
+		// 2-byte runes after an odd prefix: the cut falls mid-rune
+		{"odd offset", "a" + strings.Repeat("é", 150), 199},
+		// Trailing spaces and dots left by the cut are trimmed
+		{"cut before dots", strings.Repeat("a", MaxBytes-2) + "....x", MaxBytes - 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Sanitize(tt.in)
+			if len(got) != tt.want {
+				t.Errorf("len(Sanitize(%q...)) = %d, want %d", tt.in[:10], len(got), tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("Sanitize split a character: %q", got)
+			}
+			if !strings.HasPrefix(tt.in, got) {
+				t.Errorf("Sanitize(%q...) = %q, not a prefix of the input", tt.in[:10], got)
+			}
+		})
+	}
+}
+
+// Sanitize gives the same result on every platform, so that names written
+// on one can be copied to another. Each result must be valid under the rules
+// of both.
+func TestSanitizePortable(t *testing.T) {
+	platforms := []struct {
+		goos  string
+		valid func(name string) string // returns why name is invalid, or ""
+	}{
+		{"windows", windowsProblem},
+		{"unix", unixProblem},
+	}
+	inputs := []string{
+		"CON", "nul.txt", "Com1.lrc", "a:b", "x\x00y", "end. ", "dir/name", `back\slash`,
+		"tab\there", "Beyoncé", strings.Repeat("歌", 100), "  ", "?.", "PRN ",
+	}
+	for _, platform := range platforms {
+		t.Run(platform.goos, func(t *testing.T) {
+			for _, in := range inputs {
+				got := Sanitize(in)
+				if got == "" {
+					continue
+				}
+				if problem := platform.valid(got); problem != "" {
+					t.Errorf("Sanitize(%q) = %q: %s on %s", in, got, problem, platform.goos)
+				}
+			}
+		})
+	}
+}
+
+// windowsProblem checks a name against the Windows naming rules
+func windowsProblem(name string) string {
+	if i := strings.IndexAny(name, invalid); i >= 0 {
+		return "reserved character " + name[i:i+1]
+	}
+	for _, r := range name {
+		if r < 32 {
+			return "control character"
+		}
+	}
+	if strings.HasSuffix(name, ".") || strings.HasSuffix(name, " ") {
+		return "trailing dot or space"
+	}
+	stem, _, _ := strings.Cut(name, ".")
+	if reserved[strings.ToUpper(strings.TrimRight(stem, " "))] {
+		return "reserved device name"
+	}
+	if len(name) > 255 {
+		return "too long"
+	}
+	return ""
+}
+
+// unixProblem checks a name against the rules of Linux and macOS
+// filesystems
+func unixProblem(name string) string {
+	if strings.ContainsAny(name, "/\x00") {
+		return "slash or NUL"
+	}
+	if name == "." || name == ".." {
+		return "directory name"
+	}
+	if len(name) > 255 {
+		return "too long"
+	}
+	return ""
+}
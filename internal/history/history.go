@@ -0,0 +1,100 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Record is one transcription attempt. It holds no file names or lyrics so
+// the history can be kept by default.
+type Record struct {
+	Time              time.Time `json:"time"`
+	Backend           string    `json:"backend"`
+	AudioSeconds      float64   `json:"audio_seconds"`
+	Cost              float64   `json:"cost"`
+	ProcessingSeconds float64   `json:"processing_seconds"`
+	Failed            bool      `json:"failed,omitempty"`
+}
+
+// Store is an append-only JSON Lines file of records
+type Store struct {
+	path string
+}
+
+// DefaultPath returns the history file in the user config directory
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	return filepath.Join(dir, "whisper-lrc", "history.jsonl"), nil
+}
+
+// Open returns the store at path; the file is created on the first Append
+func Open(path string) *Store {
+	return &Store{path: path}
+}
+
+// Append adds a record to the store
+func (s *Store) Append(r Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to encode history record: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+// Load returns all records, oldest first. A missing file is an empty history;
+// malformed lines are skipped.
+func (s *Store) Load() ([]Record, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue
+		}
+		records = append(records, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return records, nil
+}
+
+// Since returns the records at or after t
+func Since(records []Record, t time.Time) []Record {
+	var matched []Record
+	for _, r := range records {
+		if !r.Time.Before(t) {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
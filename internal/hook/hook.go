@@ -0,0 +1,44 @@
+package hook
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Command returns a command that runs cmdline through the shell: sh -c, or
+// cmd /C on Windows
+func Command(cmdline string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", cmdline)
+	}
+	return exec.Command("sh", "-c", cmdline)
+}
+
+// Run runs cmdline with extra environment variables and returns its combined
+// output. On failure the error includes the output.
+func Run(cmdline string, env map[string]string) (string, error) {
+	cmd := Command(cmdline)
+	cmd.Env = os.Environ()
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		cmd.Env = append(cmd.Env, name+"="+env[name])
+	}
+
+	out, err := cmd.CombinedOutput()
+	output := strings.TrimSpace(string(out))
+	if err != nil {
+		if output != "" {
+			return output, fmt.Errorf("%w: %s", err, output)
+		}
+		return output, err
+	}
+	return output, nil
+}
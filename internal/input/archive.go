@@ -0,0 +1,156 @@
+package input
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/BBleae/whisper-lrc/internal/diskspace"
+)
+
+// Member is an audio file extracted from an archive
+type Member struct {
+	// Name is the slash-separated path inside the archive
+	Name string
+	// Path is the extracted file
+	Path string
+}
+
+// IsArchive reports whether path names a zip or (gzipped) tar archive
+func IsArchive(path string) bool {
+	lower := strings.ToLower(path)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractArchive extracts the audio files of an archive into dir, keeping
+// their folders, and returns them in archive order. Other files, links and
+// entries that would land outside dir are skipped.
+func ExtractArchive(archivePath, dir string) ([]Member, error) {
+	var members []Member
+	var err error
+	if strings.HasSuffix(strings.ToLower(archivePath), ".zip") {
+		members, err = extractZip(archivePath, dir)
+	} else {
+		members, err = extractTar(archivePath, dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract %s: %w", archivePath, err)
+	}
+	return members, nil
+}
+
+func extractZip(archivePath, dir string) ([]Member, error) {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var members []Member
+	for _, f := range r.File {
+		name, ok := memberName(f.Name)
+		if !ok || !f.Mode().IsRegular() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		m, err := extractMember(rc, name, int64(f.UncompressedSize64), dir)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+func extractTar(archivePath, dir string) ([]Member, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if !strings.HasSuffix(strings.ToLower(archivePath), ".tar") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	var members []Member
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		name, ok := memberName(hdr.Name)
+		if !ok || hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		m, err := extractMember(tr, name, hdr.Size, dir)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+// memberName cleans an entry name and reports whether it is an audio file
+// that stays inside the extraction directory
+func memberName(name string) (string, bool) {
+	name = path.Clean(strings.TrimPrefix(strings.ReplaceAll(name, `\`, "/"), "/"))
+	base := path.Base(name)
+	// macOS resource forks and other hidden files
+	if strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(base, ".") {
+		return "", false
+	}
+	if !supportedExtensions[strings.ToLower(path.Ext(name))] {
+		return "", false
+	}
+	return name, filepath.IsLocal(filepath.FromSlash(name))
+}
+
+// extractMember copies at most size bytes of an entry to its place in dir
+func extractMember(r io.Reader, name string, size int64, dir string) (Member, error) {
+	if err := diskspace.Check(dir, uint64(size)+downloadHeadroom); err != nil {
+		return Member{}, err
+	}
+	dest := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return Member{}, err
+	}
+	out, err := os.Create(dest)
+	if err != nil {
+		return Member{}, err
+	}
+	_, err = io.CopyN(out, r, size)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return Member{}, fmt.Errorf("%s: %w", name, err)
+	}
+	return Member{Name: name, Path: dest}, nil
+}
@@ -0,0 +1,199 @@
+package input
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// stalePartDownload is the age after which a partial download in the cache
+// is considered abandoned
+const stalePartDownload = 24 * time.Hour
+
+// DefaultCacheDir returns the per-user directory for cached downloads
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate user cache directory: %w", err)
+	}
+	return filepath.Join(dir, "whisper-lrc", "downloads"), nil
+}
+
+// downloadCache stores downloads keyed by URL, together with the
+// validators needed to revalidate them with conditional requests
+type downloadCache struct {
+	dir string
+	// options are the download options that decide the content, such as
+	// those of yt-dlp; entries made with other options are not used
+	options string
+}
+
+// cacheEntry is the metadata stored next to each cached download
+type cacheEntry struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	File         string `json:"file"`
+	Title        string `json:"title,omitempty"`
+	Options      string `json:"options,omitempty"`
+	// Cover is the image downloaded with the audio (the yt-dlp thumbnail)
+	Cover string `json:"cover,omitempty"`
+}
+
+// setValidators adds conditional request headers for the cached copy
+func (e *cacheEntry) setValidators(req *http.Request) {
+	if e.ETag != "" {
+		req.Header.Set("If-None-Match", e.ETag)
+	}
+	if e.LastModified != "" {
+		req.Header.Set("If-Modified-Since", e.LastModified)
+	}
+}
+
+func (c *downloadCache) key(url string) string {
+	id := url
+	if c.options != "" {
+		id = c.options + "\n" + url
+	}
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *downloadCache) metaPath(url string) string {
+	return filepath.Join(c.dir, c.key(url)+".json")
+}
+
+func (c *downloadCache) path(entry *cacheEntry) string {
+	return filepath.Join(c.dir, entry.File)
+}
+
+// coverPath returns the path of the entry's cover image, or "" if it has
+// none
+func (c *downloadCache) coverPath(entry *cacheEntry) string {
+	if entry.Cover == "" {
+		return ""
+	}
+	path := filepath.Join(c.dir, entry.Cover)
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// lookup returns the cache entry for url, or nil if there is no usable copy
+func (c *downloadCache) lookup(url string) *cacheEntry {
+	data, err := os.ReadFile(c.metaPath(url))
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.URL != url || entry.Options != c.options {
+		return nil
+	}
+	if _, err := os.Stat(c.path(&entry)); err != nil {
+		return nil
+	}
+	return &entry
+}
+
+// removeStaleParts removes partial downloads left behind by interrupted
+// runs. Recent ones may belong to a run still downloading.
+func (c *downloadCache) removeStaleParts() {
+	parts, _ := filepath.Glob(filepath.Join(c.dir, "*.part"))
+	for _, part := range parts {
+		if info, err := os.Stat(part); err == nil && time.Since(info.ModTime()) > stalePartDownload {
+			os.Remove(part)
+		}
+	}
+}
+
+// store saves a download and its validators, replacing any previous copy,
+// and returns the path of the cached file
+func (c *downloadCache) store(url string, header http.Header, ext, title string, body io.Reader) (string, error) {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	c.removeStaleParts()
+
+	// Download next to the final location so the rename is atomic
+	key := c.key(url)
+	tmpFile, err := os.CreateTemp(c.dir, key+"-*.part")
+	if err != nil {
+		return "", fmt.Errorf("failed to create cache file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+
+	_, err = io.Copy(tmpFile, body)
+	tmpFile.Close()
+	if err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to save download: %w", err)
+	}
+
+	entry := cacheEntry{
+		URL:          url,
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+		File:         key + ext,
+		Title:        title,
+		Options:      c.options,
+	}
+	if old := c.lookup(url); old != nil && old.File != entry.File {
+		os.Remove(c.path(old))
+	}
+	if err := os.Rename(tmpPath, c.path(&entry)); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to save download: %w", err)
+	}
+
+	if err := c.save(&entry); err != nil {
+		return "", err
+	}
+	return c.path(&entry), nil
+}
+
+// storeFile copies audio downloaded by other means, such as yt-dlp, into
+// the cache together with its cover image, if any
+func (c *downloadCache) storeFile(url, path, title, cover string) (*cacheEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save download: %w", err)
+	}
+	defer f.Close()
+	if _, err := c.store(url, nil, filepath.Ext(path), title, f); err != nil {
+		return nil, err
+	}
+	entry := c.lookup(url)
+	if entry == nil || cover == "" {
+		return entry, nil
+	}
+
+	data, err := os.ReadFile(cover)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save cover art: %w", err)
+	}
+	entry.Cover = c.key(url) + filepath.Ext(cover)
+	if err := os.WriteFile(filepath.Join(c.dir, entry.Cover), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to save cover art: %w", err)
+	}
+	return entry, c.save(entry)
+}
+
+// save writes the metadata of a cache entry
+func (c *downloadCache) save(entry *cacheEntry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+	if err := os.WriteFile(c.metaPath(entry.URL), data, 0644); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
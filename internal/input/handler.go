@@ -1,13 +1,43 @@
 package input
 
 import (
+	"bufio"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+
+	"github.com/BBleae/whisper-lrc/internal/diskspace"
+	"github.com/BBleae/whisper-lrc/internal/tlsconfig"
+)
+
+const (
+	// downloadHeadroom is kept free on top of a download's announced size
+	downloadHeadroom = 64 << 20
+	// unknownDownloadSpace is required when a download's size is not known upfront
+	unknownDownloadSpace = 256 << 20
+	// ytDlpSpace is required before yt-dlp runs; it keeps the source stream and
+	// the converted mp3 in the temp directory at the same time
+	ytDlpSpace = 512 << 20
+)
+
+// Failure categories of Resolve, for use with errors.Is
+var (
+	// ErrUnsupportedFormat means a local file is not a supported audio format
+	ErrUnsupportedFormat = errors.New("unsupported audio format")
+	// ErrDownload means a URL could not be downloaded
+	ErrDownload = errors.New("download failed")
+	// ErrUnsupportedScheme means a URL is neither http(s) nor file://
+	ErrUnsupportedScheme = errors.New("unsupported URL scheme")
 )
 
 // Supported audio extensions
@@ -21,54 +51,172 @@ var supportedExtensions = map[string]bool{
 	".mp4":  true,
 }
 
+// IsSupported reports whether a file name has a supported audio extension
+func IsSupported(name string) bool {
+	return supportedExtensions[strings.ToLower(filepath.Ext(name))]
+}
+
+// Source is an input resolved to a local audio file
+type Source struct {
+	Path string
+	// Data holds a small download kept in memory instead of at Path (see
+	// WithMemoryLimit), and Name its file name
+	Data []byte
+	Name string
+	// Unchanged is set when a cached download was revalidated with the server
+	// and the remote audio has not changed since it was last fetched
+	Unchanged bool
+	// Cover is an image downloaded with the audio (the yt-dlp thumbnail), if any
+	Cover string
+	// Downloaded is the number of bytes fetched over the network
+	Downloaded int64
+	// Title is a readable name for a download, without extension: the
+	// Content-Disposition file name or the yt-dlp title
+	Title string
+
+	cleanup func()
+}
+
+// Cleanup removes any temporary files created while resolving the source
+func (s *Source) Cleanup() {
+	if s.cleanup != nil {
+		s.cleanup()
+	}
+}
+
 // Handler resolves various input sources to local audio files
 type Handler struct {
-	useYtDlp bool
+	useYtDlp  bool
+	rateLimit int64
+	cache     *downloadCache
+	// ytDlpCache keeps yt-dlp downloads (see WithYtDlpCache)
+	ytDlpCache *downloadCache
+	tls        tlsconfig.Options
+	cover      bool
+	// memoryLimit is the largest direct download kept in memory
+	memoryLimit int64
+	// noFallback disables retrying URLs that return no audio with yt-dlp
+	noFallback bool
+}
+
+// Option configures a Handler
+type Option func(*Handler)
+
+// WithRateLimit caps download bandwidth in bytes per second (0 means unlimited)
+func WithRateLimit(bytesPerSecond int64) Option {
+	return func(h *Handler) {
+		h.rateLimit = bytesPerSecond
+	}
+}
+
+// WithDownloadCache keeps direct downloads in dir and revalidates them with
+// ETag/Last-Modified conditional requests instead of downloading them again
+func WithDownloadCache(dir string) Option {
+	return func(h *Handler) {
+		h.cache = &downloadCache{dir: dir}
+	}
+}
+
+// WithYtDlpCache keeps yt-dlp downloads in dir, keyed by URL and the yt-dlp
+// options that decide the audio. They are reused as they are, since yt-dlp
+// cannot tell whether the audio changed, so only use this when asked to.
+func WithYtDlpCache(dir string) Option {
+	return func(h *Handler) {
+		h.ytDlpCache = &downloadCache{dir: dir, options: strings.Join(ytDlpAudioArgs, " ")}
+	}
+}
+
+// WithTLS applies the TLS options to direct downloads and passes the
+// equivalent flags to yt-dlp
+func WithTLS(opts tlsconfig.Options) Option {
+	return func(h *Handler) {
+		h.tls = opts
+	}
+}
+
+// WithCoverArt also downloads the video thumbnail with yt-dlp downloads
+func WithCoverArt() Option {
+	return func(h *Handler) {
+		h.cover = true
+	}
+}
+
+// WithMemoryLimit keeps direct downloads of up to limit bytes in memory
+// instead of writing them to a temporary file. Such sources have Data set and
+// no Path, so only use this when nothing needs the audio on disk. Downloads
+// into the WithDownloadCache directory are not affected.
+func WithMemoryLimit(limit int64) Option {
+	return func(h *Handler) {
+		h.memoryLimit = limit
+	}
+}
+
+// WithoutYtDlpFallback fails direct downloads that return a web page or
+// other non-audio content instead of retrying them with yt-dlp
+func WithoutYtDlpFallback() Option {
+	return func(h *Handler) {
+		h.noFallback = true
+	}
 }
 
 // NewHandler creates a new input handler
-func NewHandler(useYtDlp bool) *Handler {
-	return &Handler{
+func NewHandler(useYtDlp bool, opts ...Option) *Handler {
+	h := &Handler{
 		useYtDlp: useYtDlp,
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
-// Resolve converts an input (file path, URL, etc.) to a local file path
-// Returns the path and a cleanup function (nil if no cleanup needed)
-func (h *Handler) Resolve(input string) (string, func(), error) {
+// Resolve converts an input (file path, URL, etc.) to a local audio file.
+// Callers must call Cleanup on the returned source when done with it.
+func (h *Handler) Resolve(input string) (*Source, error) {
+	input, err := NormalizeInput(input, false)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check if it's a URL
-	if strings.HasPrefix(input, "http://") || strings.HasPrefix(input, "https://") {
+	if IsURL(input) {
 		return h.resolveURL(input)
 	}
+	if scheme := urlScheme(input); scheme != "" {
+		return nil, fmt.Errorf("%w %s:// in %s (use http://, https:// or a local path)", ErrUnsupportedScheme, scheme, input)
+	}
 
 	// Local file
 	return h.resolveLocalFile(input)
 }
 
-func (h *Handler) resolveLocalFile(path string) (string, func(), error) {
+func (h *Handler) resolveLocalFile(path string) (*Source, error) {
 	// Check if file exists
 	info, err := os.Stat(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return "", nil, fmt.Errorf("file not found: %s", path)
+			if looksLikeHost(path) {
+				return nil, fmt.Errorf("file not found: %s (to download it, use https://%s)", path, path)
+			}
+			return nil, fmt.Errorf("file not found: %s", path)
 		}
-		return "", nil, fmt.Errorf("failed to access file: %w", err)
+		return nil, fmt.Errorf("failed to access file: %w", err)
 	}
 
 	if info.IsDir() {
-		return "", nil, fmt.Errorf("path is a directory: %s", path)
+		return nil, fmt.Errorf("path is a directory: %s", path)
 	}
 
 	// Check extension
 	ext := strings.ToLower(filepath.Ext(path))
 	if !supportedExtensions[ext] {
-		return "", nil, fmt.Errorf("unsupported audio format: %s", ext)
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, ext)
 	}
 
-	return path, nil, nil
+	return &Source{Path: path}, nil
 }
 
-func (h *Handler) resolveURL(url string) (string, func(), error) {
+func (h *Handler) resolveURL(url string) (*Source, error) {
 	// Check if it's a YouTube URL and yt-dlp is enabled
 	if h.useYtDlp && isYouTubeURL(url) {
 		return h.downloadWithYtDlp(url)
@@ -78,8 +226,14 @@ func (h *Handler) resolveURL(url string) (string, func(), error) {
 	return h.downloadDirect(url)
 }
 
-func (h *Handler) downloadDirect(inputURL string) (string, func(), error) {
+func (h *Handler) downloadDirect(inputURL string) (*Source, error) {
+	tlsConfig, err := tlsconfig.Load(h.tls)
+	if err != nil {
+		return nil, err
+	}
+
 	client := &http.Client{
+		Transport: tlsconfig.Transport(tlsConfig),
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			if len(via) >= 10 {
 				return fmt.Errorf("too many redirects")
@@ -88,41 +242,158 @@ func (h *Handler) downloadDirect(inputURL string) (string, func(), error) {
 		},
 	}
 
-	resp, err := client.Get(inputURL)
+	req, err := http.NewRequest(http.MethodGet, inputURL, nil)
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to download: %w", err)
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+
+	// Revalidate a cached copy instead of downloading it again
+	var cached *cacheEntry
+	if h.cache != nil {
+		if cached = h.cache.lookup(inputURL); cached != nil {
+			cached.setValidators(req)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDownload, err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		return &Source{Path: h.cache.path(cached), Unchanged: true, Title: cached.Title}, nil
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		return "", nil, fmt.Errorf("download failed with status: %d", resp.StatusCode)
+		return nil, fmt.Errorf("%w with status: %d", ErrDownload, resp.StatusCode)
 	}
 
+	ext := getAudioExtension(resp)
+	title := dispositionTitle(resp.Header.Get("Content-Disposition"))
+
+	var body io.Reader = resp.Body
+	if h.rateLimit > 0 {
+		body = newRateLimitedReader(resp.Body, h.rateLimit)
+	}
+	counter := &countingReader{r: body}
+
+	buffered := bufio.NewReaderSize(counter, sniffLength)
+	head, _ := buffered.Peek(sniffLength)
+
+	// Explain web pages (login walls, expired links) instead of uploading them
 	contentType := resp.Header.Get("Content-Type")
-	if strings.HasPrefix(contentType, "text/html") {
-		return "", nil, fmt.Errorf("URL returned HTML instead of audio (possibly a redirect to error page)")
+	if strings.HasPrefix(contentType, "text/html") || strings.HasPrefix(http.DetectContentType(head), "text/html") {
+		page, _ := io.ReadAll(io.LimitReader(buffered, maxHTMLPage))
+		return h.ytDlpFallback(inputURL, h.htmlPageError(inputURL, page))
 	}
 
-	ext := getAudioExtension(resp)
+	// Trust the content over the Content-Type header
+	sniffed, err := sniffAudio(head)
+	if err != nil {
+		return h.ytDlpFallback(inputURL, err)
+	}
+	if sniffed != "" {
+		ext = sniffed
+	}
+	body = buffered
+
+	inMemory := h.cache == nil && h.memoryLimit > 0 && resp.ContentLength >= 0 && resp.ContentLength <= h.memoryLimit
+	if !inMemory {
+		downloadDir := os.TempDir()
+		if h.cache != nil {
+			downloadDir = h.cache.dir
+		}
+		need := uint64(unknownDownloadSpace)
+		if resp.ContentLength > 0 {
+			need = uint64(resp.ContentLength) + downloadHeadroom
+		}
+		if err := diskspace.Check(downloadDir, need); err != nil {
+			return nil, err
+		}
+	}
+
+	if h.cache != nil {
+		path, err := h.cache.store(inputURL, resp.Header, ext, title, body)
+		if err != nil {
+			return nil, err
+		}
+		return &Source{Path: path, Downloaded: counter.n, Title: title}, nil
+	}
+
+	// Downloads of unknown size start in memory too and move to a temp file
+	// once they outgrow the limit
+	if h.memoryLimit > 0 && resp.ContentLength <= h.memoryLimit {
+		sp := &spool{limit: h.memoryLimit, ext: ext}
+		// A known size is allocated once instead of grown by doubling
+		if resp.ContentLength > 0 {
+			sp.buf.Grow(int(resp.ContentLength))
+		}
+		if _, err := io.Copy(sp, body); err != nil {
+			sp.discard()
+			return nil, fmt.Errorf("%w: %w", ErrDownload, err)
+		}
+		src, err := sp.source()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrDownload, err)
+		}
+		src.Downloaded, src.Title = counter.n, title
+		return src, nil
+	}
 
-	tmpFile, err := os.CreateTemp("", "whisper-lrc-*"+ext)
+	// The download keeps a .part suffix until it is complete
+	tmpFile, err := os.CreateTemp("", "whisper-lrc-*"+ext+".part")
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to create temp file: %w", err)
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	partPath := tmpFile.Name()
+
+	_, err = io.Copy(tmpFile, body)
+	tmpFile.Close()
+	if err != nil {
+		os.Remove(partPath)
+		return nil, fmt.Errorf("%w: %w", ErrDownload, err)
+	}
+	tmpPath := strings.TrimSuffix(partPath, ".part")
+	if err := os.Rename(partPath, tmpPath); err != nil {
+		os.Remove(partPath)
+		return nil, fmt.Errorf("%w: %w", ErrDownload, err)
 	}
-	tmpPath := tmpFile.Name()
 
 	cleanup := func() {
 		os.Remove(tmpPath)
 	}
+	return &Source{Path: tmpPath, Downloaded: counter.n, Title: title, cleanup: cleanup}, nil
+}
 
-	_, err = io.Copy(tmpFile, resp.Body)
-	tmpFile.Close()
+// dispositionTitle returns the file name of a Content-Disposition header
+// without directories and extension, or "" if there is none
+func dispositionTitle(header string) string {
+	if header == "" {
+		return ""
+	}
+	_, params, err := mime.ParseMediaType(header)
 	if err != nil {
-		cleanup()
-		return "", nil, fmt.Errorf("failed to save download: %w", err)
+		return ""
 	}
+	name := path.Base(strings.ReplaceAll(params["filename"], "\\", "/"))
+	name = strings.TrimSpace(strings.TrimSuffix(name, path.Ext(name)))
+	if name == "." || name == "/" {
+		return ""
+	}
+	return name
+}
 
-	return tmpPath, cleanup, nil
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
 }
 
 // getAudioExtension determines the audio file extension from HTTP response
@@ -179,16 +450,56 @@ func getAudioExtension(resp *http.Response) string {
 	return ".mp3"
 }
 
-func (h *Handler) downloadWithYtDlp(url string) (string, func(), error) {
+// ytDlpFallback retries a URL that returned no audio with yt-dlp, which can
+// extract the audio of media pages on many sites. Without yt-dlp, or with
+// WithoutYtDlpFallback, the download fails with cause.
+func (h *Handler) ytDlpFallback(url string, cause error) (*Source, error) {
+	if h.noFallback {
+		return nil, fmt.Errorf("%w: %w", ErrDownload, cause)
+	}
+	if _, err := exec.LookPath("yt-dlp"); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDownload, cause)
+	}
+	src, err := h.downloadWithYtDlp(url)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w (yt-dlp could not extract audio from it either)", ErrDownload, cause)
+	}
+	return src, nil
+}
+
+// ytDlpAudioArgs are the yt-dlp options that decide the audio it writes;
+// they are part of the key of cached yt-dlp downloads
+var ytDlpAudioArgs = []string{
+	"-x",                    // Extract audio
+	"--audio-format", "mp3", // Convert to mp3
+	"--audio-quality", "0", // Best quality
+	"--no-playlist", // Single video only
+}
+
+func (h *Handler) downloadWithYtDlp(url string) (*Source, error) {
+	// Reuse a cached copy, unless cover art is wanted and was not kept
+	if cache := h.ytDlpCache; cache != nil {
+		if cached := cache.lookup(url); cached != nil {
+			cover := cache.coverPath(cached)
+			if !h.cover || cover != "" {
+				return &Source{Path: cache.path(cached), Title: cached.Title, Cover: cover}, nil
+			}
+		}
+	}
+
 	// Check if yt-dlp is available
 	if _, err := exec.LookPath("yt-dlp"); err != nil {
-		return "", nil, fmt.Errorf("yt-dlp not found. Please install it: https://github.com/yt-dlp/yt-dlp")
+		return nil, fmt.Errorf("yt-dlp not found. Please install it: https://github.com/yt-dlp/yt-dlp")
+	}
+
+	if err := diskspace.Check(os.TempDir(), ytDlpSpace); err != nil {
+		return nil, err
 	}
 
 	// Create temp directory
 	tmpDir, err := os.MkdirTemp("", "whisper-lrc-ytdlp-")
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to create temp directory: %w", err)
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
 	}
 
 	cleanup := func() {
@@ -199,29 +510,78 @@ func (h *Handler) downloadWithYtDlp(url string) (string, func(), error) {
 	outputTemplate := filepath.Join(tmpDir, "audio.%(ext)s")
 
 	// Run yt-dlp
-	cmd := exec.Command("yt-dlp",
-		"-x",                    // Extract audio
-		"--audio-format", "mp3", // Convert to mp3
-		"--audio-quality", "0", // Best quality
+	args := append(slices.Clone(ytDlpAudioArgs),
 		"-o", outputTemplate, // Output path
-		"--no-playlist", // Single video only
-		url,
+		"--write-info-json", // Title for the output name
 	)
+	if h.rateLimit > 0 {
+		args = append(args, "--limit-rate", strconv.FormatInt(h.rateLimit, 10))
+	}
+	// yt-dlp has no option for a custom CA bundle; --ca-cert only applies to
+	// direct downloads and the API
+	if h.tls.InsecureSkipVerify {
+		args = append(args, "--no-check-certificates")
+	}
+	if h.tls.ClientCert != "" {
+		args = append(args, "--client-certificate", h.tls.ClientCert)
+	}
+	if h.tls.ClientKey != "" {
+		args = append(args, "--client-certificate-key", h.tls.ClientKey)
+	}
+	if h.cover {
+		args = append(args,
+			"--write-thumbnail",
+			"--convert-thumbnails", "jpg",
+			"-o", "thumbnail:"+filepath.Join(tmpDir, "cover.%(ext)s"),
+		)
+	}
+	cmd := exec.Command("yt-dlp", append(args, url)...)
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		cleanup()
-		return "", nil, fmt.Errorf("yt-dlp failed: %w\nOutput: %s", err, string(output))
+		return nil, fmt.Errorf("%w: yt-dlp failed: %w\nOutput: %s", ErrDownload, err, string(output))
 	}
 
-	// Find the downloaded file
-	files, err := filepath.Glob(filepath.Join(tmpDir, "audio.*"))
-	if err != nil || len(files) == 0 {
+	// Find the downloaded file next to its metadata
+	matches, _ := filepath.Glob(filepath.Join(tmpDir, "audio.*"))
+	var files []string
+	for _, f := range matches {
+		if !strings.HasSuffix(f, ".json") {
+			files = append(files, f)
+		}
+	}
+	if len(files) == 0 {
 		cleanup()
-		return "", nil, fmt.Errorf("yt-dlp download completed but no audio file found")
+		return nil, fmt.Errorf("yt-dlp download completed but no audio file found")
+	}
+
+	source := &Source{Path: files[0], cleanup: cleanup}
+	if data, err := os.ReadFile(filepath.Join(tmpDir, "audio.info.json")); err == nil {
+		var info struct {
+			Title string `json:"title"`
+		}
+		if json.Unmarshal(data, &info) == nil {
+			source.Title = strings.TrimSpace(info.Title)
+		}
+	}
+	// yt-dlp does not report its transfer; the extracted audio stands in
+	if info, err := os.Stat(files[0]); err == nil {
+		source.Downloaded = info.Size()
+	}
+	// A missing thumbnail only makes yt-dlp warn
+	if covers, _ := filepath.Glob(filepath.Join(tmpDir, "cover.*")); len(covers) > 0 {
+		source.Cover = covers[0]
 	}
 
-	return files[0], cleanup, nil
+	// A cache that cannot be written (a full disk, say) only costs the next
+	// run a download, so the temporary copy is used instead
+	if cache := h.ytDlpCache; cache != nil {
+		if entry, err := cache.storeFile(url, source.Path, source.Title, source.Cover); err == nil && entry != nil {
+			source.Path, source.Cover = cache.path(entry), cache.coverPath(entry)
+		}
+	}
+	return source, nil
 }
 
 func isYouTubeURL(url string) bool {
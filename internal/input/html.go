@@ -0,0 +1,59 @@
+package input
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// maxHTMLPage is how much of a web page is read to explain it
+const maxHTMLPage = 256 << 10
+
+var (
+	htmlTitle    = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	htmlPassword = regexp.MustCompile(`(?i)<input[^>]+type=["']?password`)
+	htmlMedia    = regexp.MustCompile(`(?i)<(audio|video)[\s>]|property=["']og:(audio|video)`)
+	htmlTags     = regexp.MustCompile(`(?s)<(script|style)[^>]*>.*?</(script|style)>|<[^>]*>`)
+)
+
+// htmlPageError explains why a URL returned a web page instead of audio:
+// a login wall, a cookie consent page, a paywall, an expired link or a page
+// with embedded media, judged from its title and text.
+func (h *Handler) htmlPageError(url string, page []byte) error {
+	title := ""
+	if m := htmlTitle.FindSubmatch(page); m != nil {
+		title = strings.Join(strings.Fields(html.UnescapeString(string(m[1]))), " ")
+	}
+	text := strings.ToLower(title + " " + html.UnescapeString(htmlTags.ReplaceAllString(string(page), " ")))
+	contains := func(phrases ...string) bool {
+		for _, p := range phrases {
+			if strings.Contains(text, p) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var kind, advice string
+	switch {
+	case htmlPassword.Match(page) || contains("sign in to continue", "log in to continue", "please log in", "please sign in", "login required"):
+		kind, advice = "a login page", "the file needs an account, so download it in a browser and pass the local file"
+	case contains("cookie") && contains("consent", "accept all", "reject all", "manage cookies"):
+		kind, advice = "a cookie consent page", "open the link in a browser and pass the direct audio URL or the downloaded file"
+	case contains("subscribe to continue", "subscription required", "subscribers only", "paywall", "to continue reading"):
+		kind, advice = "a paywall page", "the file is only available to subscribers"
+	case contains("link has expired", "link expired", "has expired", "no longer available", "has been removed", "has been deleted", "invalid or expired"):
+		kind, advice = "an expired-link page", "get a fresh link to the file"
+	case isYouTubeURL(url) && !h.useYtDlp:
+		kind, advice = "a YouTube page", "add --yt-dlp to download its audio"
+	case htmlMedia.Match(page):
+		kind, advice = "a web page with embedded media", "pass the direct link to the audio file instead"
+	default:
+		kind, advice = "a web page instead of audio", "possibly a redirect to an error page"
+	}
+	if title != "" {
+		kind += fmt.Sprintf(" (%q)", title)
+	}
+	return fmt.Errorf("URL returned %s; %s", kind, advice)
+}
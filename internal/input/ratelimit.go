@@ -0,0 +1,82 @@
+package input
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseRate parses a transfer rate in bytes per second with an optional
+// K, M or G suffix (powers of 1024), e.g. "500K" or "2M", as used by yt-dlp
+func ParseRate(rate string) (int64, error) {
+	value, ok := parseBytes(rate)
+	if !ok || value <= 0 {
+		return 0, fmt.Errorf("invalid rate: %q. Use bytes per second, e.g. 500K or 2M", rate)
+	}
+	return value, nil
+}
+
+// ParseSize parses a size in bytes with an optional K, M or G suffix (powers
+// of 1024), e.g. "10M"
+func ParseSize(size string) (int64, error) {
+	value, ok := parseBytes(size)
+	if !ok || value < 0 {
+		return 0, fmt.Errorf("invalid size: %q. Use bytes, e.g. 512K or 10M", size)
+	}
+	return value, nil
+}
+
+// parseBytes parses a number of bytes with an optional K, M or G suffix
+func parseBytes(text string) (int64, bool) {
+	s := strings.TrimSpace(text)
+	multiplier := 1.0
+	if s != "" {
+		switch strings.ToUpper(s[len(s)-1:]) {
+		case "K":
+			multiplier = 1 << 10
+		case "M":
+			multiplier = 1 << 20
+		case "G":
+			multiplier = 1 << 30
+		}
+		if multiplier != 1 {
+			s = s[:len(s)-1]
+		}
+	}
+
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	return int64(value * multiplier), true
+}
+
+// rateLimitedReader throttles reads to an average number of bytes per second
+type rateLimitedReader struct {
+	r     io.Reader
+	rate  int64
+	start time.Time
+	read  int64
+}
+
+func newRateLimitedReader(r io.Reader, rate int64) *rateLimitedReader {
+	return &rateLimitedReader{r: r, rate: rate, start: time.Now()}
+}
+
+func (l *rateLimitedReader) Read(p []byte) (int, error) {
+	// Keep individual reads to ~100ms worth of data so throughput stays smooth
+	if burst := max(l.rate/10, 1); int64(len(p)) > burst {
+		p = p[:burst]
+	}
+
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+
+	expected := time.Duration(float64(l.read) / float64(l.rate) * float64(time.Second))
+	if wait := expected - time.Since(l.start); wait > 0 {
+		time.Sleep(wait)
+	}
+	return n, err
+}
@@ -0,0 +1,50 @@
+package input
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// sniffLength is the number of leading bytes sniffAudio looks at
+const sniffLength = 512
+
+// sniffAudio derives the file extension of a download from its leading
+// bytes, since servers often send audio as application/octet-stream or with
+// the wrong type. It returns "" when the format is not recognized, and an
+// error when the content is clearly not audio, such as a web page or image.
+func sniffAudio(head []byte) (string, error) {
+	switch {
+	case len(head) == 0:
+		return "", fmt.Errorf("URL returned an empty file")
+	case bytes.HasPrefix(head, []byte("ID3")):
+		return ".mp3", nil
+	case len(head) >= 2 && head[0] == 0xff && head[1]&0xe0 == 0xe0 && head[1]&0x06 != 0:
+		// MPEG audio frame sync with a layer set; ADTS AAC has layer 0
+		return ".mp3", nil
+	case len(head) >= 12 && string(head[:4]) == "RIFF" && string(head[8:12]) == "WAVE":
+		return ".wav", nil
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		return ".flac", nil
+	case bytes.HasPrefix(head, []byte("OggS")):
+		return ".ogg", nil
+	case bytes.HasPrefix(head, []byte{0x1a, 0x45, 0xdf, 0xa3}):
+		return ".webm", nil
+	case len(head) >= 12 && string(head[4:8]) == "ftyp":
+		if brand := string(head[8:12]); brand == "M4A " || brand == "M4B " {
+			return ".m4a", nil
+		}
+		return ".mp4", nil
+	}
+
+	contentType, _, _ := strings.Cut(http.DetectContentType(head), ";")
+	switch {
+	case strings.HasPrefix(contentType, "text/"), strings.HasPrefix(contentType, "image/"),
+		strings.HasPrefix(contentType, "font/"), contentType == "application/pdf",
+		contentType == "application/zip", contentType == "application/x-gzip",
+		contentType == "application/x-rar-compressed":
+		return "", fmt.Errorf("URL returned %s content instead of audio", contentType)
+	}
+	return "", nil
+}
@@ -0,0 +1,55 @@
+package input
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+)
+
+// spool keeps a download in memory until it grows past limit, then moves it
+// to a temporary file
+type spool struct {
+	limit int64
+	ext   string
+	buf   bytes.Buffer
+	file  *os.File
+}
+
+func (s *spool) Write(p []byte) (int, error) {
+	if s.file == nil && int64(s.buf.Len()+len(p)) > s.limit {
+		file, err := os.CreateTemp("", "whisper-lrc-*"+s.ext)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create temp file: %w", err)
+		}
+		s.file = file
+		if _, err := file.Write(s.buf.Bytes()); err != nil {
+			return 0, err
+		}
+		s.buf = bytes.Buffer{}
+	}
+	if s.file != nil {
+		return s.file.Write(p)
+	}
+	return s.buf.Write(p)
+}
+
+// source closes the spool and returns the download as a source
+func (s *spool) source() (*Source, error) {
+	if s.file == nil {
+		return &Source{Name: "audio" + s.ext, Data: s.buf.Bytes()}, nil
+	}
+	path := s.file.Name()
+	if err := s.file.Close(); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return &Source{Path: path, cleanup: func() { os.Remove(path) }}, nil
+}
+
+// discard removes the temporary file, if any
+func (s *spool) discard() {
+	if s.file != nil {
+		s.file.Close()
+		os.Remove(s.file.Name())
+	}
+}
@@ -0,0 +1,53 @@
+package input
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// spoolChunk is the size of the reads a download is copied in
+const spoolChunk = 32 << 10
+
+// benchmarkSpool copies a download of size through a spool with limit,
+// the way downloadDirect does; known is whether the size was sent
+func benchmarkSpool(b *testing.B, size, limit int64, known bool) {
+	download := make([]byte, size)
+	b.SetBytes(size)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sp := &spool{limit: limit, ext: ".mp3"}
+		if known {
+			sp.buf.Grow(int(size))
+		}
+		// Hide WriterTo so io.Copy reads in chunks like from a response body
+		r := struct{ io.Reader }{bytes.NewReader(download)}
+		if _, err := io.CopyBuffer(sp, r, make([]byte, spoolChunk)); err != nil {
+			b.Fatal(err)
+		}
+		src, err := sp.source()
+		if err != nil {
+			b.Fatal(err)
+		}
+		src.Cleanup()
+	}
+}
+
+// BenchmarkSpoolMemory keeps a download of known size under the limit in
+// memory: one buffer of the download's size, without a second copy
+func BenchmarkSpoolMemory(b *testing.B) {
+	benchmarkSpool(b, 8<<20, 10<<20, true)
+}
+
+// BenchmarkSpoolMemoryUnknownSize is BenchmarkSpoolMemory without a
+// Content-Length, where the buffer grows as the download arrives
+func BenchmarkSpoolMemoryUnknownSize(b *testing.B) {
+	benchmarkSpool(b, 8<<20, 10<<20, false)
+}
+
+// BenchmarkSpoolFile moves a download past the limit to a temp file; the
+// memory used stays near the limit however large the download
+func BenchmarkSpoolFile(b *testing.B) {
+	benchmarkSpool(b, 32<<20, 1<<20, false)
+}
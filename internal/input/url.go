@@ -0,0 +1,95 @@
+package input
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode"
+)
+
+// IsURL reports whether input is an http(s) URL
+func IsURL(input string) bool {
+	lower := strings.ToLower(input)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// urlScheme returns the scheme of input if it has one, such as "ftp" for
+// ftp://host/file. Windows drive letters are not schemes.
+func urlScheme(input string) string {
+	scheme, _, ok := strings.Cut(input, "://")
+	if !ok || len(scheme) < 2 || strings.ContainsAny(scheme, `/\`) {
+		return ""
+	}
+	return strings.ToLower(scheme)
+}
+
+// NormalizeInput rewrites the inputs accepted besides paths and http(s) URLs:
+// file:// URIs become local paths and protocol-relative //host/path URLs get
+// https:. With assumeURL, a host/path input such as example.com/song.mp3 that
+// is not a local file is taken as an https URL too. Existing files always
+// win, and other inputs are returned unchanged.
+func NormalizeInput(input string, assumeURL bool) (string, error) {
+	if IsURL(input) {
+		return input, nil
+	}
+	if urlScheme(input) == "file" {
+		return filePath(input)
+	}
+	if _, err := os.Stat(input); err == nil {
+		return input, nil
+	}
+	if strings.HasPrefix(input, "//") && looksLikeHost(input[2:]) {
+		return "https:" + input, nil
+	}
+	if assumeURL && urlScheme(input) == "" && looksLikeHost(input) {
+		return "https://" + input, nil
+	}
+	return input, nil
+}
+
+// filePath converts a file:// URI to a local path
+func filePath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("invalid file URI %s: %w", uri, err)
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return "", fmt.Errorf("file URI %s names another host; only local files are supported", uri)
+	}
+	path := u.Path
+	// file:///C:/Music/song.mp3
+	if runtime.GOOS == "windows" && len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	return filepath.FromSlash(path), nil
+}
+
+// looksLikeHost reports whether input starts with a host name or IPv4
+// address followed by a path, like example.com/song.mp3
+func looksLikeHost(input string) bool {
+	host, path, ok := strings.Cut(input, "/")
+	if !ok || path == "" {
+		return false
+	}
+	host, _, _ = strings.Cut(host, ":")
+	if host == "localhost" || net.ParseIP(host) != nil {
+		return true
+	}
+	labels := strings.Split(host, ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, label := range labels {
+		if label == "" || strings.IndexFunc(label, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-'
+		}) >= 0 {
+			return false
+		}
+	}
+	tld := labels[len(labels)-1]
+	return strings.IndexFunc(tld, func(r rune) bool { return !unicode.IsLetter(r) }) < 0
+}
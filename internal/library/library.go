@@ -0,0 +1,159 @@
+package library
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Refresher tells a media server that files in some folders changed so new
+// lyrics and subtitles show up without a full library scan. Folders are
+// absolute paths as the server sees them.
+type Refresher interface {
+	Refresh(dirs []string) error
+}
+
+// Jellyfin reports changed folders to a Jellyfin (or Emby) server
+type Jellyfin struct {
+	URL    string
+	APIKey string
+	Client *http.Client
+}
+
+// Refresh posts the folders to /Library/Media/Updated, which rescans only
+// the items under them
+func (j *Jellyfin) Refresh(dirs []string) error {
+	type update struct {
+		Path       string
+		UpdateType string
+	}
+	var body struct{ Updates []update }
+	for _, dir := range dirs {
+		body.Updates = append(body.Updates, update{Path: dir, UpdateType: "Modified"})
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(j.URL, "/")+"/Library/Media/Updated", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("invalid Jellyfin URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("MediaBrowser Token=%q", j.APIKey))
+	resp, err := j.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("jellyfin refresh failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("jellyfin refresh failed: %s", statusError(resp))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// Plex asks a Plex server to scan changed folders of its library sections
+type Plex struct {
+	URL    string
+	Token  string
+	Client *http.Client
+}
+
+// plexSections is the /library/sections listing
+type plexSections struct {
+	Directories []struct {
+		Key       string `xml:"key,attr"`
+		Title     string `xml:"title,attr"`
+		Locations []struct {
+			Path string `xml:"path,attr"`
+		} `xml:"Location"`
+	} `xml:"Directory"`
+}
+
+// Refresh starts a partial scan of each folder in the section whose location
+// contains it. Folders outside every section are an error.
+func (p *Plex) Refresh(dirs []string) error {
+	resp, err := p.get("/library/sections", nil)
+	if err != nil {
+		return err
+	}
+	var sections plexSections
+	err = xml.NewDecoder(resp.Body).Decode(&sections)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("plex refresh failed: invalid section list: %w", err)
+	}
+
+	var missing []string
+	for _, dir := range dirs {
+		key := ""
+		for _, section := range sections.Directories {
+			for _, loc := range section.Locations {
+				if within(dir, loc.Path) {
+					key = section.Key
+				}
+			}
+		}
+		if key == "" {
+			missing = append(missing, dir)
+			continue
+		}
+		resp, err := p.get("/library/sections/"+url.PathEscape(key)+"/refresh", url.Values{"path": {dir}})
+		if err != nil {
+			return err
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("plex refresh: no library section contains %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// get sends an authenticated GET request and checks the status
+func (p *Plex) get(endpoint string, query url.Values) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(p.URL, "/")+endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Plex URL: %w", err)
+	}
+	if query != nil {
+		req.URL.RawQuery = query.Encode()
+	}
+	req.Header.Set("X-Plex-Token", p.Token)
+	req.Header.Set("Accept", "application/xml")
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("plex refresh failed: %w", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		return nil, fmt.Errorf("plex refresh failed: %s", statusError(resp))
+	}
+	return resp, nil
+}
+
+// within reports whether dir is root or inside it. Both sides may use either
+// slash, as the server can run on another OS.
+func within(dir, root string) bool {
+	dir = path.Clean(strings.ReplaceAll(dir, `\`, "/"))
+	root = strings.TrimSuffix(path.Clean(strings.ReplaceAll(root, `\`, "/")), "/")
+	return dir == root || strings.HasPrefix(dir, root+"/")
+}
+
+// statusError describes a failed response with the start of its body
+func statusError(resp *http.Response) string {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if text := strings.TrimSpace(string(msg)); text != "" {
+		return resp.Status + ": " + text
+	}
+	return resp.Status
+}
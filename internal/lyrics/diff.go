@@ -0,0 +1,222 @@
+package lyrics
+
+import (
+	"strings"
+	"unicode"
+)
+
+// DiffOp is the kind of change between two aligned lines
+type DiffOp int
+
+const (
+	// Same lines have the same text
+	Same DiffOp = iota
+	// Changed lines were aligned with each other but their text differs
+	Changed
+	// Removed lines only exist in the reference
+	Removed
+	// Added lines only exist in the compared file
+	Added
+)
+
+// LineDiff pairs a reference line with a line of the compared file. Ref is
+// nil for added lines and Hyp is nil for removed lines.
+type LineDiff struct {
+	Op  DiffOp
+	Ref *Line
+	Hyp *Line
+}
+
+// Delta returns how much later the compared line starts, in seconds
+func (d LineDiff) Delta() float64 {
+	if d.Ref == nil || d.Hyp == nil {
+		return 0
+	}
+	return d.Hyp.Start - d.Ref.Start
+}
+
+// Diff is the comparison of a file against a reference
+type Diff struct {
+	Lines []LineDiff
+
+	// Word-level edit counts behind the word error rate
+	RefWords      int
+	Substitutions int
+	Deletions     int
+	Insertions    int
+}
+
+// WER returns the word error rate of the compared file against the reference
+func (d *Diff) WER() float64 {
+	if d.RefWords == 0 {
+		if d.Insertions > 0 {
+			return 1
+		}
+		return 0
+	}
+	return float64(d.Substitutions+d.Deletions+d.Insertions) / float64(d.RefWords)
+}
+
+// Compare aligns the lines of hyp with ref and counts word errors. Lines are
+// compared in time order; case and punctuation are ignored.
+func Compare(ref, hyp *File) *Diff {
+	refLines := sortedLines(ref)
+	hypLines := sortedLines(hyp)
+	d := &Diff{}
+
+	// Line alignment
+	refKeys := make([]string, len(refLines))
+	for i, line := range refLines {
+		refKeys[i] = strings.Join(words(line.Text), " ")
+	}
+	hypKeys := make([]string, len(hypLines))
+	for i, line := range hypLines {
+		hypKeys[i] = strings.Join(words(line.Text), " ")
+	}
+	for _, step := range align(refKeys, hypKeys) {
+		ld := LineDiff{Op: step.op}
+		if step.ref >= 0 {
+			ld.Ref = &refLines[step.ref]
+		}
+		if step.hyp >= 0 {
+			ld.Hyp = &hypLines[step.hyp]
+		}
+		d.Lines = append(d.Lines, ld)
+	}
+
+	// Word error rate over the whole text
+	var refWords, hypWords []string
+	for _, line := range refLines {
+		refWords = append(refWords, words(line.Text)...)
+	}
+	for _, line := range hypLines {
+		hypWords = append(hypWords, words(line.Text)...)
+	}
+	d.RefWords = len(refWords)
+	d.Substitutions, d.Deletions, d.Insertions = wordErrors(refWords, hypWords)
+	return d
+}
+
+func sortedLines(f *File) []Line {
+	result := f.Result()
+	lines := make([]Line, len(result.Segments))
+	for i, seg := range result.Segments {
+		lines[i] = Line{Start: seg.Start, End: seg.End, Text: seg.Text}
+	}
+	return lines
+}
+
+// words splits text into lowercase words for comparison. Scripts written
+// without spaces (Chinese, Japanese) count each character as a word.
+func words(text string) []string {
+	var result []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			result = append(result, string(word))
+			word = word[:0]
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana):
+			flush()
+			result = append(result, string(r))
+		case unicode.IsLetter(r) || unicode.IsNumber(r) || r == '\'':
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return result
+}
+
+type alignStep struct {
+	op       DiffOp
+	ref, hyp int // indexes, -1 when absent
+}
+
+// align computes a minimal edit script turning ref into hyp
+func align(ref, hyp []string) []alignStep {
+	// dist[i][j] is the edit distance between ref[i:] and hyp[j:]
+	dist := make([][]int, len(ref)+1)
+	for i := range dist {
+		dist[i] = make([]int, len(hyp)+1)
+	}
+	for i := len(ref); i >= 0; i-- {
+		for j := len(hyp); j >= 0; j-- {
+			switch {
+			case i == len(ref):
+				dist[i][j] = len(hyp) - j
+			case j == len(hyp):
+				dist[i][j] = len(ref) - i
+			default:
+				sub := dist[i+1][j+1]
+				if ref[i] != hyp[j] {
+					sub++
+				}
+				dist[i][j] = min(sub, dist[i+1][j]+1, dist[i][j+1]+1)
+			}
+		}
+	}
+
+	var steps []alignStep
+	i, j := 0, 0
+	for i < len(ref) || j < len(hyp) {
+		switch {
+		case i < len(ref) && j < len(hyp) && ref[i] == hyp[j] && dist[i][j] == dist[i+1][j+1]:
+			steps = append(steps, alignStep{Same, i, j})
+			i, j = i+1, j+1
+		case i < len(ref) && j < len(hyp) && dist[i][j] == dist[i+1][j+1]+1:
+			steps = append(steps, alignStep{Changed, i, j})
+			i, j = i+1, j+1
+		case i < len(ref) && dist[i][j] == dist[i+1][j]+1:
+			steps = append(steps, alignStep{Removed, i, -1})
+			i++
+		default:
+			steps = append(steps, alignStep{Added, -1, j})
+			j++
+		}
+	}
+	return steps
+}
+
+// edits is an edit distance split by kind of edit
+type edits struct {
+	total, sub, del, ins int
+}
+
+// wordErrors counts the substitutions, deletions and insertions of a minimal
+// edit script, keeping only two rows so long transcripts stay cheap
+func wordErrors(ref, hyp []string) (sub, del, ins int) {
+	prev := make([]edits, len(hyp)+1)
+	cur := make([]edits, len(hyp)+1)
+	for j := range prev {
+		prev[j] = edits{total: j, ins: j}
+	}
+	for i := 1; i <= len(ref); i++ {
+		cur[0] = edits{total: i, del: i}
+		for j := 1; j <= len(hyp); j++ {
+			best := prev[j-1]
+			if ref[i-1] != hyp[j-1] {
+				best.total++
+				best.sub++
+			}
+			if prev[j].total+1 < best.total {
+				best = prev[j]
+				best.total++
+				best.del++
+			}
+			if cur[j-1].total+1 < best.total {
+				best = cur[j-1]
+				best.total++
+				best.ins++
+			}
+			cur[j] = best
+		}
+		prev, cur = cur, prev
+	}
+	last := prev[len(hyp)]
+	return last.sub, last.del, last.ins
+}
@@ -0,0 +1,115 @@
+package lyrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/BBleae/whisper-lrc/internal/output"
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// Lint checks line timing and adds the problems found to f.Issues
+func (f *File) Lint() {
+	latest := math.Inf(-1)
+	seen := make(map[int64]int)
+	for i, line := range f.Lines {
+		ms := millis(line.Start)
+		if !line.Repeat {
+			if line.Start < latest {
+				f.Issues = append(f.Issues, Issue{Line: line.Number, Message: "line is out of order", Fixable: true})
+			}
+			latest = math.Max(latest, line.Start)
+		}
+
+		if first, ok := seen[ms]; ok {
+			f.Issues = append(f.Issues, Issue{Line: line.Number, Message: fmt.Sprintf("duplicate timestamp (also on line %d)", first), Fixable: true})
+		} else {
+			seen[ms] = line.Number
+		}
+
+		if f.Format != "srt" {
+			continue
+		}
+		if line.End <= line.Start {
+			f.Issues = append(f.Issues, Issue{Line: line.Number, Message: "cue ends before it starts"})
+		}
+		if i > 0 && line.Start > f.Lines[i-1].Start && line.Start < f.Lines[i-1].End {
+			f.Issues = append(f.Issues, Issue{Line: line.Number, Message: "cue overlaps the previous cue"})
+		}
+	}
+
+	sort.SliceStable(f.Issues, func(i, j int) bool {
+		return f.Issues[i].Line < f.Issues[j].Line
+	})
+}
+
+// Fix repairs the fixable issues: lines are sorted by time, lines sharing a
+// timestamp are merged and, when the file is written, headers are moved to
+// the top, cues are renumbered and the byte order mark is dropped. It returns
+// the number of issues fixed; the others remain in f.Issues. Files with lines
+// that could not be parsed are left alone so rewriting them loses nothing.
+func (f *File) Fix() (int, error) {
+	if f.unparsed > 0 {
+		return 0, fmt.Errorf("%d line(s) could not be parsed; correct them before using --fix", f.unparsed)
+	}
+
+	sort.SliceStable(f.Lines, func(i, j int) bool {
+		return millis(f.Lines[i].Start) < millis(f.Lines[j].Start)
+	})
+
+	var merged []Line
+	for _, line := range f.Lines {
+		if n := len(merged); n > 0 && millis(merged[n-1].Start) == millis(line.Start) {
+			prev := &merged[n-1]
+			if line.Text != prev.Text {
+				prev.Text = strings.TrimSpace(prev.Text + " " + line.Text)
+			}
+			prev.End = math.Max(prev.End, line.End)
+			prev.Comments = append(prev.Comments, line.Comments...)
+			continue
+		}
+		merged = append(merged, line)
+	}
+	f.Lines = merged
+
+	fixed := 0
+	var remaining []Issue
+	for _, issue := range f.Issues {
+		if issue.Fixable {
+			fixed++
+		} else {
+			remaining = append(remaining, issue)
+		}
+	}
+	f.Issues = remaining
+	return fixed, nil
+}
+
+// String renders the file in its format
+func (f *File) String() string {
+	if f.Format == "srt" {
+		return output.NewSRTFormatter().Format(f.Result())
+	}
+
+	var sb strings.Builder
+	for _, header := range f.Headers {
+		sb.WriteString(header + "\n")
+	}
+	if len(f.Headers) > 0 {
+		sb.WriteString("\n")
+	}
+	for _, line := range f.Lines {
+		for _, comment := range line.Comments {
+			sb.WriteString(comment + "\n")
+		}
+		sb.WriteString(output.FormatLRCLine(whisper.Segment{Start: line.Start, Text: line.Text}))
+	}
+	return sb.String()
+}
+
+// millis rounds seconds to whole milliseconds for comparisons
+func millis(seconds float64) int64 {
+	return int64(math.Round(seconds * 1000))
+}
@@ -0,0 +1,299 @@
+package lyrics
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// File is a parsed LRC or SRT lyrics file
+type File struct {
+	Format string // "lrc" or "srt"
+	// Headers holds LRC tag lines such as [ar:Artist], verbatim
+	Headers []string
+	Lines   []Line
+	// Issues lists problems found while parsing and linting
+	Issues []Issue
+
+	// unparsed counts lines that would be lost when the file is rewritten
+	unparsed int
+}
+
+// Line is one timed lyric line or subtitle cue
+type Line struct {
+	Start float64 // seconds
+	End   float64 // seconds; only set for SRT cues
+	Text  string
+	// Comments holds "#" comment lines (e.g. section markers) that precede
+	// the line in LRC files
+	Comments []string
+	Number   int // 1-based line number in the source file
+	// Repeat is set for the second and later timestamps of an LRC line
+	// such as [00:12.00][01:05.00]Chorus
+	Repeat bool
+}
+
+// Issue is a problem found in a lyrics file
+type Issue struct {
+	Line    int // 1-based line number, 0 for the whole file
+	Message string
+	// Fixable is set for issues Fix can repair
+	Fixable bool
+}
+
+func (i Issue) String() string {
+	if i.Line == 0 {
+		return i.Message
+	}
+	return fmt.Sprintf("line %d: %s", i.Line, i.Message)
+}
+
+// Load reads and parses a lyrics file, picking the format from its extension
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var issues []Issue
+	if !utf8.Valid(data) {
+		issues = append(issues, Issue{Message: "file is not valid UTF-8"})
+	}
+	text := string(data)
+	if strings.HasPrefix(text, "\ufeff") {
+		text = strings.TrimPrefix(text, "\ufeff")
+		issues = append(issues, Issue{Message: "file starts with a byte order mark", Fixable: true})
+	}
+
+	var f *File
+	if strings.EqualFold(filepath.Ext(path), ".srt") {
+		f = ParseSRT(text)
+	} else {
+		f = ParseLRC(text)
+	}
+	f.Issues = append(issues, f.Issues...)
+	return f, nil
+}
+
+// Result converts the file to a transcription result with segments in time
+// order. LRC lines end where the next line starts.
+func (f *File) Result() *whisper.TranscriptionResult {
+	lines := append([]Line(nil), f.Lines...)
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].Start < lines[j].Start
+	})
+
+	result := &whisper.TranscriptionResult{}
+	for i, line := range lines {
+		end := line.End
+		if f.Format == "lrc" {
+			end = line.Start
+			if i+1 < len(lines) {
+				end = lines[i+1].Start
+			}
+		}
+		result.Segments = append(result.Segments, whisper.Segment{Start: line.Start, End: end, Text: line.Text})
+		result.Text += line.Text + " "
+	}
+	result.Text = strings.TrimSpace(result.Text)
+	if len(f.Lines) > 0 {
+		result.Duration = result.Segments[len(result.Segments)-1].End
+	}
+	return result
+}
+
+var (
+	lrcTagPattern       = regexp.MustCompile(`^\[([^\]]*)\]`)
+	lrcTimestampPattern = regexp.MustCompile(`^(\d+):(\d+)(?:[.:](\d{1,3}))?$`)
+	lrcHeaderPattern    = regexp.MustCompile(`^([A-Za-z#]+):(.*)$`)
+)
+
+// lrcHeaderKeys are the ID tags defined by the LRC format
+var lrcHeaderKeys = map[string]bool{
+	"ar": true, "al": true, "ti": true, "au": true, "by": true,
+	"length": true, "offset": true, "re": true, "ve": true,
+	"la": true, "#": true,
+}
+
+// ParseLRC parses LRC lyrics. Lines with several timestamps are expanded into
+// one Line per timestamp.
+func ParseLRC(text string) *File {
+	f := &File{Format: "lrc"}
+	var comments []string
+
+	for i, raw := range strings.Split(text, "\n") {
+		number := i + 1
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			comments = append(comments, line)
+			continue
+		}
+
+		// Collect leading [..] tags
+		var times []float64
+		rest := line
+		header, malformed := false, false
+		for {
+			m := lrcTagPattern.FindStringSubmatch(rest)
+			if m == nil {
+				break
+			}
+			if seconds, ok, err := parseLRCTimestamp(m[1]); ok {
+				if err != nil {
+					f.Issues = append(f.Issues, Issue{Line: number, Message: err.Error()})
+					malformed = true
+				} else {
+					times = append(times, seconds)
+				}
+			} else if len(times) == 0 && lrcHeaderPattern.MatchString(m[1]) && strings.TrimSpace(rest[len(m[0]):]) == "" {
+				f.parseHeader(m[0], m[1], number)
+				header = true
+			} else {
+				break
+			}
+			rest = rest[len(m[0]):]
+		}
+		if header {
+			continue
+		}
+		if len(times) == 0 {
+			if !malformed {
+				f.Issues = append(f.Issues, Issue{Line: number, Message: "line has no timestamp"})
+			}
+			f.unparsed++
+			continue
+		}
+
+		text := strings.TrimSpace(rest)
+		for j, t := range times {
+			f.Lines = append(f.Lines, Line{Start: t, Text: text, Comments: comments, Number: number, Repeat: j > 0})
+			comments = nil
+		}
+	}
+	return f
+}
+
+func (f *File) parseHeader(tag, content string, number int) {
+	m := lrcHeaderPattern.FindStringSubmatch(content)
+	key, value := strings.ToLower(m[1]), strings.TrimSpace(m[2])
+
+	if len(f.Lines) > 0 {
+		f.Issues = append(f.Issues, Issue{Line: number, Message: fmt.Sprintf("header tag %s after lyrics", tag), Fixable: true})
+	}
+	switch {
+	case !lrcHeaderKeys[key]:
+		f.Issues = append(f.Issues, Issue{Line: number, Message: fmt.Sprintf("unknown header tag %s", tag)})
+	case key == "offset":
+		if _, err := strconv.Atoi(value); err != nil {
+			f.Issues = append(f.Issues, Issue{Line: number, Message: fmt.Sprintf("offset must be whole milliseconds: %s", tag)})
+		}
+	case key == "length":
+		if _, ok, err := parseLRCTimestamp(value); !ok || err != nil {
+			f.Issues = append(f.Issues, Issue{Line: number, Message: fmt.Sprintf("length must be mm:ss: %s", tag)})
+		}
+	}
+	for _, existing := range f.Headers {
+		if h := lrcHeaderPattern.FindStringSubmatch(strings.Trim(existing, "[]")); h != nil && strings.ToLower(h[1]) == key && key != "#" {
+			f.Issues = append(f.Issues, Issue{Line: number, Message: fmt.Sprintf("duplicate header tag %s", tag)})
+			break
+		}
+	}
+	f.Headers = append(f.Headers, tag)
+}
+
+// parseLRCTimestamp parses mm:ss, mm:ss.xx or mm:ss.xxx. ok reports whether
+// the tag looks like a timestamp at all; err is set when it is malformed.
+func parseLRCTimestamp(tag string) (seconds float64, ok bool, err error) {
+	m := lrcTimestampPattern.FindStringSubmatch(tag)
+	if m == nil {
+		return 0, false, nil
+	}
+	mins, _ := strconv.Atoi(m[1])
+	secs, _ := strconv.Atoi(m[2])
+	if secs >= 60 {
+		return 0, true, fmt.Errorf("invalid timestamp [%s]: seconds out of range", tag)
+	}
+	ms := 0
+	if m[3] != "" {
+		frac := m[3]
+		ms, _ = strconv.Atoi(frac)
+		for i := len(frac); i < 3; i++ {
+			ms *= 10
+		}
+	}
+	return float64(mins*60000+secs*1000+ms) / 1000, true, nil
+}
+
+var srtTimingPattern = regexp.MustCompile(`^(\d+):(\d{2}):(\d{2})[,.](\d{3})\s*-->\s*(\d+):(\d{2}):(\d{2})[,.](\d{3})`)
+
+// ParseSRT parses SRT subtitles
+func ParseSRT(text string) *File {
+	f := &File{Format: "srt"}
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	expected := 1
+	for i := 0; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "" {
+			continue
+		}
+		number := i + 1
+
+		// Sequence number, then timing
+		index, err := strconv.Atoi(strings.TrimSpace(lines[i]))
+		if err == nil {
+			if index != expected {
+				f.Issues = append(f.Issues, Issue{Line: number, Message: fmt.Sprintf("cue number %d, expected %d", index, expected), Fixable: true})
+			}
+			i++
+		} else {
+			f.Issues = append(f.Issues, Issue{Line: number, Message: "cue has no sequence number", Fixable: true})
+		}
+		expected++
+		if i >= len(lines) {
+			f.Issues = append(f.Issues, Issue{Line: number, Message: "cue has no timing line"})
+			f.unparsed++
+			break
+		}
+
+		m := srtTimingPattern.FindStringSubmatch(strings.TrimSpace(lines[i]))
+		if m == nil {
+			f.Issues = append(f.Issues, Issue{Line: i + 1, Message: fmt.Sprintf("malformed timing line %q", strings.TrimSpace(lines[i]))})
+			f.unparsed++
+			for i < len(lines) && strings.TrimSpace(lines[i]) != "" {
+				i++
+			}
+			continue
+		}
+		cue := Line{Start: srtSeconds(m[1:5]), End: srtSeconds(m[5:9]), Number: i + 1}
+
+		var text []string
+		for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+			i++
+			text = append(text, strings.TrimSpace(lines[i]))
+		}
+		cue.Text = strings.Join(text, "\n")
+		if cue.Text == "" {
+			f.Issues = append(f.Issues, Issue{Line: cue.Number, Message: "cue has no text"})
+		}
+		f.Lines = append(f.Lines, cue)
+	}
+	return f
+}
+
+func srtSeconds(parts []string) float64 {
+	h, _ := strconv.Atoi(parts[0])
+	m, _ := strconv.Atoi(parts[1])
+	s, _ := strconv.Atoi(parts[2])
+	ms, _ := strconv.Atoi(parts[3])
+	return float64(h*3600000+m*60000+s*1000+ms) / 1000
+}
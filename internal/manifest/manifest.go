@@ -0,0 +1,93 @@
+// Package manifest records which inputs a --sync run has transcribed, so
+// later runs only process new and changed audio
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/BBleae/whisper-lrc/internal/storage"
+)
+
+// Entry is the state of one input after its last successful transcription
+type Entry struct {
+	// SHA256 is the hash of the audio that was transcribed
+	SHA256 string    `json:"sha256"`
+	Output string    `json:"output"`
+	Synced time.Time `json:"synced"`
+}
+
+// Manifest maps inputs (absolute paths or URLs) to their entries
+type Manifest struct {
+	Files map[string]Entry `json:"files"`
+	path  string
+}
+
+// Load reads the manifest at path. A missing file gives an empty manifest
+// that is created on the first Save.
+func Load(path string) (*Manifest, error) {
+	m := &Manifest{Files: map[string]Entry{}, path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	if m.Files == nil {
+		m.Files = map[string]Entry{}
+	}
+	return m, nil
+}
+
+// Unchanged reports whether input was transcribed before from audio with
+// the same hash
+func (m *Manifest) Unchanged(input, hash string) bool {
+	entry, ok := m.Files[input]
+	return ok && entry.SHA256 == hash
+}
+
+// Record notes a successful transcription of input
+func (m *Manifest) Record(input, hash, output string) {
+	m.Files[input] = Entry{SHA256: hash, Output: output, Synced: time.Now().UTC()}
+}
+
+// Save writes the manifest back to its file
+func (m *Manifest) Save() error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := storage.WriteFile(m.path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// Hash returns the hex SHA-256 of data
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// HashFile returns the hex SHA-256 of a file's contents
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
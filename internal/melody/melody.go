@@ -0,0 +1,71 @@
+package melody
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// Line is the pitch contour of one lyric line
+type Line struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+	// F0 holds the vocal pitch in Hz every Hop seconds from Start; 0 marks
+	// frames without a detected pitch
+	F0 []float64 `json:"f0"`
+}
+
+// Melody is the melody document written as an auxiliary output
+type Melody struct {
+	Hop   float64 `json:"hop"`
+	Lines []Line  `json:"lines"`
+}
+
+// Extract cuts a pitch track (Hz every hop seconds from the start of the
+// audio) into the contours of the transcribed lines
+func Extract(result *whisper.TranscriptionResult, f0 []float64, hop float64) *Melody {
+	m := &Melody{Hop: hop, Lines: make([]Line, 0, len(result.Segments))}
+	for _, seg := range result.Segments {
+		first := int(math.Round(seg.Start / hop))
+		last := int(math.Round(seg.End / hop))
+		first, last = max(first, 0), min(last, len(f0))
+
+		contour := make([]float64, 0, max(last-first, 0))
+		for i := first; i < last; i++ {
+			contour = append(contour, math.Round(f0[i]*10)/10)
+		}
+		m.Lines = append(m.Lines, Line{
+			Start: seg.Start,
+			End:   seg.End,
+			Text:  strings.TrimSpace(seg.Text),
+			F0:    contour,
+		})
+	}
+	return m
+}
+
+// minVoiced is the fewest pitched frames needed to name a note
+const minVoiced = 3
+
+// Note returns the MIDI note of the median pitch between start and end, or
+// false when too little of that time has a detected pitch
+func Note(f0 []float64, hop, start, end float64) (int, bool) {
+	first := max(int(math.Round(start/hop)), 0)
+	last := min(int(math.Round(end/hop)), len(f0))
+
+	var voiced []float64
+	for i := first; i < last; i++ {
+		if f0[i] > 0 {
+			voiced = append(voiced, f0[i])
+		}
+	}
+	if len(voiced) < minVoiced {
+		return 0, false
+	}
+	sort.Float64s(voiced)
+	median := voiced[len(voiced)/2]
+	return int(math.Round(69 + 12*math.Log2(median/440))), true
+}
@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Failure categories of a file
+const (
+	FailureAuth              = "auth"
+	FailureRateLimited       = "rate_limited"
+	FailureTooLarge          = "too_large"
+	FailureDownload          = "download"
+	FailureUnsupportedFormat = "unsupported_format"
+	FailureOther             = "other"
+)
+
+// Run is the timing of one batch. Like the usage history it holds no file
+// names or lyrics, and it is only ever written to the local file.
+type Run struct {
+	Time              time.Time      `json:"time"`
+	Files             int            `json:"files"`
+	Failed            int            `json:"failed,omitempty"`
+	Seconds           float64        `json:"seconds"`
+	AudioSeconds      float64        `json:"audio_seconds"`
+	DownloadSeconds   float64        `json:"download_seconds"`
+	TranscribeSeconds float64        `json:"transcribe_seconds"`
+	Failures          map[string]int `json:"failures,omitempty"`
+}
+
+// AddFailure counts a failed file in a category
+func (r *Run) AddFailure(category string) {
+	if r.Failures == nil {
+		r.Failures = map[string]int{}
+	}
+	r.Failures[category]++
+	r.Failed++
+}
+
+// Store is an append-only JSON Lines file of runs
+type Store struct {
+	path string
+}
+
+// DefaultPath returns the metrics file in the user config directory
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	return filepath.Join(dir, "whisper-lrc", "metrics.jsonl"), nil
+}
+
+// Open returns the store at path; the file is created on the first Append
+func Open(path string) *Store {
+	return &Store{path: path}
+}
+
+// Append adds a run to the store
+func (s *Store) Append(r Run) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to encode metrics: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create metrics directory: %w", err)
+	}
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open metrics: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	return nil
+}
+
+// Load returns all runs, oldest first. A missing file has no runs; malformed
+// lines are skipped.
+func (s *Store) Load() ([]Run, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open metrics: %w", err)
+	}
+	defer f.Close()
+
+	var runs []Run
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r Run
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue
+		}
+		runs = append(runs, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read metrics: %w", err)
+	}
+	return runs, nil
+}
+
+// Since returns the runs at or after t
+func Since(runs []Run, t time.Time) []Run {
+	var matched []Run
+	for _, r := range runs {
+		if !r.Time.Before(t) {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Result is the outcome of one input
+type Result struct {
+	Input string
+	// Output is where the lyrics were written; empty when the input failed
+	Output string
+	Error  string
+}
+
+// Summary describes a finished batch
+type Summary struct {
+	Host     string
+	Duration time.Duration
+	Results  []Result
+	// NotProcessed counts inputs skipped because the batch stopped early or
+	// they had not changed
+	NotProcessed int
+}
+
+// Failed returns the number of inputs that failed
+func (s *Summary) Failed() int {
+	failed := 0
+	for _, r := range s.Results {
+		if r.Error != "" {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Headline is a one-line description of the batch
+func (s *Summary) Headline() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d file(s) done", len(s.Results)-s.Failed())
+	if failed := s.Failed(); failed > 0 {
+		fmt.Fprintf(&sb, ", %d failed", failed)
+	}
+	if s.NotProcessed > 0 {
+		fmt.Fprintf(&sb, ", %d not processed", s.NotProcessed)
+	}
+	return sb.String()
+}
+
+// Text is a plain-text report with a line per input
+func (s *Summary) Text() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "whisper-lrc finished on %s after %s: %s.\n\n", s.Host, s.Duration.Round(time.Second), s.Headline())
+	for _, r := range s.Results {
+		if r.Error != "" {
+			fmt.Fprintf(&sb, "✗ %s: %s\n", r.Input, r.Error)
+		} else {
+			fmt.Fprintf(&sb, "✓ %s -> %s\n", r.Input, r.Output)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,145 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Webhook formats
+const (
+	Slack    = "slack"
+	Discord  = "discord"
+	Telegram = "telegram"
+)
+
+// Message length limits of each service, in characters
+var messageLimits = map[string]int{
+	Slack:    40000,
+	Discord:  2000,
+	Telegram: 4096,
+}
+
+// Webhook is a chat channel that receives the summary
+type Webhook struct {
+	Format string
+	URL    string
+}
+
+// ParseWebhook reads a webhook URL. The format is taken from the host
+// (hooks.slack.com, discord.com, api.telegram.org) or from a "slack:",
+// "discord:" or "telegram:" prefix, e.g. for a Slack-compatible Mattermost
+// server. Telegram URLs are the bot's sendMessage method with a chat_id
+// query parameter.
+func ParseWebhook(s string) (Webhook, error) {
+	format := ""
+	if prefix, rest, ok := strings.Cut(s, ":"); ok {
+		if _, known := messageLimits[prefix]; known {
+			format, s = prefix, rest
+		}
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return Webhook{}, fmt.Errorf("invalid webhook URL %q", s)
+	}
+	if format == "" {
+		switch strings.ToLower(u.Hostname()) {
+		case "hooks.slack.com":
+			format = Slack
+		case "discord.com", "discordapp.com", "ptb.discord.com", "canary.discord.com":
+			format = Discord
+		case "api.telegram.org":
+			format = Telegram
+		default:
+			return Webhook{}, fmt.Errorf("unknown webhook service %s (prefix the URL with slack:, discord: or telegram:)", u.Host)
+		}
+	}
+	if format == Telegram && u.Query().Get("chat_id") == "" {
+		return Webhook{}, fmt.Errorf("the Telegram webhook URL needs a chat_id parameter")
+	}
+	return Webhook{Format: format, URL: s}, nil
+}
+
+// SendWebhook posts the summary to a chat channel
+func SendWebhook(client *http.Client, w Webhook, s *Summary) error {
+	var payload map[string]any
+	switch w.Format {
+	case Slack:
+		payload = map[string]any{"text": s.message(messageLimits[Slack], "*")}
+	case Discord:
+		payload = map[string]any{
+			"content": s.message(messageLimits[Discord], "**"),
+			// File names should not ping anyone
+			"allowed_mentions": map[string]any{"parse": []string{}},
+		}
+	case Telegram:
+		payload = map[string]any{
+			"text":                     s.message(messageLimits[Telegram], ""),
+			"disable_web_page_preview": true,
+		}
+	default:
+		return fmt.Errorf("unknown webhook format %q", w.Format)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(w.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// The error repeats the URL, which holds the webhook's secret
+		if uerr, ok := err.(*url.Error); ok {
+			err = uerr.Err
+		}
+		return fmt.Errorf("failed to notify %s: %w", w.Format, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("failed to notify %s: %s: %s", w.Format, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// message renders the summary as a chat message of at most limit
+// characters, with the headline wrapped in emphasis markers. Per-file lines
+// that do not fit are counted instead.
+func (s *Summary) message(limit int, emphasis string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%swhisper-lrc: %s%s\n", emphasis, s.Headline(), emphasis)
+	fmt.Fprintf(&sb, "%s, %s\n", s.Host, s.Duration.Round(time.Second))
+
+	// Failures first, as they are what the channel needs to act on
+	var lines []string
+	for _, r := range s.Results {
+		if r.Error != "" {
+			lines = append(lines, fmt.Sprintf("✗ %s: %s", r.Input, r.Error))
+		}
+	}
+	for _, r := range s.Results {
+		if r.Error == "" {
+			lines = append(lines, fmt.Sprintf("✓ %s -> %s", r.Input, r.Output))
+		}
+	}
+
+	// Room for the "and N more" line
+	const reserve = 32
+	used := len([]rune(sb.String()))
+	for i, line := range lines {
+		n := len([]rune(line)) + 1
+		if used+n > limit-reserve {
+			fmt.Fprintf(&sb, "…and %d more", len(lines)-i)
+			break
+		}
+		sb.WriteString(line + "\n")
+		used += n
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
@@ -0,0 +1,101 @@
+package output
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// ASS layout, sized for 1080p video
+const (
+	ASSWidth  = 1920
+	ASSHeight = 1080
+)
+
+// assHeader declares a bottom-centered karaoke style: words sweep from white
+// (secondary colour) to gold (primary colour) as they are sung
+const assHeader = `[Script Info]
+; Generated by whisper-lrc
+Title: %s
+ScriptType: v4.00+
+PlayResX: %d
+PlayResY: %d
+WrapStyle: 0
+ScaledBorderAndShadow: yes
+
+[V4+ Styles]
+Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding
+Style: Default,Arial,72,&H0000D7FF,&H00FFFFFF,&H00000000,&H80000000,-1,0,0,0,100,100,0,0,1,4,2,2,60,60,120,1
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+`
+
+// ASSFormatter formats transcription as Advanced SubStation Alpha subtitles
+// with \kf karaoke tags for every word with timing
+type ASSFormatter struct {
+	Title string
+	// LeadIn shows each line this long before it is sung
+	LeadIn time.Duration
+}
+
+// NewASSFormatter creates a new ASS formatter with a one second lead-in
+func NewASSFormatter() *ASSFormatter {
+	return &ASSFormatter{LeadIn: time.Second}
+}
+
+// Format converts transcription result to ASS format
+func (f *ASSFormatter) Format(result *whisper.TranscriptionResult) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(assHeader, assText(f.Title), ASSWidth, ASSHeight))
+
+	for _, seg := range result.Segments {
+		if strings.TrimSpace(seg.Text) == "" {
+			continue
+		}
+		words := seg.Words
+		if len(words) == 0 {
+			words = []whisper.Word{{Start: seg.Start, End: seg.End, Word: seg.Text}}
+		}
+
+		// Karaoke tags count centiseconds from the start of the dialogue line
+		start := max(centiseconds(seg.Start-f.LeadIn.Seconds()), 0)
+		end := max(centiseconds(seg.End), start)
+		var text strings.Builder
+		cursor := start
+		for i, word := range words {
+			if ws := centiseconds(word.Start); ws > cursor {
+				text.WriteString(fmt.Sprintf(`{\k%d}`, ws-cursor))
+				cursor = ws
+			}
+			we := max(centiseconds(word.End), cursor)
+			w := word.Word
+			if i == 0 {
+				w = strings.TrimSpace(w)
+			}
+			text.WriteString(fmt.Sprintf(`{\kf%d}%s`, we-cursor, assText(w)))
+			cursor = we
+		}
+		end = max(end, cursor)
+
+		sb.WriteString(fmt.Sprintf("Dialogue: 0,%s,%s,Default,,0,0,0,,%s\n",
+			formatASSTimestamp(start), formatASSTimestamp(end), text.String()))
+	}
+
+	return sb.String()
+}
+
+func centiseconds(seconds float64) int {
+	return int(math.Round(seconds * 100))
+}
+
+// formatASSTimestamp converts centiseconds to ASS timestamp format 0:00:00.00
+func formatASSTimestamp(cs int) string {
+	return fmt.Sprintf("%d:%02d:%02d.%02d", cs/360000, cs/6000%60, cs/100%60, cs%100)
+}
+
+// assText replaces characters that ASS treats as markup
+var assText = strings.NewReplacer("{", "(", "}", ")", `\`, "/", "\n", " ", "\r", "").Replace
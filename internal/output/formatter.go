@@ -1,9 +1,13 @@
 package output
 
 import (
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
+	"unicode"
 
+	"github.com/BBleae/whisper-lrc/internal/structure"
 	"github.com/BBleae/whisper-lrc/internal/whisper"
 )
 
@@ -13,7 +17,18 @@ type Formatter interface {
 }
 
 // LRCFormatter formats transcription as LRC lyrics
-type LRCFormatter struct{}
+type LRCFormatter struct {
+	// MarkSections adds "# Verse"/"# Chorus" comment lines before each detected section
+	MarkSections bool
+	// MarkLanguages prefixes each line with its language code, e.g. "(ja) "
+	MarkLanguages bool
+	// WordTimes adds enhanced LRC <mm:ss.xx> tags before each word of
+	// segments with word timing
+	WordTimes bool
+	// Offset is written as an [offset:] tag when non-zero; players show a
+	// line Offset milliseconds before its timestamp
+	Offset int
+}
 
 // NewLRCFormatter creates a new LRC formatter
 func NewLRCFormatter() *LRCFormatter {
@@ -29,18 +44,58 @@ func (f *LRCFormatter) Format(result *whisper.TranscriptionResult) string {
 	if result.Language != "" {
 		sb.WriteString(fmt.Sprintf("[la:%s]\n", result.Language))
 	}
+	if f.Offset != 0 {
+		sb.WriteString(fmt.Sprintf("[offset:%+d]\n", f.Offset))
+	}
 	sb.WriteString("\n")
 
+	// Map segment index to the section starting there
+	sectionStarts := make(map[int]string)
+	if f.MarkSections {
+		for _, section := range structure.Detect(result) {
+			sectionStarts[section.First] = structure.Label(section.Kind)
+		}
+	}
+
 	// Add lyrics with timestamps
-	for _, seg := range result.Segments {
-		timestamp := formatLRCTimestamp(seg.Start)
-		text := strings.TrimSpace(seg.Text)
-		sb.WriteString(fmt.Sprintf("[%s]%s\n", timestamp, text))
+	for i, seg := range result.Segments {
+		if label, ok := sectionStarts[i]; ok {
+			sb.WriteString(fmt.Sprintf("# %s\n", label))
+		}
+		if f.WordTimes && len(seg.Words) > 0 {
+			seg.Text = formatLRCWords(seg)
+		}
+		if f.MarkLanguages {
+			seg.Text = markLanguage(result.LanguageOf(seg), seg.Text)
+		}
+		sb.WriteString(FormatLRCLine(seg))
 	}
 
 	return sb.String()
 }
 
+// formatLRCWords renders segment text with a tag before each word and one
+// for the end of the last word, e.g. "<00:12.00>Hello <00:12.50>world <00:13.10>"
+func formatLRCWords(seg whisper.Segment) string {
+	var sb strings.Builder
+	for i, word := range seg.Words {
+		text := word.Word
+		if i == 0 {
+			text = strings.TrimLeftFunc(text, unicode.IsSpace)
+		}
+		trimmed := strings.TrimLeftFunc(text, unicode.IsSpace)
+		sb.WriteString(text[:len(text)-len(trimmed)])
+		sb.WriteString(fmt.Sprintf("<%s>%s", formatLRCTimestamp(word.Start), trimmed))
+	}
+	sb.WriteString(fmt.Sprintf("<%s>", formatLRCTimestamp(seg.Words[len(seg.Words)-1].End)))
+	return sb.String()
+}
+
+// FormatLRCLine renders a single segment as a timestamped LRC line
+func FormatLRCLine(seg whisper.Segment) string {
+	return fmt.Sprintf("[%s]%s\n", formatLRCTimestamp(seg.Start), strings.TrimSpace(seg.Text))
+}
+
 // formatLRCTimestamp converts seconds to LRC timestamp format [mm:ss.xx]
 func formatLRCTimestamp(seconds float64) string {
 	totalMs := int(seconds * 1000)
@@ -51,8 +106,20 @@ func formatLRCTimestamp(seconds float64) string {
 	return fmt.Sprintf("%02d:%02d.%02d", mins, secs, centisecs)
 }
 
+// markLanguage prefixes text with a language code in parentheses
+func markLanguage(lang, text string) string {
+	text = strings.TrimSpace(text)
+	if lang == "" {
+		return text
+	}
+	return fmt.Sprintf("(%s) %s", lang, text)
+}
+
 // SRTFormatter formats transcription as SRT subtitles
-type SRTFormatter struct{}
+type SRTFormatter struct {
+	// MarkLanguages prefixes each cue with its language code, e.g. "(ja) "
+	MarkLanguages bool
+}
 
 // NewSRTFormatter creates a new SRT formatter
 func NewSRTFormatter() *SRTFormatter {
@@ -74,6 +141,9 @@ func (f *SRTFormatter) Format(result *whisper.TranscriptionResult) string {
 
 		// Text
 		text := strings.TrimSpace(seg.Text)
+		if f.MarkLanguages {
+			text = markLanguage(result.LanguageOf(seg), text)
+		}
 		sb.WriteString(text + "\n")
 
 		// Blank line separator
@@ -93,3 +163,163 @@ func formatSRTTimestamp(seconds float64) string {
 
 	return fmt.Sprintf("%02d:%02d:%02d,%03d", hours, mins, secs, ms)
 }
+
+// VTTFormatter formats transcription as WebVTT subtitles
+type VTTFormatter struct {
+	// Cue settings applied to every cue; empty values are omitted
+	Line     string
+	Position string
+	Align    string
+	// Note is extra text for the NOTE header block
+	Note string
+	// MarkLanguages wraps each cue's text in a <lang> span
+	MarkLanguages bool
+}
+
+// NewVTTFormatter creates a new WebVTT formatter
+func NewVTTFormatter() *VTTFormatter {
+	return &VTTFormatter{}
+}
+
+// Format converts transcription result to WebVTT format
+func (f *VTTFormatter) Format(result *whisper.TranscriptionResult) string {
+	var sb strings.Builder
+
+	sb.WriteString("WEBVTT\n\n")
+
+	// Metadata header
+	sb.WriteString("NOTE\nGenerated by whisper-lrc\n")
+	if result.Language != "" {
+		sb.WriteString(fmt.Sprintf("Language: %s\n", result.Language))
+	}
+	if f.Note != "" {
+		// Cue text may not contain "-->" and the block ends at the first blank line
+		note := strings.ReplaceAll(f.Note, "-->", "->")
+		for _, line := range strings.Split(note, "\n") {
+			if strings.TrimSpace(line) != "" {
+				sb.WriteString(line + "\n")
+			}
+		}
+	}
+	sb.WriteString("\n")
+
+	settings := f.cueSettings()
+	for _, seg := range result.Segments {
+		if lang := result.LanguageOf(seg); f.MarkLanguages && lang != "" {
+			seg.Text = fmt.Sprintf("<lang %s>%s</lang>", lang, strings.TrimSpace(seg.Text))
+		}
+		sb.WriteString(formatVTTCue(seg, settings))
+	}
+
+	return sb.String()
+}
+
+// FormatVTTCue renders a single segment as a WebVTT cue without cue settings
+func FormatVTTCue(seg whisper.Segment) string {
+	return formatVTTCue(seg, "")
+}
+
+func formatVTTCue(seg whisper.Segment, settings string) string {
+	startTS := formatVTTTimestamp(seg.Start)
+	endTS := formatVTTTimestamp(seg.End)
+	text := strings.TrimSpace(seg.Text)
+	return fmt.Sprintf("%s --> %s%s\n%s\n\n", startTS, endTS, settings, text)
+}
+
+// cueSettings renders the cue settings suffix for timing lines
+func (f *VTTFormatter) cueSettings() string {
+	var settings []string
+	if f.Line != "" {
+		settings = append(settings, "line:"+f.Line)
+	}
+	if f.Position != "" {
+		settings = append(settings, "position:"+f.Position)
+	}
+	if f.Align != "" {
+		settings = append(settings, "align:"+f.Align)
+	}
+	if len(settings) == 0 {
+		return ""
+	}
+	return " " + strings.Join(settings, " ")
+}
+
+// ValidateVTTSettings checks cue setting values against the WebVTT syntax
+func ValidateVTTSettings(line, position, align string) error {
+	if line != "" && !isVTTLine(line) {
+		return fmt.Errorf("invalid VTT line setting: %s. Use a line number (e.g. -1) or percentage (e.g. 90%%)", line)
+	}
+	if position != "" && !isVTTPercentage(position) {
+		return fmt.Errorf("invalid VTT position setting: %s. Use a percentage (e.g. 50%%)", position)
+	}
+	switch align {
+	case "", "start", "center", "end", "left", "right":
+	default:
+		return fmt.Errorf("invalid VTT align setting: %s. Use start, center, end, left or right", align)
+	}
+	return nil
+}
+
+func isVTTLine(v string) bool {
+	if isVTTPercentage(v) {
+		return true
+	}
+	_, err := strconv.Atoi(v)
+	return err == nil
+}
+
+func isVTTPercentage(v string) bool {
+	if !strings.HasSuffix(v, "%") {
+		return false
+	}
+	n, err := strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64)
+	return err == nil && n >= 0 && n <= 100
+}
+
+// formatVTTTimestamp converts seconds to WebVTT timestamp format 00:00:00.000
+func formatVTTTimestamp(seconds float64) string {
+	return strings.Replace(formatSRTTimestamp(seconds), ",", ".", 1)
+}
+
+// JSONLSegment is one line of JSON Lines output
+type JSONLSegment struct {
+	File     string  `json:"file,omitempty"`
+	Start    float64 `json:"start"`
+	End      float64 `json:"end"`
+	Text     string  `json:"text"`
+	Language string  `json:"language,omitempty"`
+}
+
+// JSONLFormatter formats transcription as JSON Lines, one segment per line
+type JSONLFormatter struct{}
+
+// NewJSONLFormatter creates a new JSON Lines formatter
+func NewJSONLFormatter() *JSONLFormatter {
+	return &JSONLFormatter{}
+}
+
+// Format converts transcription result to JSON Lines format
+func (f *JSONLFormatter) Format(result *whisper.TranscriptionResult) string {
+	var sb strings.Builder
+	for _, seg := range result.Segments {
+		sb.WriteString(FormatJSONLSegment("", seg))
+	}
+	return sb.String()
+}
+
+// FormatJSONLSegment renders a single segment as a JSON line, tagged with the
+// input file when file is not empty
+func FormatJSONLSegment(file string, seg whisper.Segment) string {
+	data, err := json.Marshal(JSONLSegment{
+		File:     file,
+		Start:    seg.Start,
+		End:      seg.End,
+		Text:     strings.TrimSpace(seg.Text),
+		Language: whisper.LanguageCode(seg.Language),
+	})
+	if err != nil {
+		// Marshalling plain strings and floats cannot fail except for NaN/Inf times
+		return ""
+	}
+	return string(data) + "\n"
+}
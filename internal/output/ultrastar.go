@@ -0,0 +1,110 @@
+package output
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/BBleae/whisper-lrc/internal/melody"
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// ultraStarBPM is the #BPM header value. UltraStar counts quarter beats, so
+// one beat lasts 60/(4*BPM) seconds: 50ms at 300 BPM.
+const ultraStarBPM = 300
+
+// ultraStarBeat is the length of one UltraStar beat in seconds
+const ultraStarBeat = 60.0 / (4 * ultraStarBPM)
+
+// UltraStarFormatter formats transcription as an UltraStar Deluxe song file
+// (.txt). Each word with timing becomes a note; segments without word timing
+// become one note per line. Note pitches come from Pitch when set, otherwise
+// notes are written as freestyle notes, which are shown but not scored.
+type UltraStarFormatter struct {
+	Title  string
+	Artist string
+	// Audio is the file name of the song audio for the #MP3 header
+	Audio string
+	// Pitch is the vocal pitch in Hz every PitchHop seconds
+	Pitch    []float64
+	PitchHop float64
+}
+
+// NewUltraStarFormatter creates a new UltraStar formatter
+func NewUltraStarFormatter() *UltraStarFormatter {
+	return &UltraStarFormatter{}
+}
+
+// Format converts transcription result to UltraStar format
+func (f *UltraStarFormatter) Format(result *whisper.TranscriptionResult) string {
+	lines := make([][]whisper.Word, 0, len(result.Segments))
+	for _, seg := range result.Segments {
+		words := seg.Words
+		if len(words) == 0 {
+			words = []whisper.Word{{Start: seg.Start, End: seg.End, Word: seg.Text}}
+		}
+		if strings.TrimSpace(seg.Text) != "" {
+			lines = append(lines, words)
+		}
+	}
+
+	gap := 0.0
+	if len(lines) > 0 {
+		gap = lines[0][0].Start
+	}
+	beat := func(t float64) int {
+		return int(math.Round((t - gap) / ultraStarBeat))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("#TITLE:%s\n", f.Title))
+	sb.WriteString(fmt.Sprintf("#ARTIST:%s\n", f.Artist))
+	if f.Audio != "" {
+		sb.WriteString(fmt.Sprintf("#MP3:%s\n", f.Audio))
+	}
+	if result.Language != "" {
+		if name := whisper.LanguageName(result.Language); name != "" {
+			first, size := utf8.DecodeRuneInString(name)
+			sb.WriteString(fmt.Sprintf("#LANGUAGE:%s\n", string(unicode.ToUpper(first))+name[size:]))
+		}
+	}
+	sb.WriteString(fmt.Sprintf("#BPM:%d\n", ultraStarBPM))
+	sb.WriteString(fmt.Sprintf("#GAP:%d\n", int(math.Round(gap*1000))))
+
+	// Notes may not overlap, so each starts no earlier than the last ended
+	next := 0
+	for i, words := range lines {
+		if i > 0 {
+			sb.WriteString(fmt.Sprintf("- %d\n", next))
+		}
+		for j, word := range words {
+			start := max(beat(word.Start), next)
+			length := max(beat(word.End)-start, 1)
+			next = start + length
+
+			text := strings.Map(func(r rune) rune {
+				if r == '\n' || r == '\r' {
+					return ' '
+				}
+				return r
+			}, word.Word)
+			if j == 0 {
+				text = strings.TrimLeftFunc(text, unicode.IsSpace)
+			}
+
+			kind, pitch := "F", 0
+			if f.PitchHop > 0 {
+				if note, ok := melody.Note(f.Pitch, f.PitchHop, word.Start, word.End); ok {
+					// UltraStar pitch 0 is middle C (MIDI note 60)
+					kind, pitch = ":", note-60
+				}
+			}
+			sb.WriteString(fmt.Sprintf("%s %d %d %d %s\n", kind, start, length, pitch, text))
+		}
+	}
+	sb.WriteString("E\n")
+
+	return sb.String()
+}
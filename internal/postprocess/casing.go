@@ -0,0 +1,110 @@
+package postprocess
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// Casing styles accepted by NewCasing
+const (
+	CaseKeep     = "keep"
+	CaseSentence = "sentence"
+	CaseLower    = "lower"
+)
+
+// Casing changes the letter case of segment text. Lyrics are often written
+// in lowercase or with only the first letter of a line capitalized, while
+// Whisper writes subtitle-style sentences.
+type Casing struct {
+	style string
+}
+
+// NewCasing creates a casing stage for keep, sentence or lower
+func NewCasing(style string) (*Casing, error) {
+	switch style {
+	case CaseKeep, CaseSentence, CaseLower:
+		return &Casing{style: style}, nil
+	default:
+		return nil, fmt.Errorf("unknown casing: %s (use keep, sentence or lower)", style)
+	}
+}
+
+// Process implements Processor
+func (c *Casing) Process(result *whisper.TranscriptionResult) {
+	switch c.style {
+	case CaseSentence:
+		english := whisper.LanguageCode(result.Language) == "en"
+		mapText(result, func(text string) string {
+			return sentenceCase(text, english)
+		})
+	case CaseLower:
+		mapText(result, strings.ToLower)
+	}
+}
+
+// sentenceCase capitalizes the first letter of a line and lowercases the
+// rest, keeping the English pronoun "I" and its contractions
+func sentenceCase(text string, english bool) string {
+	words := strings.SplitAfter(strings.ToLower(text), " ")
+	first := true
+	for i, word := range words {
+		if english && isPronounI(word) {
+			word = "I" + word[1:]
+		}
+		if first {
+			if idx := strings.IndexFunc(word, unicode.IsLetter); idx >= 0 {
+				r, size := utf8.DecodeRuneInString(word[idx:])
+				word = word[:idx] + string(unicode.ToUpper(r)) + word[idx+size:]
+				first = false
+			}
+		}
+		words[i] = word
+	}
+	return strings.Join(words, "")
+}
+
+// isPronounI reports whether a lowercased word is "i", "i'm", "i'll" and
+// the like, possibly followed by spaces and punctuation
+func isPronounI(word string) bool {
+	trimmed := strings.TrimRightFunc(word, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	})
+	if trimmed == "i" {
+		return true
+	}
+	for _, suffix := range []string{"'m", "'ll", "'d", "'ve", "’m", "’ll", "’d", "’ve"} {
+		if trimmed == "i"+suffix {
+			return true
+		}
+	}
+	return false
+}
+
+// lineEndPunctuation is dropped from the end of lines by
+// TrailingPunctuation; question and exclamation marks carry meaning and stay
+const lineEndPunctuation = ".,;:…。，、；："
+
+// TrailingPunctuation removes periods, commas and similar marks from the end
+// of every segment, as lyric sheets write lines without them
+type TrailingPunctuation struct{}
+
+// NewTrailingPunctuation creates the trailing punctuation stage
+func NewTrailingPunctuation() *TrailingPunctuation {
+	return &TrailingPunctuation{}
+}
+
+// Process implements Processor. An ellipsis written as "..." is removed like
+// any other trailing period.
+func (t *TrailingPunctuation) Process(result *whisper.TranscriptionResult) {
+	mapText(result, func(text string) string {
+		trimmed := strings.TrimRight(strings.TrimRightFunc(text, unicode.IsSpace), lineEndPunctuation)
+		if strings.TrimSpace(trimmed) == "" {
+			return text
+		}
+		return trimmed
+	})
+}
@@ -0,0 +1,115 @@
+package postprocess
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// Chinese script conversion directions
+const (
+	SimplifiedToTraditional = "s2t"
+	TraditionalToSimplified = "t2s"
+)
+
+// dictionary holds the phrase and character mappings for one direction
+type dictionary struct {
+	phrases   map[string]string
+	chars     map[rune]rune
+	maxPhrase int
+}
+
+var (
+	dictOnce sync.Once
+	s2tDict  *dictionary
+	t2sDict  *dictionary
+)
+
+// loadDictionaries builds both conversion dictionaries from the tables
+func loadDictionaries() {
+	s2tDict = &dictionary{phrases: map[string]string{}, chars: map[rune]rune{}}
+	t2sDict = &dictionary{phrases: map[string]string{}, chars: map[rune]rune{}}
+
+	for _, pair := range strings.Fields(s2tCharacters) {
+		r := []rune(pair)
+		s2tDict.chars[r[0]] = r[1]
+		t2sDict.chars[r[1]] = r[0]
+	}
+	for _, pair := range strings.Fields(t2sExtraCharacters) {
+		r := []rune(pair)
+		t2sDict.chars[r[0]] = r[1]
+	}
+	for _, pair := range strings.Fields(s2tPhrases) {
+		r := []rune(pair)
+		s2tDict.addPhrase(string(r[:len(r)/2]), string(r[len(r)/2:]))
+	}
+	for _, pair := range strings.Fields(t2sPhrases) {
+		r := []rune(pair)
+		t2sDict.addPhrase(string(r[:len(r)/2]), string(r[len(r)/2:]))
+	}
+}
+
+func (d *dictionary) addPhrase(from, to string) {
+	d.phrases[from] = to
+	if n := len([]rune(from)); n > d.maxPhrase {
+		d.maxPhrase = n
+	}
+}
+
+// convert applies longest-match phrase conversion, falling back to
+// character-by-character conversion
+func (d *dictionary) convert(s string) string {
+	runes := []rune(s)
+	var sb strings.Builder
+	for i := 0; i < len(runes); {
+		matched := false
+		for n := min(d.maxPhrase, len(runes)-i); n >= 2; n-- {
+			if to, ok := d.phrases[string(runes[i:i+n])]; ok {
+				sb.WriteString(to)
+				i += n
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+		if to, ok := d.chars[runes[i]]; ok {
+			sb.WriteRune(to)
+		} else {
+			sb.WriteRune(runes[i])
+		}
+		i++
+	}
+	return sb.String()
+}
+
+// ChineseConverter converts Chinese lyrics between simplified and traditional script
+type ChineseConverter struct {
+	dict *dictionary
+}
+
+// NewChineseConverter creates a converter for the given direction (s2t or t2s)
+func NewChineseConverter(variant string) (*ChineseConverter, error) {
+	dictOnce.Do(loadDictionaries)
+	switch strings.ToLower(variant) {
+	case SimplifiedToTraditional:
+		return &ChineseConverter{dict: s2tDict}, nil
+	case TraditionalToSimplified:
+		return &ChineseConverter{dict: t2sDict}, nil
+	default:
+		return nil, fmt.Errorf("invalid Chinese variant: %s. Use 's2t' or 't2s'", variant)
+	}
+}
+
+// Process converts segment text when the transcription is Chinese; other
+// languages (e.g. Japanese kanji) are left untouched
+func (c *ChineseConverter) Process(result *whisper.TranscriptionResult) {
+	switch whisper.LanguageCode(result.Language) {
+	case "zh", "yue":
+		mapText(result, c.dict.convert)
+		result.Text = c.dict.convert(result.Text)
+	}
+}
@@ -0,0 +1,91 @@
+package postprocess
+
+// The tables below cover the characters and phrases most common in song
+// lyrics rather than the full OpenCC dictionaries. Each entry is a simplified
+// form immediately followed by its traditional form.
+
+// s2tCharacters lists one-to-one character conversions; for characters with
+// several traditional forms the most common one is used and the exceptions
+// are listed in s2tPhrases
+const s2tCharacters = `
+爱愛 碍礙 肮骯 袄襖 坝壩 罢罷 摆擺 败敗 颁頒 办辦 绊絆 帮幫 绑綁 宝寶 饱飽 报報
+贝貝 备備 笔筆 毕畢 币幣 闭閉 边邊 编編 变變 标標 别別 宾賓 饼餅 并並 拨撥 补補
+财財 参參 惨慘 蚕蠶 灿燦 仓倉 层層 产產 尝嘗 长長 场場 厂廠 车車 彻徹 尘塵 陈陳
+衬襯 称稱 惩懲 诚誠 迟遲 齿齒 冲衝 虫蟲 宠寵 筹籌 础礎 处處 触觸 传傳 疮瘡 闯闖
+创創 纯純 词詞 辞辭 从從 丛叢 聪聰 错錯 达達 带帶 单單 担擔 胆膽 弹彈 当當 挡擋
+党黨 导導 岛島 祷禱 灯燈 邓鄧 敌敵 递遞 点點 电電 垫墊 钓釣 调調 叠疊 顶頂 订訂
+东東 动動 冻凍 独獨 读讀 赌賭 断斷 锻鍛 队隊 对對 顿頓 夺奪 堕墮 恶惡 儿兒 尔爾
+饵餌 贰貳 发發 罚罰 阀閥 饭飯 访訪 纺紡 飞飛 废廢 费費 纷紛 坟墳 奋奮 愤憤 粪糞
+丰豐 风風 疯瘋 锋鋒 凤鳳 肤膚 妇婦 负負 赋賦 该該 盖蓋 干幹 赶趕 刚剛 钢鋼 纲綱
+岗崗 搁擱 鸽鴿 个個 给給 巩鞏 贡貢 沟溝 构構 购購 顾顧 关關 观觀 馆館 惯慣 贯貫
+广廣 归歸 龟龜 规規 轨軌 贵貴 柜櫃 滚滾 锅鍋 国國 过過 还還 汉漢 号號 贺賀 后後
+护護 画畫 话話 怀懷 坏壞 欢歡 环環 换換 唤喚 挥揮 辉輝 汇匯 会會 绘繪 毁毀 浑渾
+伙夥 获獲 货貨 祸禍 击擊 机機 积積 饥飢 鸡雞 极極 际際 济濟 继繼 纪紀 记記 迹跡
+计計 剂劑 夹夾 价價 驾駕 坚堅 艰艱 监監 减減 检檢 简簡 见見 荐薦 舰艦 践踐 贱賤
+键鍵 渐漸 将將 奖獎 讲講 酱醬 胶膠 骄驕 娇嬌 搅攪 脚腳 饺餃 较較 轿轎 阶階 节節
+杰傑 洁潔 结結 诫誡 届屆 紧緊 仅僅 尽盡 进進 劲勁 惊驚 经經 颈頸 静靜 镜鏡 竞競
+纠糾 旧舊 举舉 剧劇 惧懼 据據 决決 觉覺 绝絕 军軍 开開 凯凱 颗顆 课課 垦墾 恳懇
+库庫 裤褲 夸誇 块塊 宽寬 矿礦 亏虧 扩擴 阔闊 腊臘 蜡蠟 来來 赖賴 蓝藍 栏欄 拦攔
+篮籃 兰蘭 烂爛 滥濫 劳勞 乐樂 垒壘 泪淚 类類 离離 里裡 礼禮 丽麗 历歷 厉厲 励勵
+联聯 连連 怜憐 帘簾 莲蓮 恋戀 脸臉 练練 炼煉 粮糧 两兩 辆輛 凉涼 谅諒 疗療 辽遼
+猎獵 临臨 邻鄰 灵靈 龄齡 岭嶺 领領 刘劉 龙龍 楼樓 娄婁 搂摟 卢盧 芦蘆 炉爐 陆陸
+录錄 虏虜 驴驢 铝鋁 屡屢 缕縷 虑慮 滤濾 绿綠 乱亂 轮輪 论論 罗羅 逻邏 锣鑼 骡騾
+络絡 妈媽 马馬 码碼 骂罵 吗嗎 买買 卖賣 麦麥 迈邁 脉脈 满滿 猫貓 贸貿 么麼 没沒
+门門 们們 梦夢 弥彌 觅覓 绵綿 庙廟 灭滅 闽閩 鸣鳴 铭銘 谬謬 亩畝 难難 闹鬧 脑腦
+恼惱 腻膩 鸟鳥 拧擰 宁寧 农農 浓濃 脓膿 诺諾 欧歐 盘盤 赔賠 喷噴 鹏鵬 骗騙 飘飄
+贫貧 频頻 凭憑 苹蘋 评評 泼潑 颇頗 扑撲 铺鋪 谱譜 齐齊 骑騎 岂豈 启啟 气氣 弃棄
+迁遷 签簽 谦謙 钱錢 钳鉗 浅淺 谴譴 枪槍 墙牆 蔷薔 强強 抢搶 乔喬 桥橋 侨僑 窍竅
+亲親 轻輕 氢氫 倾傾 顷頃 请請 庆慶 穷窮 琼瓊 区區 驱驅 躯軀 趋趨 权權 劝勸 却卻
+确確 让讓 扰擾 热熱 认認 荣榮 软軟 锐銳 润潤 洒灑 伞傘 丧喪 扫掃 涩澀 杀殺 纱紗
+晒曬 闪閃 陕陝 伤傷 赏賞 烧燒 绍紹 设設 摄攝 审審 婶嬸 肾腎 渗滲 声聲 绳繩 胜勝
+圣聖 师師 诗詩 狮獅 湿濕 时時 识識 实實 势勢 适適 释釋 饰飾 视視 试試 寿壽 兽獸
+书書 输輸 术術 树樹 属屬 数數 帅帥 双雙 谁誰 税稅 顺順 说說 烁爍 丝絲 饲飼 颂頌
+诉訴 肃肅 虽雖 随隨 岁歲 孙孫 损損 笋筍 缩縮 琐瑣 锁鎖 态態 摊攤 滩灘 瘫癱 坛壇
+谈談 叹嘆 汤湯 烫燙 涛濤 讨討 腾騰 誊謄 题題 体體 厅廳 听聽 铜銅 统統 头頭 图圖
+涂塗 团團 颓頹 脱脫 袜襪 弯彎 湾灣 万萬 网網 韦韋 违違 围圍 为為 伪偽 纬緯 卫衛
+稳穩 问問 纹紋 闻聞 窝窩 卧臥 乌烏 无無 雾霧 务務 误誤 牺犧 习習 戏戲 细細 虾蝦
+吓嚇 峡峽 狭狹 厦廈 鲜鮮 闲閒 显顯 险險 现現 献獻 县縣 线線 宪憲 乡鄉 详詳 响響
+项項 萧蕭 销銷 晓曉 啸嘯 协協 胁脅 写寫 谢謝 亵褻 衅釁 兴興 须須 许許 叙敘 绪緒
+续續 轩軒 选選 悬懸 学學 寻尋 训訓 讯訊 逊遜 压壓 鸦鴉 哑啞 亚亞 讶訝 烟煙 严嚴
+盐鹽 颜顏 阎閻 艳豔 厌厭 砚硯 验驗 扬揚 杨楊 阳陽 养養 样樣 谣謠 摇搖 遥遙 药藥
+爷爺 页頁 业業 叶葉 医醫 仪儀 遗遺 亿億 忆憶 义義 艺藝 议議 异異 译譯 阴陰 银銀
+饮飲 隐隱 应應 婴嬰 鹰鷹 樱櫻 营營 蝇蠅 赢贏 拥擁 涌湧 优優 忧憂 犹猶 邮郵 鱼魚
+渔漁 与與 语語 狱獄 预預 誉譽 渊淵 园園 员員 圆圓 缘緣 远遠 愿願 约約 跃躍 钥鑰
+阅閱 悦悅 云雲 运運 韵韻 杂雜 灾災 载載 暂暫 赞讚 脏髒 凿鑿 枣棗 灶竈 则則 责責
+择擇 泽澤 贼賊 赠贈 闸閘 诈詐 斋齋 债債 毡氈 盏盞 斩斬 崭嶄 战戰 张張 涨漲 帐帳
+账賬 胀脹 赵趙 这這 针針 侦偵 诊診 镇鎮 阵陣 睁睜 争爭 征徵 挣掙 郑鄭 证證 织織
+职職 执執 纸紙 挚摯 掷擲 帜幟 质質 钟鐘 终終 种種 肿腫 众眾 昼晝 皱皺 骤驟 猪豬
+诸諸 烛燭 嘱囑 贮貯 驻駐 筑築 铸鑄 专專 砖磚 转轉 赚賺 庄莊 装裝 妆妝 壮壯 状狀
+锥錐 坠墜 准準 浊濁 资資 总總 纵縱 邹鄒 组組 钻鑽 着著 于於 几幾 余餘 间間 华華
+牵牽 够夠 缠纏 绕繞 痴癡 哟喲 呜嗚 哗嘩 啰囉 侣侶 红紅 绸綢 缎緞 链鏈 荡蕩 颤顫
+剑劍 侠俠 铁鐵 锈鏽 铃鈴 铛鐺 宫宮 鹤鶴 鸳鴛 鸯鴦 鸿鴻 鹊鵲 苇葦 丢丟 笼籠 晕暈
+挂掛 惭慚 谎謊 呗唄 虚虛 烦煩
+`
+
+// s2tPhrases lists phrases whose traditional form differs from the
+// character-by-character conversion
+const s2tPhrases = `
+	头发頭髮 白发白髮 长发長髮 秀发秀髮 理发理髮 发型髮型 以后以後 然后然後 后来後來 之后之後
+	最后最後 前后前後 后悔後悔 皇后皇后 王后王后 后宫後宮 干净乾淨 干杯乾杯 干燥乾燥 干枯乾枯
+	饼干餅乾 若干若干 干涉干涉 干扰干擾 一只一隻 两只兩隻 公里公里 千里千里 万里萬里 里程里程
+	邻里鄰里 故里故里 面条麵條 奋斗奮鬥 战斗戰鬥 斗争鬥爭 重复重複 复杂複雜 反复反覆 回复回覆
+	恢复恢復 复活復活 关系關係 联系聯繫 钟情鍾情 钟爱鍾愛 茶几茶几 轻松輕鬆 放松放鬆 日历日曆
+	征服征服 出征出征 远征遠征 长征長征 批准批准 准许准許 不准不准 冲洗沖洗 胡须鬍鬚 收获收穫
+	心脏心臟 合并合併 手表手錶 范围範圍 模范模範 规范規範 丑陋醜陋 旅游旅遊 游戏遊戲 周末週末
+	舍不得捨不得 不舍不捨 刮风颳風
+`
+
+// t2sExtraCharacters lists traditional characters that are not the default
+// conversion target of any simplified character
+const t2sExtraCharacters = `
+	裏里 髮发 乾干 隻只 麵面 臺台 颱台 檯台 複复 復复
+	係系 繫系 鬚须 穫获 曆历 儘尽 瞭了 鍾钟 鬆松 遊游
+	範范 鬥斗 艷艳 贊赞 臟脏 捨舍 汙污 捲卷 穀谷 颳刮
+	醜丑 沖冲 歎叹 週周 樸朴 佈布 併并 閑闲 錶表
+`
+
+// t2sPhrases lists phrases that must keep characters the character table
+// would otherwise convert
+const t2sPhrases = `
+	著名著名 顯著显著 著作著作 著稱著称 名著名著 土著土著 乾隆乾隆 乾坤乾坤
+`
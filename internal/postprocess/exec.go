@@ -0,0 +1,68 @@
+package postprocess
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/BBleae/whisper-lrc/internal/hook"
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// Exec runs an external command as a post-processor. The command gets the
+// transcription as JSON (the verbose_json shape of the API) on stdin and
+// prints the changed transcription on stdout; printing nothing leaves it
+// unchanged. Only the text, language and segments are taken from its output;
+// the rest, such as request timing and the raw API response, is kept. The
+// input being processed is in WHISPER_LRC_INPUT. A failure leaves the result
+// unchanged and is kept in Err until the next file.
+type Exec struct {
+	// Command is run by the shell (sh -c, or cmd /C on Windows)
+	Command string
+	// Input names the current file for the command
+	Input string
+	// Err is the failure of the last run, if any
+	Err error
+}
+
+// NewExec creates a post-processor that runs command
+func NewExec(command string) *Exec {
+	return &Exec{Command: command}
+}
+
+// Process pipes the result through the command
+func (e *Exec) Process(result *whisper.TranscriptionResult) {
+	e.Err = e.run(result)
+}
+
+func (e *Exec) run(result *whisper.TranscriptionResult) error {
+	in, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	cmd := hook.Command(e.Command)
+	cmd.Env = append(os.Environ(), "WHISPER_LRC_INPUT="+e.Input)
+	cmd.Stdin = bytes.NewReader(in)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("post-processor %q failed: %w: %s", e.Command, err, msg)
+		}
+		return fmt.Errorf("post-processor %q failed: %w", e.Command, err)
+	}
+
+	if len(bytes.TrimSpace(stdout.Bytes())) == 0 {
+		return nil
+	}
+	var changed whisper.TranscriptionResult
+	if err := json.Unmarshal(stdout.Bytes(), &changed); err != nil {
+		return fmt.Errorf("post-processor %q printed invalid JSON: %w", e.Command, err)
+	}
+	result.Text, result.Language, result.Segments = changed.Text, changed.Language, changed.Segments
+	return nil
+}
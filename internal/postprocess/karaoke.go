@@ -0,0 +1,138 @@
+package postprocess
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// smallKana are kana that merge with the preceding character into one mora
+const smallKana = "ゃゅょぁぃぅぇぉゎャュョァィゥェォヮ"
+
+// KaraokeEstimator estimates word timing for segments that only have segment
+// timestamps. Each segment's time is distributed over its words in
+// proportion to their estimated syllable counts; CJK characters are timed
+// one by one.
+type KaraokeEstimator struct{}
+
+// NewKaraokeEstimator creates a karaoke timing estimator
+func NewKaraokeEstimator() *KaraokeEstimator {
+	return &KaraokeEstimator{}
+}
+
+// Process fills in Words for segments that have none
+func (k *KaraokeEstimator) Process(result *whisper.TranscriptionResult) {
+	for i := range result.Segments {
+		seg := &result.Segments[i]
+		if len(seg.Words) > 0 || seg.End <= seg.Start {
+			continue
+		}
+		seg.Words = estimateWords(seg.Start, seg.End, seg.Text)
+	}
+}
+
+// estimateWords splits text into words and spreads start..end across them
+func estimateWords(start, end float64, text string) []whisper.Word {
+	tokens := splitWords(strings.TrimSpace(text))
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	weights := make([]int, len(tokens))
+	total := 0
+	for i, token := range tokens {
+		weights[i] = syllables(token)
+		total += weights[i]
+	}
+
+	words := make([]whisper.Word, len(tokens))
+	pos := start
+	for i, token := range tokens {
+		share := (end - start) * float64(weights[i]) / float64(total)
+		words[i] = whisper.Word{Start: pos, End: pos + share, Word: token}
+		pos += share
+	}
+	words[len(words)-1].End = end
+	return words
+}
+
+// splitWords splits text at whitespace and around CJK characters. Each word
+// keeps its preceding whitespace and surrounding punctuation.
+func splitWords(text string) []string {
+	var words []string
+	var current strings.Builder
+	space := false  // whitespace seen since the last character
+	letter := false // current word has a letter, not just punctuation
+	flush := func() {
+		if letter {
+			words = append(words, current.String())
+			current.Reset()
+			letter = false
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.IsSpace(r):
+			space = true
+			continue
+		case strings.ContainsRune(smallKana, r) && !space:
+			// Part of the previous mora
+		case isCJK(r):
+			flush()
+		case space:
+			flush()
+		}
+		if space {
+			current.WriteRune(' ')
+			space = false
+		}
+		current.WriteRune(r)
+		letter = letter || unicode.IsLetter(r) || unicode.IsNumber(r)
+	}
+	if current.Len() > 0 {
+		// Trailing punctuation belongs to the last word
+		if !letter && len(words) > 0 {
+			words[len(words)-1] += current.String()
+		} else {
+			words = append(words, current.String())
+		}
+	}
+	return words
+}
+
+// syllables estimates the number of sung syllables in a word: one per CJK
+// character or Hangul block, otherwise one per group of vowels
+func syllables(word string) int {
+	count := 0
+	vowel := false
+	letters := 0
+	for _, r := range strings.ToLower(word) {
+		switch {
+		case strings.ContainsRune(smallKana, r):
+		case isCJK(r) || unicode.Is(unicode.Hangul, r):
+			count++
+		case strings.ContainsRune("aeiouyàáâãäåæèéêëìíîïòóôõöøùúûüýÿ", r):
+			if !vowel {
+				count++
+			}
+			vowel = true
+			letters++
+			continue
+		case unicode.IsLetter(r):
+			letters++
+		}
+		vowel = false
+	}
+	if count == 0 {
+		// Scripts without Latin vowels: assume one syllable per two letters
+		count = (letters + 1) / 2
+	}
+	return max(count, 1)
+}
+
+// isCJK reports whether r is written without spaces between words
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana)
+}
@@ -0,0 +1,35 @@
+package postprocess
+
+import (
+	"strings"
+
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// LanguageFilter drops segments whose language is not in a list. Segments
+// without their own language use the language of the whole transcription.
+type LanguageFilter struct {
+	keep map[string]bool
+}
+
+// NewLanguageFilter creates a filter keeping the given language codes or names
+func NewLanguageFilter(languages []string) *LanguageFilter {
+	keep := make(map[string]bool)
+	for _, lang := range languages {
+		if lang = strings.TrimSpace(lang); lang != "" {
+			keep[whisper.LanguageCode(lang)] = true
+		}
+	}
+	return &LanguageFilter{keep: keep}
+}
+
+// Process implements Processor
+func (f *LanguageFilter) Process(result *whisper.TranscriptionResult) {
+	kept := result.Segments[:0]
+	for _, seg := range result.Segments {
+		if f.keep[result.LanguageOf(seg)] {
+			kept = append(kept, seg)
+		}
+	}
+	result.Segments = kept
+}
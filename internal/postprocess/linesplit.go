@@ -0,0 +1,105 @@
+package postprocess
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// breakAfter are marks after which a line is preferably broken
+const breakAfter = ",.;:!?、。，；！？"
+
+// LineSplitter splits segments longer than MaxChars characters into several
+// lines at word boundaries, preferring a break after punctuation. Each line
+// takes the times of its words when the backend returned word timing;
+// otherwise the segment's time is shared in proportion to line length.
+type LineSplitter struct {
+	MaxChars int
+}
+
+// Process implements Processor
+func (l *LineSplitter) Process(result *whisper.TranscriptionResult) {
+	var segments []whisper.Segment
+	for _, seg := range result.Segments {
+		segments = append(segments, l.split(seg)...)
+	}
+	result.Segments = segments
+}
+
+// split returns the lines of one segment
+func (l *LineSplitter) split(seg whisper.Segment) []whisper.Segment {
+	if l.MaxChars <= 0 || textLength(seg.Text) <= l.MaxChars {
+		return []whisper.Segment{seg}
+	}
+
+	// Words are only used while they still spell the text; stages such as
+	// censoring change the text alone
+	tokens := make([]string, len(seg.Words))
+	for i, w := range seg.Words {
+		tokens[i] = w.Word
+	}
+	if strings.TrimSpace(strings.Join(tokens, "")) != strings.TrimSpace(seg.Text) {
+		seg.Words = nil
+		tokens = splitWords(strings.TrimSpace(seg.Text))
+	}
+	groups := l.group(tokens)
+	if len(groups) < 2 {
+		return []whisper.Segment{seg}
+	}
+
+	lines := make([]whisper.Segment, len(groups))
+	total := 0
+	for _, g := range groups {
+		total += textLength(strings.Join(tokens[g[0]:g[1]], ""))
+	}
+	pos := seg.Start
+	for i, g := range groups {
+		text := strings.Join(tokens[g[0]:g[1]], "")
+		line := whisper.Segment{Text: " " + strings.TrimSpace(text), Language: seg.Language}
+		if len(seg.Words) > 0 {
+			line.Words = seg.Words[g[0]:g[1]]
+			line.Start, line.End = line.Words[0].Start, line.Words[len(line.Words)-1].End
+		} else {
+			share := (seg.End - seg.Start) * float64(textLength(text)) / float64(total)
+			line.Start, line.End = pos, pos+share
+			pos += share
+		}
+		lines[i] = line
+	}
+	// The lines together span exactly the original segment
+	lines[0].Start = seg.Start
+	lines[len(lines)-1].End = seg.End
+	return lines
+}
+
+// group splits tokens into [first, last) ranges of at most MaxChars
+// characters. A line already half full is broken after punctuation; a
+// single token longer than the limit gets a line of its own.
+func (l *LineSplitter) group(tokens []string) [][2]int {
+	var groups [][2]int
+	first, length := 0, 0
+	for i, token := range tokens {
+		n := utf8.RuneCountInString(token)
+		if i == first {
+			n = utf8.RuneCountInString(strings.TrimSpace(token))
+		}
+		if i > first && length+n > l.MaxChars {
+			groups = append(groups, [2]int{first, i})
+			first, n = i, utf8.RuneCountInString(strings.TrimSpace(token))
+			length = 0
+		}
+		length += n
+
+		trimmed := strings.TrimSpace(token)
+		last, _ := utf8.DecodeLastRuneInString(trimmed)
+		if i+1 < len(tokens) && length*2 >= l.MaxChars && strings.ContainsRune(breakAfter, last) {
+			groups = append(groups, [2]int{first, i + 1})
+			first, length = i+1, 0
+		}
+	}
+	if first < len(tokens) {
+		groups = append(groups, [2]int{first, len(tokens)})
+	}
+	return groups
+}
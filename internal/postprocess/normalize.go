@@ -0,0 +1,151 @@
+package postprocess
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// Normalization rules accepted by NewNormalizer
+const (
+	RuleAuto   = "auto"
+	RuleWidth  = "width"
+	RuleQuotes = "quotes"
+)
+
+// autoRules lists the rules applied for each language code when "auto" is requested
+var autoRules = map[string][]string{
+	"ja": {RuleWidth},
+	"zh": {RuleWidth},
+	"ko": {RuleWidth},
+}
+
+// defaultAutoRules are used by "auto" for languages without an entry in autoRules
+var defaultAutoRules = []string{RuleQuotes}
+
+// Normalizer applies language-specific text normalization to segment text
+type Normalizer struct {
+	rules []string
+}
+
+// NewNormalizer creates a normalizer for the given rules
+func NewNormalizer(rules []string) (*Normalizer, error) {
+	n := &Normalizer{}
+	for _, rule := range rules {
+		rule = strings.ToLower(strings.TrimSpace(rule))
+		switch rule {
+		case RuleAuto, RuleWidth, RuleQuotes:
+			n.rules = append(n.rules, rule)
+		default:
+			return nil, fmt.Errorf("unknown normalization rule: %s", rule)
+		}
+	}
+	return n, nil
+}
+
+// Process normalizes the text of every segment
+func (n *Normalizer) Process(result *whisper.TranscriptionResult) {
+	for _, rule := range n.resolveRules(result.Language) {
+		switch rule {
+		case RuleWidth:
+			mapText(result, normalizeWidth)
+		case RuleQuotes:
+			mapText(result, normalizeQuotes)
+		}
+	}
+}
+
+// resolveRules expands "auto" into the rules for the detected language
+func (n *Normalizer) resolveRules(language string) []string {
+	var rules []string
+	for _, rule := range n.rules {
+		if rule != RuleAuto {
+			rules = append(rules, rule)
+			continue
+		}
+		if langRules, ok := autoRules[whisper.LanguageCode(language)]; ok {
+			rules = append(rules, langRules...)
+		} else {
+			rules = append(rules, defaultAutoRules...)
+		}
+	}
+	return rules
+}
+
+// halfwidthKatakana maps halfwidth katakana (U+FF61-U+FF9F) to their fullwidth forms
+var halfwidthKatakana = map[rune]rune{
+	'｡': '。', '｢': '「', '｣': '」', '､': '、', '･': '・', 'ｦ': 'ヲ',
+	'ｧ': 'ァ', 'ｨ': 'ィ', 'ｩ': 'ゥ', 'ｪ': 'ェ', 'ｫ': 'ォ',
+	'ｬ': 'ャ', 'ｭ': 'ュ', 'ｮ': 'ョ', 'ｯ': 'ッ', 'ｰ': 'ー',
+	'ｱ': 'ア', 'ｲ': 'イ', 'ｳ': 'ウ', 'ｴ': 'エ', 'ｵ': 'オ',
+	'ｶ': 'カ', 'ｷ': 'キ', 'ｸ': 'ク', 'ｹ': 'ケ', 'ｺ': 'コ',
+	'ｻ': 'サ', 'ｼ': 'シ', 'ｽ': 'ス', 'ｾ': 'セ', 'ｿ': 'ソ',
+	'ﾀ': 'タ', 'ﾁ': 'チ', 'ﾂ': 'ツ', 'ﾃ': 'テ', 'ﾄ': 'ト',
+	'ﾅ': 'ナ', 'ﾆ': 'ニ', 'ﾇ': 'ヌ', 'ﾈ': 'ネ', 'ﾉ': 'ノ',
+	'ﾊ': 'ハ', 'ﾋ': 'ヒ', 'ﾌ': 'フ', 'ﾍ': 'ヘ', 'ﾎ': 'ホ',
+	'ﾏ': 'マ', 'ﾐ': 'ミ', 'ﾑ': 'ム', 'ﾒ': 'メ', 'ﾓ': 'モ',
+	'ﾔ': 'ヤ', 'ﾕ': 'ユ', 'ﾖ': 'ヨ',
+	'ﾗ': 'ラ', 'ﾘ': 'リ', 'ﾙ': 'ル', 'ﾚ': 'レ', 'ﾛ': 'ロ',
+	'ﾜ': 'ワ', 'ﾝ': 'ン',
+}
+
+// normalizeWidth converts fullwidth alphanumerics and spaces to ASCII and
+// halfwidth katakana to fullwidth, folding halfwidth (semi-)voiced sound marks
+// into the preceding kana. CJK punctuation is left untouched.
+func normalizeWidth(s string) string {
+	var sb strings.Builder
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '　':
+			sb.WriteRune(' ')
+		case (r >= '０' && r <= '９') || (r >= 'Ａ' && r <= 'Ｚ') || (r >= 'ａ' && r <= 'ｚ'):
+			sb.WriteRune(r - 0xFEE0)
+		case halfwidthKatakana[r] != 0:
+			kana := halfwidthKatakana[r]
+			if i+1 < len(runes) {
+				switch runes[i+1] {
+				case 'ﾞ':
+					if voiced, ok := combineVoicedMark(kana, 1); ok {
+						kana = voiced
+						i++
+					}
+				case 'ﾟ':
+					if voiced, ok := combineVoicedMark(kana, 2); ok {
+						kana = voiced
+						i++
+					}
+				}
+			}
+			sb.WriteRune(kana)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// combineVoicedMark applies a dakuten (offset 1) or handakuten (offset 2) to a
+// fullwidth katakana, reporting whether the combination exists
+func combineVoicedMark(kana rune, offset rune) (rune, bool) {
+	switch {
+	case kana == 'ウ' && offset == 1:
+		return 'ヴ', true
+	case offset == 1 && strings.ContainsRune("カキクケコサシスセソタチツテト", kana):
+		return kana + 1, true
+	case kana >= 'ハ' && kana <= 'ホ' && (kana-'ハ')%3 == 0:
+		return kana + offset, true
+	}
+	return kana, false
+}
+
+// normalizeQuotes replaces typographic quotes with their ASCII equivalents
+func normalizeQuotes(s string) string {
+	return strings.NewReplacer(
+		"“", "\"", "”", "\"", "„", "\"", "‟", "\"",
+		"‘", "'", "’", "'", "‚", "'", "‛", "'",
+		"′", "'", "″", "\"",
+	).Replace(s)
+}
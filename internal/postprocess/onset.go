@@ -0,0 +1,77 @@
+package postprocess
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// OnsetSnapper moves segment starts to the nearest vocal onset of the audio,
+// which lines up lyrics with the moment singing starts more closely than
+// Whisper's segment times. Onsets are set per file before processing; with no
+// onsets the stage does nothing.
+type OnsetSnapper struct {
+	// Window is the farthest a start time may move
+	Window time.Duration
+	// Onsets are the sorted onset times of the current audio in seconds
+	Onsets []float64
+}
+
+// NewOnsetSnapper creates an onset snapper with the given search window
+func NewOnsetSnapper(window time.Duration) *OnsetSnapper {
+	return &OnsetSnapper{Window: window}
+}
+
+// Process snaps segment starts, keeping segments in order. A segment moved
+// earlier shortens the previous segment if they would overlap.
+func (s *OnsetSnapper) Process(result *whisper.TranscriptionResult) {
+	if len(s.Onsets) == 0 {
+		return
+	}
+
+	segs := result.Segments
+	for i := range segs {
+		seg := &segs[i]
+		if seg.End <= seg.Start {
+			continue
+		}
+		t, ok := s.nearest(seg.Start)
+		if !ok || t >= seg.End || (i > 0 && t <= segs[i-1].Start) {
+			continue
+		}
+		if len(seg.Words) > 0 && t >= seg.Words[0].End {
+			continue
+		}
+
+		if i > 0 && segs[i-1].End > t {
+			segs[i-1].End = t
+			for j := range segs[i-1].Words {
+				word := &segs[i-1].Words[j]
+				word.Start = min(word.Start, t)
+				word.End = min(word.End, t)
+			}
+		}
+		seg.Start = t
+		if len(seg.Words) > 0 {
+			seg.Words[0].Start = t
+		}
+	}
+}
+
+// nearest returns the onset closest to t within the window
+func (s *OnsetSnapper) nearest(t float64) (float64, bool) {
+	i := sort.SearchFloat64s(s.Onsets, t)
+	best, found := 0.0, false
+	for _, j := range []int{i - 1, i} {
+		if j < 0 || j >= len(s.Onsets) {
+			continue
+		}
+		d := math.Abs(s.Onsets[j] - t)
+		if d <= s.Window.Seconds() && (!found || d < math.Abs(best-t)) {
+			best, found = s.Onsets[j], true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,57 @@
+package postprocess
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// Processor transforms a transcription result in place before formatting
+type Processor interface {
+	Process(result *whisper.TranscriptionResult)
+}
+
+// Pipeline runs a sequence of processors in order
+type Pipeline []Processor
+
+// Process applies every processor in the pipeline to the result
+func (p Pipeline) Process(result *whisper.TranscriptionResult) {
+	for _, proc := range p {
+		proc.Process(result)
+	}
+}
+
+// mapText applies fn to the text of every segment. Words are kept through
+// changes that keep the number of characters, such as of case or script,
+// and dropped otherwise since they no longer spell the text.
+func mapText(result *whisper.TranscriptionResult, fn func(string) string) {
+	for i := range result.Segments {
+		seg := &result.Segments[i]
+		if text := fn(seg.Text); text != seg.Text {
+			seg.Text, seg.Words = text, respell(seg.Words, seg.Text, text)
+		}
+	}
+}
+
+// respell gives words the characters of text at their positions in old, or
+// returns nil if they do not spell old or the texts differ in length
+func respell(words []whisper.Word, old, text string) []whisper.Word {
+	runes := []rune(text)
+	var joined strings.Builder
+	for _, word := range words {
+		joined.WriteString(word.Word)
+	}
+	if len(words) == 0 || joined.String() != old || utf8.RuneCountInString(old) != len(runes) {
+		return nil
+	}
+	respelled := make([]whisper.Word, len(words))
+	pos := 0
+	for i, word := range words {
+		n := utf8.RuneCountInString(word.Word)
+		word.Word = string(runes[pos : pos+n])
+		respelled[i] = word
+		pos += n
+	}
+	return respelled
+}
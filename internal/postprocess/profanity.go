@@ -0,0 +1,238 @@
+package postprocess
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// defaultWordlists holds the built-in profanity lists keyed by language code.
+// A trailing "*" matches any word suffix (e.g. inflections).
+var defaultWordlists = map[string][]string{
+	"en": {
+		"fuck*", "motherfuck*", "shit*", "bullshit*", "bitch*", "cunt*",
+		"asshole*", "bastard*", "dick", "dickhead*", "cock", "cocksucker*",
+		"pussy", "whore*", "slut*", "nigga*", "nigger*", "faggot*",
+	},
+	"es": {"puta*", "puto*", "mierda", "coño", "cabrón*", "cabron*", "joder", "pendejo*", "chinga*"},
+	"de": {"scheiße", "scheisse", "fick*", "arschloch*", "hure*", "fotze*", "wichser*"},
+	"fr": {"merde", "putain*", "connard*", "connasse*", "salope*", "encul*", "pute*"},
+	"pt": {"porra", "caralho*", "merda", "puta*", "foder", "fodase", "buceta*"},
+}
+
+// Wordlist holds profanity word lists per language. Words under the empty
+// language key apply to every language. Once loaded, it can be used by
+// several files in progress at once.
+type Wordlist struct {
+	words    map[string][]string
+	matchers map[string]*wordMatcher
+	mu       sync.Mutex // guards matchers
+}
+
+// NewWordlist creates a wordlist seeded with the built-in lists
+func NewWordlist() *Wordlist {
+	w := &Wordlist{
+		words:    make(map[string][]string),
+		matchers: make(map[string]*wordMatcher),
+	}
+	for lang, words := range defaultWordlists {
+		w.words[lang] = append(w.words[lang], words...)
+	}
+	return w
+}
+
+// LoadFile adds words from a file for a language ("" for all languages).
+// The file holds one word per line; blank lines and lines starting with # are ignored.
+func (w *Wordlist) LoadFile(lang, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open wordlist: %w", err)
+	}
+	defer f.Close()
+
+	lang = whisper.LanguageCode(lang)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		w.words[lang] = append(w.words[lang], line)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read wordlist %s: %w", path, err)
+	}
+	w.matchers = make(map[string]*wordMatcher)
+	return nil
+}
+
+// LoadSpec loads a wordlist given as "path" or "lang:path"
+func (w *Wordlist) LoadSpec(spec string) error {
+	lang, path := "", spec
+	if idx := strings.Index(spec, ":"); idx > 0 && !isWindowsDrive(spec) {
+		lang, path = spec[:idx], spec[idx+1:]
+	}
+	return w.LoadFile(lang, path)
+}
+
+// isWindowsDrive reports whether a path starts with a drive letter like C:
+func isWindowsDrive(path string) bool {
+	return len(path) >= 2 && path[1] == ':' && unicode.IsLetter(rune(path[0]))
+}
+
+// wordMatcher matches listed words for one language. Words from scripts that
+// separate words with spaces are matched against whole tokens; others (e.g.
+// CJK) are matched as substrings.
+type wordMatcher struct {
+	tokens     *regexp.Regexp
+	substrings *regexp.Regexp
+}
+
+// matcher returns the matcher covering the language's own list and the
+// language-independent list. The English list stands in for a language that
+// is not known; it does not apply to other languages, where some of its
+// words are everyday ones (German "dick" is "thick").
+func (w *Wordlist) matcher(language string) *wordMatcher {
+	lang := whisper.LanguageCode(language)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if m, ok := w.matchers[lang]; ok {
+		return m
+	}
+
+	var tokens, substrings []string
+	seen := make(map[string]bool)
+	keys := []string{"", lang}
+	if lang == "" {
+		keys = []string{"", "en"}
+	}
+	for _, key := range keys {
+		for _, word := range w.words[key] {
+			if seen[word] {
+				continue
+			}
+			seen[word] = true
+
+			wildcard := strings.HasSuffix(word, "*")
+			pattern := regexp.QuoteMeta(strings.TrimSuffix(word, "*"))
+			first, _ := utf8.DecodeRuneInString(word)
+			if !isSpacedScript(first) {
+				substrings = append(substrings, pattern)
+				continue
+			}
+			if wildcard {
+				pattern += `.*`
+			}
+			tokens = append(tokens, pattern)
+		}
+	}
+
+	m := &wordMatcher{}
+	if len(tokens) > 0 {
+		m.tokens = regexp.MustCompile("(?i)^(?:" + strings.Join(tokens, "|") + ")$")
+	}
+	if len(substrings) > 0 {
+		m.substrings = regexp.MustCompile(strings.Join(substrings, "|"))
+	}
+	w.matchers[lang] = m
+	return m
+}
+
+// isSpacedScript reports whether a rune belongs to a script that separates words with spaces
+func isSpacedScript(r rune) bool {
+	return unicode.In(r, unicode.Latin, unicode.Cyrillic, unicode.Greek)
+}
+
+// replace calls fn for every listed word in s and substitutes its result
+func (m *wordMatcher) replace(s string, fn func(string) string) string {
+	if m.tokens != nil {
+		var sb strings.Builder
+		runes := []rune(s)
+		for i := 0; i < len(runes); {
+			if !isWordRune(runes[i]) {
+				sb.WriteRune(runes[i])
+				i++
+				continue
+			}
+			j := i
+			for j < len(runes) && isWordRune(runes[j]) {
+				j++
+			}
+			token := string(runes[i:j])
+			if m.tokens.MatchString(token) {
+				token = fn(token)
+			}
+			sb.WriteString(token)
+			i = j
+		}
+		s = sb.String()
+	}
+	if m.substrings != nil {
+		s = m.substrings.ReplaceAllStringFunc(s, fn)
+	}
+	return s
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsMark(r)
+}
+
+// Contains reports whether text contains a listed word for the language
+func (w *Wordlist) Contains(language, text string) bool {
+	found := false
+	w.matcher(language).replace(text, func(word string) string {
+		found = true
+		return word
+	})
+	return found
+}
+
+// IsExplicit reports whether any segment of the result contains a listed word
+func (w *Wordlist) IsExplicit(result *whisper.TranscriptionResult) bool {
+	for _, seg := range result.Segments {
+		if w.Contains(result.Language, seg.Text) {
+			return true
+		}
+	}
+	return false
+}
+
+// Censor masks profanity in segment text, keeping the first letter of each match
+type Censor struct {
+	wordlist *Wordlist
+}
+
+// NewCensor creates a censor backed by the given wordlist
+func NewCensor(wordlist *Wordlist) *Censor {
+	return &Censor{wordlist: wordlist}
+}
+
+// Process masks listed words in every segment
+func (c *Censor) Process(result *whisper.TranscriptionResult) {
+	m := c.wordlist.matcher(result.Language)
+	mask := func(s string) string {
+		return m.replace(s, maskWord)
+	}
+	mapText(result, mask)
+	result.Text = mask(result.Text)
+}
+
+// maskWord replaces every character after the first with an asterisk;
+// single-character words are masked entirely
+func maskWord(word string) string {
+	runes := []rune(word)
+	if len(runes) == 1 {
+		return "*"
+	}
+	for i := 1; i < len(runes); i++ {
+		runes[i] = '*'
+	}
+	return string(runes)
+}
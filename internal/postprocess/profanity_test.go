@@ -0,0 +1,40 @@
+package postprocess
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWordlistLanguages(t *testing.T) {
+	w := NewWordlist()
+	tests := []struct {
+		language, text string
+		want           bool
+	}{
+		{"english", "what a dick", true},
+		{"en", "shit happens", true},
+		{"", "shit happens", true}, // unknown language
+		{"german", "ein dick belegtes Brot", false},
+		{"swedish", "slut på sommaren", false},
+		{"german", "so eine Scheiße", true},
+		{"german", "shit happens", false},
+	}
+	for _, tt := range tests {
+		if got := w.Contains(tt.language, tt.text); got != tt.want {
+			t.Errorf("Contains(%q, %q) = %v, want %v", tt.language, tt.text, got, tt.want)
+		}
+	}
+
+	// Lists without a language apply everywhere
+	path := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(path, []byte("shit*\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.LoadSpec(path); err != nil {
+		t.Fatal(err)
+	}
+	if !w.Contains("german", "shit happens") {
+		t.Error("a list without a language does not apply to German")
+	}
+}
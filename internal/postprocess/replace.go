@@ -0,0 +1,110 @@
+package postprocess
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// replaceLanguage matches what looks like the language prefix of a rule, as
+// in "ja:..."
+var replaceLanguage = regexp.MustCompile(`^([a-z]{2,3}):`)
+
+// replaceRule is one pattern and its replacement, for one language or all
+type replaceRule struct {
+	lang        string
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// Replacer rewrites segment text with user rules, such as the correct
+// spelling of a name Whisper keeps mishearing. Rules apply in order.
+// Segments left empty are dropped.
+type Replacer struct {
+	rules []replaceRule
+}
+
+// NewReplacer parses rules written as "pattern=>replacement", optionally
+// prefixed with a language code ("ja:pattern=>replacement"). A prefix that is
+// not a known language code is an error; a rule starting with ":" applies to
+// every language, so that ":foo:bar=>baz" replaces "foo:bar". Patterns are Go
+// regular expressions and replacements may refer to groups as $1.
+func NewReplacer(specs []string) (*Replacer, error) {
+	r := &Replacer{}
+	for _, spec := range specs {
+		rule := replaceRule{}
+		rest, unscoped := strings.CutPrefix(spec, ":")
+		if m := replaceLanguage.FindStringSubmatch(spec); m != nil && !unscoped {
+			if !whisper.IsLanguageCode(m[1]) {
+				return nil, fmt.Errorf("unknown language %q in replacement %q (start it with : if %q is part of the pattern)", m[1], spec, m[0])
+			}
+			rule.lang, rest = m[1], spec[len(m[0]):]
+		}
+		pattern, replacement, ok := strings.Cut(rest, "=>")
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("invalid replacement %q (use pattern=>replacement)", spec)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid replacement pattern %q: %w", pattern, err)
+		}
+		rule.pattern, rule.replacement = re, replacement
+		r.rules = append(r.rules, rule)
+	}
+	return r, nil
+}
+
+// LoadReplacer reads rules from a corrections file, one rule per line in the
+// form NewReplacer takes. Blank lines and lines starting with # are ignored.
+func LoadReplacer(path string) (*Replacer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open corrections: %w", err)
+	}
+	defer f.Close()
+
+	r := &Replacer{}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := NewReplacer([]string{line})
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		r.rules = append(r.rules, rule.rules...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read corrections %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// Process implements Processor. Word timing no longer matches changed text
+// and is dropped for those segments.
+func (r *Replacer) Process(result *whisper.TranscriptionResult) {
+	kept := result.Segments[:0]
+	for _, seg := range result.Segments {
+		lang := result.LanguageOf(seg)
+		text := seg.Text
+		for _, rule := range r.rules {
+			if rule.lang == "" || rule.lang == lang {
+				text = rule.pattern.ReplaceAllString(text, rule.replacement)
+			}
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		if text != seg.Text {
+			seg.Text, seg.Words = text, nil
+		}
+		kept = append(kept, seg)
+	}
+	result.Segments = kept
+}
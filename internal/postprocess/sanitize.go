@@ -0,0 +1,122 @@
+package postprocess
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// Sanitization rules accepted by NewSanitizer
+const (
+	RuleAll         = "all"
+	RuleAnnotations = "annotations"
+	RuleWhitespace  = "whitespace"
+	RulePunctuation = "punctuation"
+)
+
+var (
+	// bracketed matches square-bracketed annotations, which Whisper only
+	// uses for sounds: [Music], [BLANK_AUDIO]
+	bracketed = regexp.MustCompile(`\[[^\[\]]*\]`)
+	// parenthesized matches parentheses; only those describing sounds are
+	// removed, since lyrics put backing vocals in parentheses too
+	parenthesized = regexp.MustCompile(`\([^()]*\)`)
+	soundWords    = regexp.MustCompile(`(?i)music|applause|laugh|cheer|clap|crowd|silence|instrumental|inaudible|noise|sound|humming|whistl|sigh|breath|static|beep|singing|speaking|foreign`)
+	musicNotes    = strings.NewReplacer("♪", "", "♫", "", "♬", "", "♩", "")
+	spaces        = regexp.MustCompile(`\s+`)
+)
+
+// Punctuation left at the edges of a segment by removed annotations or split
+// sentences; sentence-final marks and opening quotes are kept
+const (
+	leadingPunctuation  = "-–—,.;:…·•"
+	trailingPunctuation = "-–—,;:·•"
+)
+
+// Sanitizer removes transcription artifacts from segment text: sound
+// annotations, irregular whitespace and stray punctuation. Segments left
+// empty are dropped.
+type Sanitizer struct {
+	annotations bool
+	whitespace  bool
+	punctuation bool
+}
+
+// NewSanitizer creates a sanitizer for the given rules
+func NewSanitizer(rules []string) (*Sanitizer, error) {
+	s := &Sanitizer{}
+	for _, rule := range rules {
+		switch strings.ToLower(strings.TrimSpace(rule)) {
+		case RuleAll:
+			s.annotations, s.whitespace, s.punctuation = true, true, true
+		case RuleAnnotations:
+			s.annotations = true
+		case RuleWhitespace:
+			s.whitespace = true
+		case RulePunctuation:
+			s.punctuation = true
+		default:
+			return nil, fmt.Errorf("unknown sanitization rule: %s", rule)
+		}
+	}
+	return s, nil
+}
+
+// Process implements Processor. Word timing no longer matches changed text
+// and is dropped for those segments.
+func (s *Sanitizer) Process(result *whisper.TranscriptionResult) {
+	kept := result.Segments[:0]
+	for _, seg := range result.Segments {
+		text := s.clean(seg.Text)
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		if text != seg.Text {
+			seg.Text, seg.Words = text, nil
+		}
+		kept = append(kept, seg)
+	}
+	result.Segments = kept
+	result.Text = s.clean(result.Text)
+}
+
+// clean applies the rules in a fixed order, so removed annotations do not
+// leave doubled spaces or dangling punctuation behind
+func (s *Sanitizer) clean(text string) string {
+	if s.annotations {
+		text = removeAnnotations(text)
+	}
+	if s.whitespace {
+		text = strings.TrimSpace(spaces.ReplaceAllString(text, " "))
+	}
+	if s.punctuation {
+		text = trimPunctuation(text)
+	}
+	return text
+}
+
+// removeAnnotations removes bracketed sound descriptions and music notes
+func removeAnnotations(text string) string {
+	text = bracketed.ReplaceAllString(text, "")
+	text = parenthesized.ReplaceAllStringFunc(text, func(p string) string {
+		if soundWords.MatchString(p) {
+			return ""
+		}
+		return p
+	})
+	return musicNotes.Replace(text)
+}
+
+// trimPunctuation removes stray punctuation from the start and end of text,
+// keeping the space Whisper puts before segment text
+func trimPunctuation(text string) string {
+	lead := text[:len(text)-len(strings.TrimLeft(text, " "))]
+	text = strings.TrimLeft(text, leadingPunctuation+" ")
+	text = strings.TrimRight(text, trailingPunctuation+" ")
+	if text == "" {
+		return ""
+	}
+	return lead + text
+}
@@ -0,0 +1,242 @@
+package postprocess
+
+import (
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// defaultCharsPerSecond estimates speech rate when nothing better is known
+const defaultCharsPerSecond = 15.0
+
+// GapFiller interpolates timestamps for segments that arrive without usable
+// times (zeroed, inverted or going backwards) so they don't pile up at 00:00
+type GapFiller struct{}
+
+// NewGapFiller creates a gap filler
+func NewGapFiller() *GapFiller {
+	return &GapFiller{}
+}
+
+// Process fills in missing segment times from neighbouring segments and the
+// audio duration, distributing each gap in proportion to text length
+func (g *GapFiller) Process(result *whisper.TranscriptionResult) {
+	segs := result.Segments
+	known := make([]bool, len(segs))
+	lastEnd := 0.0
+	missing := false
+	for i, seg := range segs {
+		known[i] = seg.End > seg.Start && seg.Start >= lastEnd-0.5
+		if known[i] {
+			lastEnd = seg.End
+		} else {
+			missing = true
+		}
+	}
+	if !missing {
+		return
+	}
+
+	rate := speechRate(segs, known)
+	for i := 0; i < len(segs); {
+		if known[i] {
+			i++
+			continue
+		}
+
+		// Find the run of segments without times and its anchors
+		j := i
+		for j < len(segs) && !known[j] {
+			j++
+		}
+		start := 0.0
+		if i > 0 {
+			start = segs[i-1].End
+		}
+		chars := 0
+		for k := i; k < j; k++ {
+			chars += textLength(segs[k].Text)
+		}
+		end := start + float64(chars)/rate
+		switch {
+		case j < len(segs):
+			end = segs[j].Start
+		case result.Duration > start:
+			end = min(end, result.Duration)
+		}
+
+		// Spread the run across the available span
+		span := end - start
+		pos := start
+		for k := i; k < j; k++ {
+			share := span / float64(j-i)
+			if chars > 0 {
+				share = span * float64(textLength(segs[k].Text)) / float64(chars)
+			}
+			segs[k].Start = pos
+			segs[k].End = pos + share
+			pos += share
+		}
+		i = j
+	}
+}
+
+// speechRate estimates characters per second from segments with valid times
+func speechRate(segs []whisper.Segment, known []bool) float64 {
+	chars, seconds := 0, 0.0
+	for i, seg := range segs {
+		if known[i] {
+			chars += textLength(seg.Text)
+			seconds += seg.End - seg.Start
+		}
+	}
+	if chars == 0 || seconds <= 0 {
+		return defaultCharsPerSecond
+	}
+	return float64(chars) / seconds
+}
+
+// textLength counts the characters of text, ignoring surrounding whitespace
+func textLength(text string) int {
+	return max(utf8.RuneCountInString(strings.TrimSpace(text)), 1)
+}
+
+// Shift moves every timestamp by Offset; times that would fall before the
+// start of the audio are clamped to zero
+type Shift struct {
+	Offset time.Duration
+}
+
+// Process implements Processor
+func (s *Shift) Process(result *whisper.TranscriptionResult) {
+	offset := s.Offset.Seconds()
+	move := func(t float64) float64 {
+		return max(t+offset, 0)
+	}
+	for i := range result.Segments {
+		seg := &result.Segments[i]
+		seg.Start, seg.End = move(seg.Start), move(seg.End)
+		for j := range seg.Words {
+			seg.Words[j].Start, seg.Words[j].End = move(seg.Words[j].Start), move(seg.Words[j].End)
+		}
+	}
+}
+
+// minCueLength is the shortest a cue is trimmed to when keeping the gap to
+// the next one would leave it no time at all
+const minCueLength = 0.1
+
+// CueTiming enforces subtitle cue duration limits and a minimum gap between cues.
+// Zero values disable the corresponding rule.
+type CueTiming struct {
+	MinDuration time.Duration
+	MaxDuration time.Duration
+	MinGap      time.Duration
+}
+
+// Process adjusts segment end times; start times are never moved. Cues are
+// lengthened to MinDuration only up to the gap before the next cue, and cues
+// ending later than that gap allows are trimmed, though never below
+// minCueLength.
+func (c *CueTiming) Process(result *whisper.TranscriptionResult) {
+	minDur := c.MinDuration.Seconds()
+	maxDur := c.MaxDuration.Seconds()
+	gap := c.MinGap.Seconds()
+
+	segs := result.Segments
+	for i := range segs {
+		seg := &segs[i]
+		if maxDur > 0 && seg.End-seg.Start > maxDur {
+			seg.End = seg.Start + maxDur
+		}
+
+		// Latest end that keeps the gap to the next cue
+		limit := math.Inf(1)
+		if i+1 < len(segs) {
+			limit = segs[i+1].Start - gap
+		}
+
+		if minDur > 0 && seg.End-seg.Start < minDur {
+			seg.End = max(seg.End, min(seg.Start+minDur, limit))
+		}
+		if seg.End > limit {
+			seg.End = min(seg.End, max(limit, seg.Start+minCueLength))
+		}
+	}
+}
+
+// maxCueChars is the length of two subtitle lines, beyond which a cue read
+// too fast is split
+const maxCueChars = 84
+
+// ReadingSpeed rebalances cue timing so no cue exceeds a characters-per-second
+// limit. Cues too fast to read and longer than two subtitle lines are first
+// split at word boundaries, preferably after punctuation, sharing their time
+// as LineSplitter does. Each cue is then extended into the free time before
+// the next cue, and starts earlier by taking slack from the previous cue.
+// Cues that still exceed the limit are counted in Violations.
+type ReadingSpeed struct {
+	MaxCPS     float64
+	MinGap     time.Duration
+	Violations int
+}
+
+// Process adjusts cue start and end times to meet MaxCPS
+func (r *ReadingSpeed) Process(result *whisper.TranscriptionResult) {
+	gap := r.MinGap.Seconds()
+	r.split(result)
+	segs := result.Segments
+	r.Violations = 0
+
+	for i := range segs {
+		seg := &segs[i]
+		needed := float64(textLength(seg.Text)) / r.MaxCPS
+		if seg.End-seg.Start >= needed {
+			continue
+		}
+
+		// Extend into the free time before the next cue
+		latest := seg.Start + needed
+		if i+1 < len(segs) {
+			latest = min(latest, segs[i+1].Start-gap)
+		} else if result.Duration > 0 {
+			latest = min(latest, result.Duration)
+		}
+		seg.End = max(seg.End, latest)
+
+		// Start earlier, shortening the previous cue only as far as its own limit allows
+		if short := needed - (seg.End - seg.Start); short > 0 {
+			earliest := max(seg.Start-short, 0)
+			if i > 0 {
+				prev := &segs[i-1]
+				prevMinEnd := prev.Start + float64(textLength(prev.Text))/r.MaxCPS
+				earliest = max(earliest, prevMinEnd+gap)
+				if prev.End > earliest-gap {
+					prev.End = max(earliest-gap, prevMinEnd)
+				}
+			}
+			seg.Start = min(seg.Start, earliest)
+		}
+
+		if seg.End-seg.Start < needed-0.001 {
+			r.Violations++
+		}
+	}
+}
+
+// split splits the cues that are too fast to read and too long
+func (r *ReadingSpeed) split(result *whisper.TranscriptionResult) {
+	splitter := &LineSplitter{MaxChars: maxCueChars}
+	var segments []whisper.Segment
+	for _, seg := range result.Segments {
+		if float64(textLength(seg.Text))/r.MaxCPS > seg.End-seg.Start {
+			segments = append(segments, splitter.split(seg)...)
+		} else {
+			segments = append(segments, seg)
+		}
+	}
+	result.Segments = segments
+}
@@ -0,0 +1,92 @@
+package postprocess
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+func TestCueTiming(t *testing.T) {
+	type cue struct{ start, end float64 }
+	tests := []struct {
+		name   string
+		timing CueTiming
+		in     []cue
+		want   []cue
+	}{
+		{
+			name:   "overlapping cues",
+			timing: CueTiming{MinGap: 80 * time.Millisecond},
+			in:     []cue{{0, 2.5}, {2, 4}},
+			want:   []cue{{0, 1.92}, {2, 4}},
+		},
+		{
+			name:   "closer than the gap",
+			timing: CueTiming{MinGap: 80 * time.Millisecond},
+			in:     []cue{{0, 1.97}, {2, 4}},
+			want:   []cue{{0, 1.92}, {2, 4}},
+		},
+		{
+			name:   "far enough apart",
+			timing: CueTiming{MinGap: 80 * time.Millisecond},
+			in:     []cue{{0, 1.5}, {2, 4}},
+			want:   []cue{{0, 1.5}, {2, 4}},
+		},
+		{
+			name:   "min duration stops at the gap",
+			timing: CueTiming{MinDuration: time.Second, MinGap: 80 * time.Millisecond},
+			in:     []cue{{0, 0.3}, {0.6, 2}},
+			want:   []cue{{0, 0.52}, {0.6, 2}},
+		},
+		{
+			name:   "min duration with room",
+			timing: CueTiming{MinDuration: time.Second, MinGap: 80 * time.Millisecond},
+			in:     []cue{{0, 0.3}, {3, 4}},
+			want:   []cue{{0, 1}, {3, 4}},
+		},
+		{
+			name:   "max duration",
+			timing: CueTiming{MaxDuration: 7 * time.Second},
+			in:     []cue{{0, 10}, {12, 14}},
+			want:   []cue{{0, 7}, {12, 14}},
+		},
+		{
+			name:   "back to back keeps the floor",
+			timing: CueTiming{MinGap: 80 * time.Millisecond},
+			in:     []cue{{1, 2}, {1.05, 3}},
+			want:   []cue{{1, 1 + minCueLength}, {1.05, 3}},
+		},
+		{
+			name:   "already shorter than the floor",
+			timing: CueTiming{MinGap: 80 * time.Millisecond},
+			in:     []cue{{1, 1.05}, {1.05, 3}},
+			want:   []cue{{1, 1.05}, {1.05, 3}},
+		},
+		{
+			name:   "next cue at the start of the audio",
+			timing: CueTiming{MinGap: 80 * time.Millisecond},
+			in:     []cue{{0, 1}, {0.05, 2}},
+			want:   []cue{{0, minCueLength}, {0.05, 2}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &whisper.TranscriptionResult{}
+			for _, c := range tt.in {
+				result.Segments = append(result.Segments, whisper.Segment{Start: c.start, End: c.end})
+			}
+			tt.timing.Process(result)
+			for i, seg := range result.Segments {
+				want := tt.want[i]
+				if math.Abs(seg.Start-want.start) > 1e-9 || math.Abs(seg.End-want.end) > 1e-9 {
+					t.Errorf("cue %d = %.3f-%.3f, want %.3f-%.3f", i, seg.Start, seg.End, want.start, want.end)
+				}
+				if seg.End <= seg.Start {
+					t.Errorf("cue %d has no duration: %.3f-%.3f", i, seg.Start, seg.End)
+				}
+			}
+		})
+	}
+}
@@ -2,22 +2,32 @@ package progress
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
 	"sync"
 	"time"
 )
 
-// Tracker displays progress for batch processing
+// Tracker displays progress for batch processing. Several files may be in
+// progress at once; the progress line shows the one started first.
 type Tracker struct {
 	total     int
-	current   int
-	fileName  string
-	status    string
+	active    []activeFile
 	errors    []string
 	completed []string
 	mu        sync.Mutex
 	done      chan struct{}
 	started   bool
+	out       io.Writer
+	log       io.Writer
+}
+
+// activeFile is a file in progress
+type activeFile struct {
+	index  int
+	name   string
+	status string
 }
 
 // NewTracker creates a new progress tracker
@@ -27,6 +37,25 @@ func NewTracker(total int) *Tracker {
 		errors:    make([]string, 0),
 		completed: make([]string, 0),
 		done:      make(chan struct{}),
+		out:       os.Stdout,
+	}
+}
+
+// SetOutput sets where progress is written (stdout by default)
+func (t *Tracker) SetOutput(w io.Writer) {
+	t.out = w
+}
+
+// SetLog sets a writer that receives a timestamped plain-text copy of every
+// message, without the progress line
+func (t *Tracker) SetLog(w io.Writer) {
+	t.log = w
+}
+
+// logf writes a line to the log writer, if any; callers hold t.mu
+func (t *Tracker) logf(format string, args ...any) {
+	if t.log != nil {
+		fmt.Fprintf(t.log, "%s "+format+"\n", append([]any{time.Now().Format(time.RFC3339)}, args...)...)
 	}
 }
 
@@ -42,24 +71,40 @@ func (t *Tracker) Stop() {
 		close(t.done)
 		t.started = false
 		// Clear the progress line
-		fmt.Print("\r" + strings.Repeat(" ", 80) + "\r")
+		fmt.Fprint(t.out, "\r"+strings.Repeat(" ", 80)+"\r")
 	}
 }
 
-// SetCurrent updates the current file being processed
+// SetCurrent adds a file to the files in progress; index counts from 1
 func (t *Tracker) SetCurrent(index int, fileName string) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	t.current = index
-	t.fileName = fileName
-	t.status = "Processing..."
+	t.active = append(t.active, activeFile{index: index, name: fileName, status: "Processing..."})
+	t.logf("[%d/%d] %s", index, t.total, fileName)
 }
 
-// SetStatus updates the status message
-func (t *Tracker) SetStatus(status string) {
+// SetStatus updates the status message of a file in progress
+func (t *Tracker) SetStatus(index int, status string) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	t.status = status
+	for i := range t.active {
+		if t.active[i].index == index {
+			t.active[i].status = status
+			t.logf("%s: %s", t.active[i].name, status)
+		}
+	}
+}
+
+// Finish removes a file from the files in progress
+func (t *Tracker) Finish(index int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := range t.active {
+		if t.active[i].index == index {
+			t.active = append(t.active[:i], t.active[i+1:]...)
+			return
+		}
+	}
 }
 
 // Complete marks a file as completed
@@ -78,6 +123,22 @@ func (t *Tracker) Error(input string, err error) {
 	t.printError(input, err)
 }
 
+// Skip records a file that was not processed and why
+func (t *Tracker) Skip(input, reason string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprintf(t.out, "\r%s○ %s: %s\n", strings.Repeat(" ", 80)+"\r", truncate(input, 30), reason)
+	t.logf("skipped %s: %s", input, reason)
+}
+
+// Log prints a message above the progress line
+func (t *Tracker) Log(message string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprintf(t.out, "\r%s%s\n", strings.Repeat(" ", 80)+"\r", message)
+	t.logf("%s", message)
+}
+
 func (t *Tracker) render() {
 	ticker := time.NewTicker(100 * time.Millisecond)
 	defer ticker.Stop()
@@ -91,27 +152,31 @@ func (t *Tracker) render() {
 			return
 		case <-ticker.C:
 			t.mu.Lock()
-			if t.fileName != "" {
+			if len(t.active) > 0 {
 				// Truncate filename if too long
-				name := t.fileName
+				file := t.active[0]
+				name := file.name
 				if len(name) > 30 {
 					name = name[:27] + "..."
 				}
 
 				progress := fmt.Sprintf("\r%s [%d/%d] %s: %s",
 					spinChars[spinIdx],
-					t.current,
+					file.index,
 					t.total,
 					name,
-					t.status,
+					file.status,
 				)
+				if len(t.active) > 1 {
+					progress += fmt.Sprintf(" (+%d more)", len(t.active)-1)
+				}
 				// Pad with spaces and truncate to terminal width
 				if len(progress) < 80 {
 					progress += strings.Repeat(" ", 80-len(progress))
 				} else {
 					progress = progress[:80]
 				}
-				fmt.Print(progress)
+				fmt.Fprint(t.out, progress)
 			}
 			t.mu.Unlock()
 			spinIdx = (spinIdx + 1) % len(spinChars)
@@ -121,12 +186,14 @@ func (t *Tracker) render() {
 
 func (t *Tracker) printCompleted(input, output string) {
 	// Clear progress line and print completion
-	fmt.Printf("\r%s✓ %s -> %s\n", strings.Repeat(" ", 80)+"\r", truncate(input, 30), truncate(output, 30))
+	fmt.Fprintf(t.out, "\r%s✓ %s -> %s\n", strings.Repeat(" ", 80)+"\r", truncate(input, 30), truncate(output, 30))
+	t.logf("completed %s -> %s", input, output)
 }
 
 func (t *Tracker) printError(input string, err error) {
 	// Clear progress line and print error
-	fmt.Printf("\r%s✗ %s: %v\n", strings.Repeat(" ", 80)+"\r", truncate(input, 30), err)
+	fmt.Fprintf(t.out, "\r%s✗ %s: %v\n", strings.Repeat(" ", 80)+"\r", truncate(input, 30), err)
+	t.logf("failed %s: %v", input, err)
 }
 
 func truncate(s string, maxLen int) string {
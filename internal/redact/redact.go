@@ -0,0 +1,65 @@
+package redact
+
+import (
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Placeholder replaces redacted text
+const Placeholder = "[REDACTED]"
+
+// keyPattern matches OpenAI-style keys and bearer tokens, which may appear
+// in error bodies even when they are not the key in use
+var keyPattern = regexp.MustCompile(`(?i)(sk-[a-z0-9_-]{8,}|bearer\s+[^\s"']+)`)
+
+var (
+	mu      sync.RWMutex
+	secrets []string
+)
+
+// Register adds a secret, such as the API key in use, to hide from all
+// redacted text
+func Register(secret string) {
+	if len(secret) < 4 {
+		// Too short to redact without mangling unrelated text
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, s := range secrets {
+		if s == secret {
+			return
+		}
+	}
+	secrets = append(secrets, secret)
+}
+
+// String hides registered secrets and anything that looks like an API key
+// or bearer token
+func String(text string) string {
+	mu.RLock()
+	for _, s := range secrets {
+		text = strings.ReplaceAll(text, s, Placeholder)
+	}
+	mu.RUnlock()
+	return keyPattern.ReplaceAllString(text, Placeholder)
+}
+
+// Writer returns a writer that redacts everything written to w. Each write
+// is redacted on its own, so a secret split across writes is not caught.
+func Writer(w io.Writer) io.Writer {
+	return &writer{w: w}
+}
+
+type writer struct {
+	w io.Writer
+}
+
+func (r *writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,143 @@
+package report
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BBleae/whisper-lrc/internal/redact"
+)
+
+// Report collects diagnostics of a run for an issue report. Registered
+// secrets are redacted when the bundle is written; audio and lyrics are never
+// included.
+type Report struct {
+	mu         sync.Mutex
+	args       []string
+	log        bytes.Buffer
+	errors     []string
+	requestIDs []string
+}
+
+// New creates a report for a run with the given command line arguments
+func New(args []string) *Report {
+	return &Report{args: args}
+}
+
+// Write appends to the run log
+func (r *Report) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.log.Write(p)
+}
+
+// AddError records a failure of the run
+func (r *Report) AddError(message string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errors = append(r.errors, message)
+}
+
+// AddRequestID records the ID of a failed API request
+func (r *Report) AddRequestID(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requestIDs = append(r.requestIDs, id)
+}
+
+// Save writes the report as a zip file
+func (r *Report) Save(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	files := []struct{ name, content string }{
+		{"environment.txt", environment()},
+		{"command.txt", strings.Join(redactArgs(r.args), " ") + "\n"},
+		{"errors.txt", strings.Join(r.errors, "\n") + "\n"},
+		{"requests.txt", strings.Join(r.requestIDs, "\n") + "\n"},
+		{"log.txt", r.log.String()},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return fmt.Errorf("failed to write error report: %w", err)
+		}
+		if _, err := w.Write([]byte(redact.String(f.content))); err != nil {
+			return fmt.Errorf("failed to write error report: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to write error report: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write error report: %w", err)
+	}
+	return nil
+}
+
+// redactArgs hides flag values that hold credentials and URL query strings,
+// which often carry access tokens
+func redactArgs(args []string) []string {
+	result := make([]string, len(args))
+	hideNext := false
+	for i, arg := range args {
+		switch {
+		case hideNext:
+			arg, hideNext = redact.Placeholder, false
+		case arg == "--api-key":
+			hideNext = true
+		case strings.HasPrefix(arg, "--api-key="):
+			arg = "--api-key=" + redact.Placeholder
+		}
+		if u, err := url.Parse(arg); err == nil && u.Scheme != "" && u.Host != "" && u.RawQuery != "" {
+			u.RawQuery = "REDACTED"
+			arg = u.String()
+		}
+		result[i] = arg
+	}
+	return result
+}
+
+// environment describes the program version, platform and external tools
+func environment() string {
+	var sb strings.Builder
+	version := "(unknown)"
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		version = info.Main.Version
+	}
+	fmt.Fprintf(&sb, "whisper-lrc: %s\n", version)
+	fmt.Fprintf(&sb, "go: %s\n", runtime.Version())
+	fmt.Fprintf(&sb, "platform: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	for _, tool := range []struct{ name, flag string }{
+		{"ffmpeg", "-version"},
+		{"ffprobe", "-version"},
+		{"yt-dlp", "--version"},
+	} {
+		fmt.Fprintf(&sb, "%s: %s\n", tool.name, toolVersion(tool.name, tool.flag))
+	}
+	return sb.String()
+}
+
+// toolVersion returns the first line of a tool's version output
+func toolVersion(name, flag string) string {
+	if _, err := exec.LookPath(name); err != nil {
+		return "not found"
+	}
+	output, err := exec.Command(name, flag).Output()
+	if err != nil {
+		return fmt.Sprintf("error: %v", err)
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+	return line
+}
@@ -0,0 +1,218 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/BBleae/whisper-lrc/internal/redact"
+	"github.com/BBleae/whisper-lrc/internal/tlsconfig"
+)
+
+// S3 stores files in an S3 bucket, or an S3-compatible server when
+// AWS_ENDPOINT_URL is set. Credentials come from the standard AWS
+// environment variables.
+type S3 struct {
+	bucket       string
+	prefix       string
+	region       string
+	endpoint     *url.URL
+	pathStyle    bool
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	client       *http.Client
+}
+
+// newS3 configures the bucket and key prefix of an s3://bucket/prefix URL
+func newS3(u *url.URL, tlsConfig *tls.Config) (*S3, error) {
+	s := &S3{
+		bucket:       u.Host,
+		prefix:       strings.Trim(u.Path, "/"),
+		region:       os.Getenv("AWS_REGION"),
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		client:       &http.Client{Transport: tlsconfig.Transport(tlsConfig)},
+	}
+	if s.bucket == "" {
+		return nil, fmt.Errorf("s3:// output needs a bucket, e.g. s3://my-bucket/lyrics")
+	}
+	if s.accessKey == "" || s.secretKey == "" {
+		return nil, fmt.Errorf("s3:// output needs AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	redact.Register(s.secretKey)
+	redact.Register(s.sessionToken)
+	if s.region == "" {
+		s.region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if s.region == "" {
+		s.region = "us-east-1"
+	}
+
+	// S3-compatible servers such as MinIO are addressed with path-style URLs
+	if endpoint := os.Getenv("AWS_ENDPOINT_URL"); endpoint != "" {
+		e, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+		if err != nil || e.Host == "" {
+			return nil, fmt.Errorf("invalid AWS_ENDPOINT_URL %q", endpoint)
+		}
+		s.endpoint, s.pathStyle = e, true
+	} else {
+		s.endpoint = &url.URL{Scheme: "https", Host: fmt.Sprintf("%s.s3.%s.amazonaws.com", s.bucket, s.region)}
+	}
+	return s, nil
+}
+
+// Write uploads the object
+func (s *S3) Write(name string, data []byte) error {
+	status, err := s.do(http.MethodPut, name, data)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("failed to upload %s: status %d", s.Location(name), status)
+	}
+	return nil
+}
+
+// Exists sends a HEAD request for the object
+func (s *S3) Exists(name string) (bool, error) {
+	status, err := s.do(http.MethodHead, name, nil)
+	if err != nil {
+		return false, err
+	}
+	switch status {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("failed to check %s: status %d", s.Location(name), status)
+	}
+}
+
+// Location returns the s3:// URL of the object
+func (s *S3) Location(name string) string {
+	return "s3://" + s.bucket + "/" + s.key(name)
+}
+
+func (s *S3) key(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if s.prefix == "" {
+		return name
+	}
+	return s.prefix + "/" + name
+}
+
+// do sends a signed request and returns the response status
+func (s *S3) do(method, name string, data []byte) (int, error) {
+	u := *s.endpoint
+	path := "/" + awsEscape(s.key(name))
+	if s.pathStyle {
+		path = u.Path + "/" + awsEscape(s.bucket) + path
+	}
+	u.RawPath = path
+	u.Path, _ = url.PathUnescape(path)
+
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return 0, fmt.Errorf("invalid S3 URL: %w", err)
+	}
+	s.sign(req, path, data, time.Now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reach S3: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+// sign adds an AWS Signature Version 4 to the request
+func (s *S3) sign(req *http.Request, path string, payload []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	headers := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	values := []string{req.URL.Host, payloadHash, amzDate}
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+		headers = append(headers, "x-amz-security-token")
+		values = append(values, s.sessionToken)
+	}
+
+	var canonicalHeaders strings.Builder
+	for i, h := range headers {
+		canonicalHeaders.WriteString(h + ":" + values[i] + "\n")
+	}
+	signedHeaders := strings.Join(headers, ";")
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		"", // No query string
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := []byte("AWS4" + s.secretKey)
+	for _, part := range []string{date, s.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsEscape percent-encodes everything but unreserved characters and
+// slashes, as the signature requires
+func awsEscape(s string) string {
+	var sb strings.Builder
+	for _, b := range []byte(s) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9',
+			b == '-', b == '_', b == '.', b == '~', b == '/':
+			sb.WriteByte(b)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", b)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// SFTP stores files over SFTP by running the OpenSSH sftp client in batch
+// mode. Batch mode cannot ask for passwords, so the server must accept a key
+// from the SSH agent or ~/.ssh.
+type SFTP struct {
+	target string // user@host
+	port   string
+	dir    string
+}
+
+// newSFTP reads sftp://user@host:port/path. The path is absolute; a path
+// starting with /~/ is relative to the home directory.
+func newSFTP(u *url.URL) (*SFTP, error) {
+	if _, err := exec.LookPath("sftp"); err != nil {
+		return nil, fmt.Errorf("sftp not found. Please install the OpenSSH client")
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("sftp:// output needs a host, e.g. sftp://user@nas/volume1/lyrics")
+	}
+	if _, ok := u.User.Password(); ok {
+		return nil, fmt.Errorf("sftp:// output does not support passwords; use an SSH key")
+	}
+
+	s := &SFTP{target: u.Hostname(), port: u.Port(), dir: strings.TrimSuffix(u.Path, "/")}
+	if u.User != nil {
+		s.target = u.User.Username() + "@" + s.target
+	}
+	if rest, ok := strings.CutPrefix(s.dir, "/~"); ok {
+		s.dir = strings.TrimPrefix(rest, "/")
+	}
+	return s, nil
+}
+
+// Write uploads the file from a temporary copy, creating missing directories
+func (s *SFTP) Write(name string, data []byte) error {
+	tmpFile, err := os.CreateTemp("", "whisper-lrc-upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	_, err = tmpFile.Write(data)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	remote := s.path(name)
+	var batch strings.Builder
+	// A leading "-" lets the batch continue when the directory exists
+	var dirs []string
+	for dir := path.Dir(remote); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		dirs = append(dirs, dir)
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		fmt.Fprintf(&batch, "-mkdir %s\n", sftpQuote(dirs[i]))
+	}
+	fmt.Fprintf(&batch, "put %s %s\n", sftpQuote(tmpFile.Name()), sftpQuote(remote))
+
+	if err := s.run(batch.String()); err != nil {
+		return fmt.Errorf("failed to upload %s: %w", s.Location(name), err)
+	}
+	return nil
+}
+
+// Exists lists the file. sftp only tells a missing file apart by its
+// message, "not found" from ls or "No such file" from the server; other
+// failures, such as a refused connection, are returned.
+func (s *SFTP) Exists(name string) (bool, error) {
+	err := s.run(fmt.Sprintf("ls %s\n", sftpQuote(s.path(name))))
+	var failure *sftpError
+	if errors.As(err, &failure) && (strings.Contains(failure.output, "not found") || strings.Contains(failure.output, "No such file")) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check %s: %w", s.Location(name), err)
+	}
+	return true, nil
+}
+
+// Location returns the sftp:// URL of the file
+func (s *SFTP) Location(name string) string {
+	host := s.target
+	if s.port != "" {
+		host += ":" + s.port
+	}
+	p := s.path(name)
+	if !strings.HasPrefix(p, "/") {
+		p = "/~/" + p
+	}
+	return "sftp://" + host + p
+}
+
+func (s *SFTP) path(name string) string {
+	if s.dir == "" {
+		return strings.TrimPrefix(name, "/")
+	}
+	return s.dir + "/" + strings.TrimPrefix(name, "/")
+}
+
+// run executes sftp commands in batch mode, which stops at the first failed
+// command
+func (s *SFTP) run(commands string) error {
+	args := []string{"-b", "-", "-q"}
+	if s.port != "" {
+		args = append(args, "-P", s.port)
+	}
+	args = append(args, s.target)
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("sftp", args...)
+	cmd.Stdin = strings.NewReader(commands)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return &sftpError{err: err, output: strings.TrimSpace(stderr.String())}
+	}
+	return nil
+}
+
+// sftpError is a failed sftp run with the messages it printed
+type sftpError struct {
+	err    error
+	output string
+}
+
+func (e *sftpError) Error() string {
+	return fmt.Sprintf("sftp failed: %v\nOutput: %s", e.err, e.output)
+}
+
+func (e *sftpError) Unwrap() error {
+	return e.err
+}
+
+// sftpQuote quotes a path for an sftp batch command
+func sftpQuote(p string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(p) + `"`
+}
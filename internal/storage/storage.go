@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BBleae/whisper-lrc/internal/diskspace"
+)
+
+// localHeadroom is kept free on a local filesystem beyond the file being
+// written
+const localHeadroom = 1 << 20
+
+// PartSuffix marks a local file that is still being written. Files are
+// renamed to their final name once complete, so an interrupted run never
+// leaves a truncated output behind.
+const PartSuffix = ".part"
+
+// stalePart is the age after which a leftover part file is considered
+// abandoned rather than in progress
+const stalePart = time.Minute
+
+// Storage is a destination for output files. Names are slash-separated paths
+// relative to the root of the storage.
+type Storage interface {
+	// Write stores data under name, replacing any existing file
+	Write(name string, data []byte) error
+	// Exists reports whether a file is stored under name
+	Exists(name string) (bool, error)
+	// Location describes where name is stored, for messages
+	Location(name string) string
+}
+
+// IsRemote reports whether location is a URL handled by a remote storage
+// rather than a local directory
+func IsRemote(location string) bool {
+	scheme, _, ok := strings.Cut(location, "://")
+	return ok && len(scheme) > 1 // Not a Windows drive letter
+}
+
+// Open returns the storage for location: a local directory, or a URL such as
+// s3://bucket/prefix, webdavs://host/path or sftp://user@host/path.
+// tlsConfig applies to S3 and WebDAV (nil keeps the defaults).
+func Open(location string, tlsConfig *tls.Config) (Storage, error) {
+	if !IsRemote(location) {
+		return Local{Dir: location}, nil
+	}
+
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output location: %w", err)
+	}
+	switch u.Scheme {
+	case "s3":
+		return newS3(u, tlsConfig)
+	case "webdav", "webdavs":
+		return newWebDAV(u, tlsConfig)
+	case "sftp":
+		return newSFTP(u)
+	default:
+		return nil, fmt.Errorf("unsupported output location %q (use a directory, s3://, webdav(s):// or sftp://)", location)
+	}
+}
+
+// Local stores files in a directory; an empty Dir takes names as paths of
+// their own
+type Local struct {
+	Dir string
+}
+
+// Write creates the parent directories and checks for free space first
+func (l Local) Write(name string, data []byte) error {
+	path := l.path(name)
+	if err := diskspace.Check(filepath.Dir(path), uint64(len(data))+localHeadroom); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return WriteFile(path, data, 0644)
+}
+
+// WriteFile writes data to a part file next to path and renames it to path
+// once complete
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	part := path + PartSuffix
+	if err := os.WriteFile(part, data, perm); err != nil {
+		os.Remove(part)
+		return err
+	}
+	if err := os.Rename(part, path); err != nil {
+		os.Remove(part)
+		return err
+	}
+	return nil
+}
+
+// RemoveStalePart removes the part file of path left behind by an
+// interrupted run
+func RemoveStalePart(path string) {
+	part := path + PartSuffix
+	if info, err := os.Stat(part); err == nil && time.Since(info.ModTime()) > stalePart {
+		os.Remove(part)
+	}
+}
+
+// Exists reports whether the file exists. A part file left by an interrupted
+// write does not count and is cleaned up.
+func (l Local) Exists(name string) (bool, error) {
+	RemoveStalePart(l.path(name))
+	_, err := os.Stat(l.path(name))
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// Location returns the file path
+func (l Local) Location(name string) string {
+	return l.path(name)
+}
+
+func (l Local) path(name string) string {
+	return filepath.Join(l.Dir, filepath.FromSlash(name))
+}
@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/BBleae/whisper-lrc/internal/redact"
+	"github.com/BBleae/whisper-lrc/internal/tlsconfig"
+)
+
+// WebDAV stores files on a WebDAV server with PUT, creating missing
+// collections as needed. Credentials come from the URL user info.
+type WebDAV struct {
+	base     *url.URL
+	user     string
+	password string
+	client   *http.Client
+}
+
+// newWebDAV maps webdav:// to http and webdavs:// to https
+func newWebDAV(u *url.URL, tlsConfig *tls.Config) (*WebDAV, error) {
+	base := *u
+	base.Scheme = "http"
+	if u.Scheme == "webdavs" {
+		base.Scheme = "https"
+	}
+	base.User = nil
+	base.Path = strings.TrimSuffix(base.Path, "/")
+
+	w := &WebDAV{
+		base:   &base,
+		client: &http.Client{Transport: tlsconfig.Transport(tlsConfig)},
+	}
+	if u.User != nil {
+		w.user = u.User.Username()
+		w.password, _ = u.User.Password()
+		redact.Register(w.password)
+	}
+	return w, nil
+}
+
+// Write uploads the file. A 409 Conflict means a parent collection is
+// missing, so the collections are created and the upload retried.
+func (w *WebDAV) Write(name string, data []byte) error {
+	p := w.path(name)
+	status, err := w.do(http.MethodPut, p, data)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusConflict {
+		if err := w.mkcol(path.Dir(p)); err != nil {
+			return err
+		}
+		if status, err = w.do(http.MethodPut, p, data); err != nil {
+			return err
+		}
+	}
+	if status < 200 || status > 299 {
+		return fmt.Errorf("failed to upload %s: status %d", w.Location(name), status)
+	}
+	return nil
+}
+
+// mkcol creates the collection at the URL path dir and its missing parents
+func (w *WebDAV) mkcol(dir string) error {
+	if dir == "/" {
+		return nil
+	}
+	status, err := w.do("MKCOL", dir, nil)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusConflict {
+		if err := w.mkcol(path.Dir(dir)); err != nil {
+			return err
+		}
+		if status, err = w.do("MKCOL", dir, nil); err != nil {
+			return err
+		}
+	}
+	// 405 Method Not Allowed: the collection already exists
+	if (status < 200 || status > 299) && status != http.StatusMethodNotAllowed {
+		return fmt.Errorf("failed to create %s: status %d", w.url(dir), status)
+	}
+	return nil
+}
+
+// Exists sends a HEAD request
+func (w *WebDAV) Exists(name string) (bool, error) {
+	status, err := w.do(http.MethodHead, w.path(name), nil)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case status == http.StatusNotFound:
+		return false, nil
+	case status >= 200 && status <= 299:
+		return true, nil
+	default:
+		return false, fmt.Errorf("failed to check %s: status %d", w.Location(name), status)
+	}
+}
+
+// Location returns the URL of the file
+func (w *WebDAV) Location(name string) string {
+	return w.url(w.path(name))
+}
+
+// path returns the URL path of a file
+func (w *WebDAV) path(name string) string {
+	return w.base.Path + "/" + strings.TrimPrefix(name, "/")
+}
+
+// url returns the URL of a URL path on the server
+func (w *WebDAV) url(p string) string {
+	u := *w.base
+	u.Path = p
+	return u.String()
+}
+
+// do sends a request for a URL path and returns the response status
+func (w *WebDAV) do(method, p string, data []byte) (int, error) {
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, w.url(p), body)
+	if err != nil {
+		return 0, fmt.Errorf("invalid WebDAV URL: %w", err)
+	}
+	if w.user != "" {
+		req.SetBasicAuth(w.user, w.password)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reach WebDAV server: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,114 @@
+package structure
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// Section kinds
+const (
+	Verse  = "verse"
+	Chorus = "chorus"
+	Bridge = "bridge"
+)
+
+// sectionGap is the pause (in seconds) between lines that starts a new section
+const sectionGap = 5.0
+
+// Section is a contiguous block of lyrics with a structural role
+type Section struct {
+	Kind  string   `json:"kind"`
+	Start float64  `json:"start"`
+	End   float64  `json:"end"`
+	First int      `json:"-"` // index of the first segment in the section
+	Lines []string `json:"lines"`
+}
+
+// Song is the song-structure document written as an auxiliary output
+type Song struct {
+	Sections []Section `json:"sections"`
+}
+
+// Detect splits the transcription into sections. Lines that repeat elsewhere
+// in the song form choruses; the remaining blocks are verses, except for a
+// non-repeating block between the last two choruses, which is a bridge.
+func Detect(result *whisper.TranscriptionResult) []Section {
+	counts := make(map[string]int)
+	keys := make([]string, len(result.Segments))
+	for i, seg := range result.Segments {
+		keys[i] = normalizeLine(seg.Text)
+		if keys[i] != "" {
+			counts[keys[i]]++
+		}
+	}
+
+	sections := make([]Section, 0)
+	for i, seg := range result.Segments {
+		if keys[i] == "" {
+			continue
+		}
+		kind := Verse
+		if counts[keys[i]] > 1 {
+			kind = Chorus
+		}
+
+		if n := len(sections); n > 0 {
+			last := &sections[n-1]
+			if last.Kind == kind && seg.Start-last.End < sectionGap {
+				last.End = seg.End
+				last.Lines = append(last.Lines, strings.TrimSpace(seg.Text))
+				continue
+			}
+		}
+		sections = append(sections, Section{
+			Kind:  kind,
+			Start: seg.Start,
+			End:   seg.End,
+			First: i,
+			Lines: []string{strings.TrimSpace(seg.Text)},
+		})
+	}
+
+	markBridge(sections)
+	return sections
+}
+
+// markBridge relabels the verse between the last two choruses as a bridge,
+// provided at least one earlier chorus exists
+func markBridge(sections []Section) {
+	var choruses []int
+	for i, s := range sections {
+		if s.Kind == Chorus {
+			choruses = append(choruses, i)
+		}
+	}
+	if len(choruses) < 3 {
+		return
+	}
+	prev, last := choruses[len(choruses)-2], choruses[len(choruses)-1]
+	if last-prev == 2 && sections[prev+1].Kind == Verse {
+		sections[prev+1].Kind = Bridge
+	}
+}
+
+// normalizeLine reduces a line to lowercase letters and digits so that
+// repeats differing only in punctuation or spacing compare equal
+func normalizeLine(text string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// Label returns a display name for a section kind
+func Label(kind string) string {
+	if kind == "" {
+		return ""
+	}
+	return strings.ToUpper(kind[:1]) + kind[1:]
+}
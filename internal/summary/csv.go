@@ -0,0 +1,43 @@
+package summary
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// csvHeader names the columns of WriteCSV
+var csvHeader = []string{"File", "Status", "Audio seconds", "Language", "Segments", "Output", "Error"}
+
+// WriteCSV writes one row per file for review in a spreadsheet. The file
+// starts with a byte order mark so that Excel reads non-ASCII file names as
+// UTF-8, and text that a spreadsheet would run as a formula is quoted.
+func (b *Batch) WriteCSV(w io.Writer) error {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader)
+	for _, f := range b.Files {
+		audio, segments := "", ""
+		if f.AudioSeconds > 0 {
+			audio = strconv.FormatFloat(f.AudioSeconds, 'f', 1, 64)
+		}
+		if f.Status == StatusOK {
+			segments = strconv.Itoa(f.Segments)
+		}
+		cw.Write([]string{cell(f.Input), f.Status, audio, f.Language, segments, cell(f.Output), cell(f.Reason)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// cell keeps a spreadsheet from taking text such as "=cmd" or "-rf" for a
+// formula
+func cell(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
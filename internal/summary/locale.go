@@ -0,0 +1,108 @@
+package summary
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+// Locale holds the separators used to print numbers
+type Locale struct {
+	Decimal string
+	Group   string
+}
+
+// English is the default locale: 1,234.5
+var English = Locale{Decimal: ".", Group: ","}
+
+// Languages that write 1.234,5 and 1 234,5
+var (
+	dotGrouping = map[string]bool{
+		"da": true, "de": true, "el": true, "es": true, "id": true, "it": true,
+		"nl": true, "pt": true, "ro": true, "tr": true, "vi": true,
+	}
+	spaceGrouping = map[string]bool{
+		"cs": true, "fi": true, "fr": true, "hu": true, "nb": true, "no": true,
+		"pl": true, "ru": true, "sk": true, "sv": true, "uk": true,
+	}
+)
+
+// LocaleFromEnv picks the separators from LC_ALL, LC_NUMERIC or LANG, in the
+// POSIX order of precedence. Unknown or unset locales print like English.
+func LocaleFromEnv() Locale {
+	for _, name := range []string{"LC_ALL", "LC_NUMERIC", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			return ParseLocale(value)
+		}
+	}
+	return English
+}
+
+// ParseLocale returns the separators for a locale name such as de_DE.UTF-8
+func ParseLocale(name string) Locale {
+	lang, _, _ := strings.Cut(strings.ToLower(name), "_")
+	lang, _, _ = strings.Cut(lang, ".")
+	lang, _, _ = strings.Cut(lang, "-")
+	switch {
+	case dotGrouping[lang]:
+		return Locale{Decimal: ",", Group: "."}
+	case spaceGrouping[lang]:
+		return Locale{Decimal: ",", Group: "\u202f"} // Narrow no-break space
+	default:
+		return English
+	}
+}
+
+// Number formats n with the given number of decimals and grouped thousands
+func (l Locale) Number(n float64, decimals int) string {
+	s := fmt.Sprintf("%.*f", decimals, math.Abs(n))
+	whole, fraction, _ := strings.Cut(s, ".")
+
+	var b strings.Builder
+	if n < 0 && strings.Trim(s, "0.") != "" {
+		b.WriteByte('-')
+	}
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(l.Group)
+		}
+		b.WriteRune(digit)
+	}
+	if fraction != "" {
+		b.WriteString(l.Decimal)
+		b.WriteString(fraction)
+	}
+	return b.String()
+}
+
+// Bytes formats a size in decimal units, e.g. 45.2 MB
+func (l Locale) Bytes(n int64) string {
+	if n < 1000 {
+		return l.Number(float64(n), 0) + " B"
+	}
+	size := float64(n)
+	for _, unit := range []string{"kB", "MB", "GB"} {
+		size /= 1000
+		if size < 1000 || unit == "GB" {
+			return l.Number(size, 1) + " " + unit
+		}
+	}
+	return ""
+}
+
+// Seconds formats a short processing time, e.g. 12.3 s
+func (l Locale) Seconds(d time.Duration) string {
+	return l.Number(d.Seconds(), 1) + " s"
+}
+
+// Clock formats a length of audio as m:ss or h:mm:ss
+func Clock(d time.Duration) string {
+	total := int64(d.Round(time.Second) / time.Second)
+	h, m, s := total/3600, total/60%60, total%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
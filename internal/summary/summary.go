@@ -0,0 +1,120 @@
+package summary
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// File statuses
+const (
+	StatusOK      = "ok"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// File is the outcome of one input
+type File struct {
+	Input  string `json:"input"`
+	Output string `json:"output,omitempty"`
+	Status string `json:"status"`
+	// Reason is the error of a failed input or why it was skipped
+	Reason            string  `json:"reason,omitempty"`
+	AudioSeconds      float64 `json:"audio_seconds,omitempty"`
+	DownloadSeconds   float64 `json:"download_seconds,omitempty"`
+	TranscribeSeconds float64 `json:"transcribe_seconds,omitempty"`
+	DownloadBytes     int64   `json:"download_bytes,omitempty"`
+	Language          string  `json:"language,omitempty"`
+	Segments          int     `json:"segments,omitempty"`
+}
+
+// Batch totals the files of a run for the summary printed at the end
+type Batch struct {
+	Succeeded     int     `json:"succeeded"`
+	Failed        int     `json:"failed"`
+	Skipped       int     `json:"skipped"`
+	Seconds       float64 `json:"seconds"`
+	AudioSeconds  float64 `json:"audio_seconds"`
+	DownloadBytes int64   `json:"download_bytes"`
+	// Realtime is the audio length processed per second of the run
+	Realtime float64 `json:"realtime_factor"`
+	Files    []File  `json:"files"`
+}
+
+// Add records a file
+func (b *Batch) Add(f File) {
+	switch f.Status {
+	case StatusOK:
+		b.Succeeded++
+	case StatusFailed:
+		b.Failed++
+	default:
+		b.Skipped++
+	}
+	b.AudioSeconds += f.AudioSeconds
+	b.DownloadBytes += f.DownloadBytes
+	b.Files = append(b.Files, f)
+}
+
+// Finish sets the length of the run
+func (b *Batch) Finish(elapsed time.Duration) {
+	b.Seconds = elapsed.Seconds()
+	if b.Seconds > 0 {
+		b.Realtime = b.AudioSeconds / b.Seconds
+	}
+}
+
+// WriteJSON writes the batch as an indented JSON object
+func (b *Batch) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b)
+}
+
+// WriteText prints the audio processed, throughput and download volume, then
+// a timing table when more than one file was transcribed
+func (b *Batch) WriteText(w io.Writer, l Locale) {
+	if b.AudioSeconds > 0 {
+		fmt.Fprintf(w, "Processed %s of audio in %s (%s× realtime)",
+			Clock(seconds(b.AudioSeconds)), Clock(seconds(b.Seconds)), l.Number(b.Realtime, 1))
+		if b.DownloadBytes > 0 {
+			fmt.Fprintf(w, ", downloaded %s", l.Bytes(b.DownloadBytes))
+		}
+		fmt.Fprintln(w)
+	}
+
+	var timed []File
+	for _, f := range b.Files {
+		if f.Status != StatusSkipped {
+			timed = append(timed, f)
+		}
+	}
+	if len(timed) < 2 {
+		return
+	}
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tAUDIO\tDOWNLOAD\tTRANSCRIBE\tRESULT")
+	for _, f := range timed {
+		// Local files take no download, and failed ones may not get as far as
+		// transcribing
+		audio, download, transcribe := "-", "-", "-"
+		if f.AudioSeconds > 0 {
+			audio = Clock(seconds(f.AudioSeconds))
+		}
+		if f.DownloadBytes > 0 {
+			download = l.Seconds(seconds(f.DownloadSeconds))
+		}
+		if f.TranscribeSeconds > 0 {
+			transcribe = l.Seconds(seconds(f.TranscribeSeconds))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", f.Input, audio, download, transcribe, f.Status)
+	}
+	tw.Flush()
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,214 @@
+package telegram
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// DefaultBaseURL is the Telegram Bot API server
+const DefaultBaseURL = "https://api.telegram.org"
+
+// MaxDownload is the largest file the Bot API lets bots download
+const MaxDownload = 20 << 20
+
+// Update is an incoming event; only messages are requested
+type Update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *Message `json:"message"`
+}
+
+// Message is a chat message with the fields the bot uses
+type Message struct {
+	MessageID int64  `json:"message_id"`
+	Chat      Chat   `json:"chat"`
+	Text      string `json:"text"`
+	Caption   string `json:"caption"`
+	Audio     *File  `json:"audio"`
+	Voice     *File  `json:"voice"`
+	Video     *File  `json:"video"`
+	VideoNote *File  `json:"video_note"`
+	Document  *File  `json:"document"`
+}
+
+// Chat identifies the conversation a message belongs to
+type Chat struct {
+	ID int64 `json:"id"`
+}
+
+// File is an attachment of a message
+type File struct {
+	FileID   string `json:"file_id"`
+	FileName string `json:"file_name"`
+	MimeType string `json:"mime_type"`
+	FileSize int64  `json:"file_size"`
+	// FilePath is set by Download
+	FilePath string `json:"file_path"`
+}
+
+// Attachment returns the media file of a message, if any
+func (m *Message) Attachment() *File {
+	for _, f := range []*File{m.Audio, m.Voice, m.Video, m.VideoNote, m.Document} {
+		if f != nil {
+			return f
+		}
+	}
+	return nil
+}
+
+// Bot calls the Bot API with a bot token
+type Bot struct {
+	token   string
+	baseURL string
+	client  *http.Client
+}
+
+// Option configures a Bot
+type Option func(*Bot)
+
+// WithBaseURL sets the Bot API server, e.g. a local telegram-bot-api server
+func WithBaseURL(baseURL string) Option {
+	return func(b *Bot) {
+		if baseURL != "" {
+			b.baseURL = baseURL
+		}
+	}
+}
+
+// WithHTTPClient sets the HTTP client. Its timeout must be longer than the
+// long polling timeout passed to Updates.
+func WithHTTPClient(client *http.Client) Option {
+	return func(b *Bot) {
+		b.client = client
+	}
+}
+
+// NewBot creates a Bot API client for token
+func NewBot(token string, opts ...Option) *Bot {
+	b := &Bot{
+		token:   token,
+		baseURL: DefaultBaseURL,
+		client:  &http.Client{Timeout: 2 * time.Minute},
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// apiResponse is the envelope of every Bot API reply
+type apiResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	Description string          `json:"description"`
+	ErrorCode   int             `json:"error_code"`
+}
+
+// call invokes a method with a JSON or multipart body and decodes the result
+func (b *Bot) call(method, contentType string, body io.Reader, result any) error {
+	endpoint := fmt.Sprintf("%s/bot%s/%s", b.baseURL, b.token, method)
+	resp, err := b.client.Post(endpoint, contentType, body)
+	if err != nil {
+		return fmt.Errorf("telegram %s: %w", method, withoutURL(err))
+	}
+	defer resp.Body.Close()
+
+	var r apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return fmt.Errorf("telegram %s: %s", method, resp.Status)
+	}
+	if !r.OK {
+		return fmt.Errorf("telegram %s: %s (%d)", method, r.Description, r.ErrorCode)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(r.Result, result)
+}
+
+// callJSON invokes a method with JSON parameters
+func (b *Bot) callJSON(method string, params map[string]any, result any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return b.call(method, "application/json", bytes.NewReader(body), result)
+}
+
+// Updates long-polls for messages after offset, waiting up to timeout
+func (b *Bot) Updates(offset int64, timeout time.Duration) ([]Update, error) {
+	var updates []Update
+	err := b.callJSON("getUpdates", map[string]any{
+		"offset":          offset,
+		"timeout":         int(timeout.Seconds()),
+		"allowed_updates": []string{"message"},
+	}, &updates)
+	return updates, err
+}
+
+// Download copies an attachment to w
+func (b *Bot) Download(f *File, w io.Writer) error {
+	var file File
+	if err := b.callJSON("getFile", map[string]any{"file_id": f.FileID}, &file); err != nil {
+		return err
+	}
+	f.FilePath = file.FilePath
+
+	resp, err := b.client.Get(fmt.Sprintf("%s/file/bot%s/%s", b.baseURL, b.token, file.FilePath))
+	if err != nil {
+		return fmt.Errorf("telegram download: %w", withoutURL(err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("telegram download: %s", resp.Status)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// withoutURL drops the request URL, which holds the token, from an HTTP
+// client error
+func withoutURL(err error) error {
+	if uerr, ok := err.(*url.Error); ok {
+		return uerr.Err
+	}
+	return err
+}
+
+// SendMessage replies to a message with text
+func (b *Bot) SendMessage(chatID, replyTo int64, text string) error {
+	return b.callJSON("sendMessage", map[string]any{
+		"chat_id":                  chatID,
+		"text":                     text,
+		"reply_to_message_id":      replyTo,
+		"disable_web_page_preview": true,
+	}, nil)
+}
+
+// SendDocument replies to a message with a file
+func (b *Bot) SendDocument(chatID, replyTo int64, name string, content []byte, caption string) error {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("chat_id", strconv.FormatInt(chatID, 10))
+	mw.WriteField("reply_to_message_id", strconv.FormatInt(replyTo, 10))
+	if caption != "" {
+		mw.WriteField("caption", caption)
+	}
+	part, err := mw.CreateFormFile("document", name)
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(content); err != nil {
+		return err
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+	return b.call("sendDocument", mw.FormDataContentType(), &buf, nil)
+}
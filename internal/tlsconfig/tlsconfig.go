@@ -0,0 +1,74 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// Options describes the TLS settings shared by the download and API clients
+type Options struct {
+	InsecureSkipVerify bool
+	CACert             string
+	ClientCert         string
+	ClientKey          string
+}
+
+// IsZero reports whether no TLS option is set
+func (o Options) IsZero() bool {
+	return o == Options{}
+}
+
+// Load builds a TLS config from the options. It returns nil when no option is
+// set so callers keep using the default transport.
+func Load(opts Options) (*tls.Config, error) {
+	if opts.IsZero() {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+
+	if opts.CACert != "" {
+		pem, err := os.ReadFile(opts.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+		// Trust the private CA in addition to the system roots
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in %s", opts.CACert)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if opts.ClientCert != "" || opts.ClientKey != "" {
+		if opts.ClientCert == "" || opts.ClientKey == "" {
+			return nil, fmt.Errorf("client certificate and key must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(opts.ClientCert, opts.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// Transport returns a copy of the default transport using cfg. HTTP/2 stays
+// enabled, which the standard library turns off for custom TLS configs.
+func Transport(cfg *tls.Config) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg != nil {
+		transport.TLSClientConfig = cfg
+		transport.ForceAttemptHTTP2 = true
+	}
+	return transport
+}
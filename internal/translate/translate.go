@@ -0,0 +1,112 @@
+package translate
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/BBleae/whisper-lrc/internal/whisper"
+)
+
+// DefaultModel is the chat model used when none is configured
+const DefaultModel = "gpt-4o-mini"
+
+// batchSize is the number of lines sent per request; songs usually fit in
+// one request while long subtitles are split to stay within output limits
+const batchSize = 80
+
+// Chatter sends chat completion requests
+type Chatter interface {
+	Chat(model string, messages []whisper.ChatMessage, jsonReply bool) (string, error)
+}
+
+// Translator translates lyrics line by line with a chat model, keeping every
+// segment's timing
+type Translator struct {
+	chat   Chatter
+	model  string
+	target string // ISO 639-1 code
+}
+
+// New creates a translator into the target language code
+func New(chat Chatter, model, target string) *Translator {
+	if model == "" {
+		model = DefaultModel
+	}
+	return &Translator{chat: chat, model: model, target: whisper.LanguageCode(target)}
+}
+
+// Target returns the target language code
+func (t *Translator) Target() string {
+	return t.target
+}
+
+// Translate returns a copy of the result with translated segment text
+func (t *Translator) Translate(result *whisper.TranscriptionResult) (*whisper.TranscriptionResult, error) {
+	translated := &whisper.TranscriptionResult{
+		Language: whisper.LanguageName(t.target),
+		Duration: result.Duration,
+		Segments: make([]whisper.Segment, len(result.Segments)),
+	}
+	copy(translated.Segments, result.Segments)
+
+	for start := 0; start < len(result.Segments); start += batchSize {
+		end := min(start+batchSize, len(result.Segments))
+		lines := make([]string, end-start)
+		for i, seg := range result.Segments[start:end] {
+			lines[i] = strings.TrimSpace(seg.Text)
+		}
+
+		out, err := t.translateLines(lines)
+		if err != nil {
+			return nil, err
+		}
+		for i, text := range out {
+			translated.Segments[start+i].Text = text
+			translated.Segments[start+i].Language = ""
+			translated.Segments[start+i].Words = nil
+		}
+	}
+
+	texts := make([]string, len(translated.Segments))
+	for i, seg := range translated.Segments {
+		texts[i] = seg.Text
+	}
+	translated.Text = strings.Join(texts, " ")
+	return translated, nil
+}
+
+// translateLines translates one batch, requiring one output line per input
+func (t *Translator) translateLines(lines []string) ([]string, error) {
+	input, err := json.Marshal(map[string][]string{"lines": lines})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode lyrics: %w", err)
+	}
+
+	system := fmt.Sprintf(`You translate song lyrics into %s (%s).
+You receive a JSON object {"lines": [...]} and reply with a JSON object {"lines": [...]} holding exactly one translated line for each input line, in the same order.
+Never merge, split, drop or add lines. Keep empty lines empty. Translate meaning naturally for singing; do not add notes or romanization.`,
+		whisper.LanguageName(t.target), t.target)
+
+	reply, err := t.chat.Chat(t.model, []whisper.ChatMessage{
+		{Role: "system", Content: system},
+		{Role: "user", Content: string(input)},
+	}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var output struct {
+		Lines []string `json:"lines"`
+	}
+	if err := json.Unmarshal([]byte(reply), &output); err != nil {
+		return nil, fmt.Errorf("model returned invalid JSON: %w", err)
+	}
+	if len(output.Lines) != len(lines) {
+		return nil, fmt.Errorf("model returned %d line(s) for %d", len(output.Lines), len(lines))
+	}
+	for i := range output.Lines {
+		output.Lines[i] = strings.TrimSpace(output.Lines[i])
+	}
+	return output.Lines, nil
+}
@@ -0,0 +1,66 @@
+package whisper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ChatMessage is one message of a chat completion request
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Chat sends messages to the chat completions endpoint of the same API and
+// returns the reply. With jsonReply set the model is asked for a JSON object.
+// On Azure OpenAI, model names the deployment of the chat model.
+func (c *Client) Chat(model string, messages []ChatMessage, jsonReply bool) (string, error) {
+	request := map[string]any{
+		"model":    model,
+		"messages": messages,
+	}
+	if jsonReply {
+		request["response_format"] = map[string]string{"type": "json_object"}
+	}
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode chat request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", c.endpoint("/chat/completions", model), bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	c.authorize(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", newAPIError(resp, body)
+	}
+
+	var completion struct {
+		Choices []struct {
+			Message ChatMessage `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &completion); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return "", fmt.Errorf("API returned no choices")
+	}
+	return completion.Choices[0].Message.Content, nil
+}
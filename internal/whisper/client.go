@@ -2,16 +2,35 @@ package whisper
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
 	"mime/multipart"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/BBleae/whisper-lrc/internal/redact"
+	"github.com/BBleae/whisper-lrc/internal/tlsconfig"
 )
 
-const apiURL = "https://api.openai.com/v1/audio/transcriptions"
+// DefaultBaseURL is the OpenAI API base URL
+const DefaultBaseURL = "https://api.openai.com/v1"
+
+// unixHost is the host of requests over a unix socket; it is ignored because
+// every connection is dialed to the socket
+const unixHost = "http://localhost"
+
+// unixAPIPath is the API path over a unix socket when the URL gives none
+const unixAPIPath = "/v1"
 
 const DefaultPrompt = `Transcribe only the actual sung or spoken lyrics. Do not add metadata such as composer, lyricist, arranger, artist names, song titles, or credits. If there is silence or instrumental sections, output nothing for those parts.`
 
@@ -20,94 +39,371 @@ type Segment struct {
 	Start float64 `json:"start"`
 	End   float64 `json:"end"`
 	Text  string  `json:"text"`
+	// Language is set by backends that detect the language per segment
+	Language string `json:"language,omitempty"`
+	// Words holds per-word timing when known
+	Words []Word `json:"words,omitempty"`
+	// AvgLogprob is the average log probability of the segment's tokens, a
+	// measure of confidence (0 when the backend does not report it)
+	AvgLogprob float64 `json:"avg_logprob,omitempty"`
+}
+
+// Word is a word (or CJK character) with timing. Word keeps the whitespace
+// that precedes it in the segment text, so joining the words of a segment
+// gives back its text.
+type Word struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Word  string  `json:"word"`
 }
 
 // TranscriptionResult holds the complete transcription
 type TranscriptionResult struct {
 	Text     string    `json:"text"`
 	Language string    `json:"language"`
+	Duration float64   `json:"duration"`
 	Segments []Segment `json:"segments"`
+	// Words is the word timing of the whole transcription as the OpenAI API
+	// sends it; the client moves it into the segments
+	Words []Word `json:"words,omitempty"`
+	// Raw is the response body the result was parsed from, when it came
+	// from the API
+	Raw []byte `json:"-"`
+	// Timing is the time spent on the requests for the result
+	Timing Timing `json:"-"`
+}
+
+// Confidence returns the average log probability of the segments weighted
+// by their length, and false when the backend reported none
+func (r *TranscriptionResult) Confidence() (float64, bool) {
+	var logprob, length float64
+	reported := false
+	for _, seg := range r.Segments {
+		logprob += seg.AvgLogprob * (seg.End - seg.Start)
+		length += seg.End - seg.Start
+		reported = reported || seg.AvgLogprob != 0
+	}
+	if !reported || length <= 0 {
+		return 0, false
+	}
+	return logprob / length, true
+}
+
+// LanguageOf returns the ISO 639-1 code of a segment's language, falling back
+// to the language detected for the whole transcription
+func (r *TranscriptionResult) LanguageOf(seg Segment) string {
+	if seg.Language != "" {
+		return LanguageCode(seg.Language)
+	}
+	return LanguageCode(r.Language)
 }
 
 // Client handles OpenAI Whisper API communication
 type Client struct {
 	apiKey     string
+	baseURL    string
+	tlsConfig  *tls.Config
 	httpClient *http.Client
+	compress   bool
+	// idleTimeout is how long idle connections are kept for reuse
+	idleTimeout time.Duration
+	http2       bool
+	// compressRefused is set once the server rejects a compressed upload
+	compressRefused atomic.Bool
+	// model is the transcription model
+	model string
+	// words requests word timestamps
+	words bool
+	// azureDeployment and azureVersion are set for Azure OpenAI
+	azureDeployment string
+	azureVersion    string
 }
 
-// NewClient creates a new Whisper API client
-func NewClient(apiKey string) *Client {
-	return &Client{
-		apiKey:     apiKey,
-		httpClient: &http.Client{},
+// Option configures a Client
+type Option func(*Client)
+
+// WithTLSConfig uses cfg for connections to the API (nil keeps the defaults)
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(c *Client) {
+		c.tlsConfig = cfg
 	}
 }
 
-// Transcribe sends an audio file to Whisper API and returns the result
-func (c *Client) Transcribe(audioPath string, language string, prompt string) (*TranscriptionResult, error) {
-	file, err := os.Open(audioPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open audio file: %w", err)
+// WithBaseURL sends requests to an OpenAI-compatible server instead of the
+// OpenAI API, e.g. http://localhost:8000/v1. A unix:///path/to/socket URL
+// talks HTTP over a unix socket, as local inference servers commonly expose.
+// The API path may follow the socket, as in unix:///run/whisper.sock/api/v1;
+// it defaults to /v1.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		if baseURL != "" {
+			c.baseURL = baseURL
+		}
 	}
-	defer file.Close()
+}
 
-	// Create multipart form
-	var buf bytes.Buffer
-	writer := multipart.NewWriter(&buf)
+// DefaultModel is the OpenAI transcription model
+const DefaultModel = "whisper-1"
 
-	// Add file
-	part, err := writer.CreateFormFile("file", filepath.Base(audioPath))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create form file: %w", err)
+// GroqBaseURL is the OpenAI-compatible API of Groq, which hosts Whisper
+// models under their own names
+const GroqBaseURL = "https://api.groq.com/openai/v1"
+
+// GroqModel is the Groq model used unless another is selected
+const GroqModel = "whisper-large-v3"
+
+// WithModel selects the transcription model, for servers that host other
+// Whisper models (an empty model keeps DefaultModel). Azure OpenAI selects
+// the model by deployment and ignores it.
+func WithModel(model string) Option {
+	return func(c *Client) {
+		if model != "" {
+			c.model = model
+		}
+	}
+}
+
+// DefaultAzureVersion is the Azure OpenAI API version used by default
+const DefaultAzureVersion = "2024-06-01"
+
+// WithAzure talks to Azure OpenAI, whose base URL is the resource endpoint
+// (https://NAME.openai.azure.com), with requests sent to a deployment of
+// the Whisper model and authorized by an api-key header. An empty
+// apiVersion uses DefaultAzureVersion.
+func WithAzure(deployment, apiVersion string) Option {
+	return func(c *Client) {
+		c.azureDeployment = deployment
+		c.azureVersion = apiVersion
+		if c.azureVersion == "" {
+			c.azureVersion = DefaultAzureVersion
+		}
 	}
-	if _, err := io.Copy(part, file); err != nil {
-		return nil, fmt.Errorf("failed to copy file: %w", err)
+}
+
+// WithWordTimestamps requests the timing of every word along with the
+// segments. Servers that do not support it send segments only.
+func WithWordTimestamps(enabled bool) Option {
+	return func(c *Client) {
+		c.words = enabled
 	}
+}
 
-	// Add model
-	if err := writer.WriteField("model", "whisper-1"); err != nil {
-		return nil, fmt.Errorf("failed to write model field: %w", err)
+// WithCompression gzips uploads. Servers that cannot decode them answer 415
+// Unsupported Media Type, after which the client sends them uncompressed.
+func WithCompression(enabled bool) Option {
+	return func(c *Client) {
+		c.compress = enabled
 	}
+}
+
+// DefaultIdleTimeout is how long an idle connection to the API is kept for
+// the next file
+const DefaultIdleTimeout = 90 * time.Second
 
-	// Add response format for timestamps
-	if err := writer.WriteField("response_format", "verbose_json"); err != nil {
-		return nil, fmt.Errorf("failed to write response_format field: %w", err)
+// WithIdleTimeout keeps idle connections to the API open for d, so a batch
+// reuses one connection instead of reconnecting for every file. Zero closes
+// the connection after each request.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.idleTimeout = d
 	}
+}
 
-	// Add timestamp granularities
-	if err := writer.WriteField("timestamp_granularities[]", "segment"); err != nil {
-		return nil, fmt.Errorf("failed to write timestamp_granularities field: %w", err)
+// WithHTTP2 enables or disables HTTP/2 for HTTPS servers; it is enabled by
+// default
+func WithHTTP2(enabled bool) Option {
+	return func(c *Client) {
+		c.http2 = enabled
 	}
+}
 
-	if language != "" {
-		if err := writer.WriteField("language", language); err != nil {
-			return nil, fmt.Errorf("failed to write language field: %w", err)
+// NewClient creates a new Whisper API client
+func NewClient(apiKey string, opts ...Option) *Client {
+	// The key must never show up in errors
+	redact.Register(apiKey)
+
+	c := &Client{
+		apiKey:      apiKey,
+		baseURL:     DefaultBaseURL,
+		model:       DefaultModel,
+		idleTimeout: DefaultIdleTimeout,
+		http2:       true,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	transport := tlsconfig.Transport(c.tlsConfig)
+	transport.IdleConnTimeout = c.idleTimeout
+	transport.DisableKeepAlives = c.idleTimeout == 0
+	if !c.http2 {
+		// An empty map stops the transport from negotiating h2
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	if path, ok := strings.CutPrefix(c.baseURL, "unix://"); ok {
+		socket, apiPath := splitUnixPath(path)
+		var dialer net.Dialer
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+		c.baseURL = unixHost + apiPath
+	}
+	c.httpClient = &http.Client{Transport: transport}
+	return c
+}
+
+// splitUnixPath splits the path of a unix:// URL into the socket and the API
+// path after it. The socket is the first element of the path that is a socket
+// on disk or, while it does not exist yet, is named *.sock; otherwise the
+// whole path is the socket.
+func splitUnixPath(path string) (socket, apiPath string) {
+	for i := 1; i <= len(path); i++ {
+		if i < len(path) && path[i] != '/' {
+			continue
+		}
+		prefix := path[:i]
+		info, err := os.Stat(prefix)
+		if err == nil && info.Mode()&os.ModeSocket != 0 || err != nil && strings.HasSuffix(prefix, ".sock") {
+			if rest := strings.TrimSuffix(path[i:], "/"); rest != "" {
+				return prefix, rest
+			}
+			return prefix, unixAPIPath
 		}
 	}
+	return path, unixAPIPath
+}
 
+// endpoint returns the URL of an API path such as "/audio/transcriptions".
+// On Azure OpenAI the path belongs to a deployment: the Whisper deployment,
+// or the chat model's deployment when one is given.
+func (c *Client) endpoint(path, deployment string) string {
+	base := strings.TrimSuffix(c.baseURL, "/")
+	if c.azureDeployment == "" {
+		return base + path
+	}
+	if deployment == "" {
+		deployment = c.azureDeployment
+	}
+	return base + "/openai/deployments/" + url.PathEscape(deployment) + path + "?api-version=" + url.QueryEscape(c.azureVersion)
+}
+
+// authorize adds the API key to a request
+func (c *Client) authorize(req *http.Request) {
+	if c.azureDeployment != "" {
+		req.Header.Set("api-key", c.apiKey)
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+}
+
+// formField is a multipart form field sent with the audio file
+type formField struct {
+	name, value string
+}
+
+// Audio is audio to upload: a file, or a small file already in memory
+type Audio struct {
+	Path string
+	// Name and Data hold audio in memory; Path is used when Data is nil
+	Name string
+	Data []byte
+}
+
+// Transcribe sends an audio file to Whisper API and returns the result
+func (c *Client) Transcribe(audioPath string, language string, prompt string) (*TranscriptionResult, error) {
+	return c.TranscribeAudio(Audio{Path: audioPath}, language, prompt)
+}
+
+// TranscribeAudio is Transcribe for audio that may be in memory
+func (c *Client) TranscribeAudio(audio Audio, language string, prompt string) (*TranscriptionResult, error) {
+	fields := []formField{
+		{"model", c.model},
+		// Response format and granularity for timestamps
+		{"response_format", "verbose_json"},
+		{"timestamp_granularities[]", "segment"},
+	}
+	if c.words {
+		fields = append(fields, formField{"timestamp_granularities[]", "word"})
+	}
+	if language != "" {
+		fields = append(fields, formField{"language", language})
+	}
+	if prompt != "" {
+		fields = append(fields, formField{"prompt", prompt})
+	}
+	return c.send("/audio/transcriptions", audio, fields)
+}
+
+// Translate sends an audio file to the Whisper translation endpoint, which
+// transcribes it into English text with segment timestamps
+func (c *Client) Translate(audioPath string, prompt string) (*TranscriptionResult, error) {
+	return c.TranslateAudio(Audio{Path: audioPath}, prompt)
+}
+
+// TranslateAudio is Translate for audio that may be in memory
+func (c *Client) TranslateAudio(audio Audio, prompt string) (*TranscriptionResult, error) {
+	fields := []formField{
+		{"model", c.model},
+		{"response_format", "verbose_json"},
+	}
 	if prompt != "" {
-		if err := writer.WriteField("prompt", prompt); err != nil {
-			return nil, fmt.Errorf("failed to write prompt field: %w", err)
+		fields = append(fields, formField{"prompt", prompt})
+	}
+	return c.send("/audio/translations", audio, fields)
+}
+
+// send posts the audio and form fields to an API path and parses the
+// verbose JSON response
+func (c *Client) send(path string, audio Audio, fields []formField) (*TranscriptionResult, error) {
+	var content io.ReaderAt
+	var size int64
+	name := audio.Name
+	if audio.Data != nil {
+		content, size = bytes.NewReader(audio.Data), int64(len(audio.Data))
+	} else {
+		file, err := os.Open(audio.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audio file: %w", err)
 		}
+		defer file.Close()
+		info, err := file.Stat()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audio file: %w", err)
+		}
+		content, size, name = file, info.Size(), filepath.Base(audio.Path)
 	}
 
+	// Build the multipart form around the file instead of copying the audio
+	// into memory: the fields and file part header go first, then the file
+	// is streamed, then the closing boundary
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	for _, field := range fields {
+		if err := writer.WriteField(field.name, field.value); err != nil {
+			return nil, fmt.Errorf("failed to write %s field: %w", field.name, err)
+		}
+	}
+	if _, err := writer.CreateFormFile("file", name); err != nil {
+		return nil, fmt.Errorf("failed to create form file: %w", err)
+	}
+	head := bytes.Clone(buf.Bytes())
+	buf.Reset()
 	if err := writer.Close(); err != nil {
 		return nil, fmt.Errorf("failed to close writer: %w", err)
 	}
+	tail := bytes.Clone(buf.Bytes())
 
-	// Create request
-	req, err := http.NewRequest("POST", apiURL, &buf)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	form := func() io.Reader {
+		return io.MultiReader(bytes.NewReader(head), io.NewSectionReader(content, 0, size), bytes.NewReader(tail))
 	}
+	length := int64(len(head)) + size + int64(len(tail))
 
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-
-	// Send request
-	resp, err := c.httpClient.Do(req)
+	timer := newTimer()
+	resp, err := c.post(path, form, length, writer.FormDataContentType(), timer)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -118,7 +414,7 @@ func (c *Client) Transcribe(audioPath string, language string, prompt string) (*
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, newAPIError(resp, body)
 	}
 
 	// Parse response
@@ -126,6 +422,75 @@ func (c *Client) Transcribe(audioPath string, language string, prompt string) (*
 	if err := json.Unmarshal(body, &result); err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
+	result.assignWords()
+	result.Raw = body
+	result.Timing = timer.timing()
 
 	return &result, nil
 }
+
+// post sends a form built by form, gzip-compressed when enabled and not yet
+// refused by the server, measured by timer
+func (c *Client) post(path string, form func() io.Reader, length int64, contentType string, timer *timer) (*http.Response, error) {
+	compress := c.compress && !c.compressRefused.Load()
+
+	req, err := http.NewRequestWithContext(timer.trace(context.Background()), "POST", c.endpoint(path, ""), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	c.authorize(req)
+	req.Header.Set("Content-Type", contentType)
+
+	if compress {
+		req.Header.Set("Content-Encoding", "gzip")
+		req.Body = gzipBody(form())
+		req.ContentLength = -1
+		req.GetBody = func() (io.ReadCloser, error) {
+			return gzipBody(form()), nil
+		}
+	} else {
+		// A known length avoids chunked uploads, which some servers reject
+		req.Body = io.NopCloser(form())
+		req.ContentLength = length
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(form()), nil
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	// RFC 7694: a server that cannot decode the content coding answers 415
+	if compress && resp.StatusCode == http.StatusUnsupportedMediaType {
+		// Drain the refusal so the connection can be reused
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		c.compressRefused.Store(true)
+		return c.post(path, form, length, contentType, timer)
+	}
+	return resp, nil
+}
+
+// gzipBody compresses r while it is being sent
+func gzipBody(r io.Reader) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		zw := gzip.NewWriter(pw)
+		_, err := io.Copy(zw, r)
+		if err == nil {
+			err = zw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+// PricePerMinute is the OpenAI price of whisper-1 transcription in USD
+const PricePerMinute = 0.006
+
+// EstimateCost returns the OpenAI price in USD for transcribing audio of the
+// given length. Self-hosted servers are not billed this way.
+func EstimateCost(length time.Duration) float64 {
+	return length.Minutes() * PricePerMinute
+}
@@ -0,0 +1,149 @@
+package whisper
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// uploadSize is the audio size used by the upload benchmarks, large enough
+// that a copy of the audio would dominate the allocations
+const uploadSize = 16 << 20
+
+// benchmarkServer returns a client for a server that reads and discards the
+// upload and answers with an empty transcription
+func benchmarkServer(b *testing.B) *Client {
+	b.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"text":"","language":"english","duration":1,"segments":[]}`)
+	}))
+	b.Cleanup(srv.Close)
+	return NewClient("key", WithBaseURL(srv.URL))
+}
+
+// BenchmarkUploadFile sends an audio file; allocations should stay far below
+// the file size, as the file is streamed into the request
+func BenchmarkUploadFile(b *testing.B) {
+	client := benchmarkServer(b)
+	path := filepath.Join(b.TempDir(), "audio.mp3")
+	if err := os.WriteFile(path, make([]byte, uploadSize), 0644); err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(uploadSize)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.TranscribeAudio(Audio{Path: path}, "", ""); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkUploadMemory sends audio held in memory, which is read in place
+// rather than copied into the form
+func BenchmarkUploadMemory(b *testing.B) {
+	client := benchmarkServer(b)
+	audio := Audio{Name: "audio.mp3", Data: make([]byte, uploadSize)}
+	b.SetBytes(uploadSize)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.TranscribeAudio(audio, "", ""); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkUploadBuffered is the reference for the two above: the form is
+// built in memory with a copy of the audio, as uploads used to be
+func BenchmarkUploadBuffered(b *testing.B) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	}))
+	defer srv.Close()
+	path := filepath.Join(b.TempDir(), "audio.mp3")
+	if err := os.WriteFile(path, make([]byte, uploadSize), 0644); err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(uploadSize)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		writer.WriteField("model", DefaultModel)
+		part, _ := writer.CreateFormFile("file", "audio.mp3")
+		file, err := os.Open(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		io.Copy(part, file)
+		file.Close()
+		writer.Close()
+		resp, err := http.Post(srv.URL, writer.FormDataContentType(), &buf)
+		if err != nil {
+			b.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+}
+
+// TestUnixSocketPath checks that the API path after the socket of a unix://
+// base URL is kept
+func TestUnixSocketPath(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "whisper")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	paths := make(chan string, 1)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths <- r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"text":"","language":"english","duration":1,"segments":[]}`)
+	})}
+	go srv.Serve(listener)
+	t.Cleanup(func() { srv.Close() })
+
+	tests := []struct {
+		base, want string
+	}{
+		{"unix://" + socket, "/v1/audio/transcriptions"},
+		{"unix://" + socket + "/", "/v1/audio/transcriptions"},
+		{"unix://" + socket + "/api/v1", "/api/v1/audio/transcriptions"},
+	}
+	for _, tt := range tests {
+		client := NewClient("key", WithBaseURL(tt.base))
+		if _, err := client.TranscribeAudio(Audio{Name: "a.mp3", Data: []byte("audio")}, "", ""); err != nil {
+			t.Fatalf("%s: %v", tt.base, err)
+		}
+		if got := <-paths; got != tt.want {
+			t.Errorf("%s: requested %s, want %s", tt.base, got, tt.want)
+		}
+	}
+}
+
+func TestSplitUnixPath(t *testing.T) {
+	tests := []struct {
+		path, socket, apiPath string
+	}{
+		{"/run/whisper.sock", "/run/whisper.sock", "/v1"},
+		{"/run/whisper.sock/api/v1", "/run/whisper.sock", "/api/v1"},
+		{"/run/whisper.sock/api/v1/", "/run/whisper.sock", "/api/v1"},
+		{"/run/whisper", "/run/whisper", "/v1"},
+	}
+	for _, tt := range tests {
+		socket, apiPath := splitUnixPath(tt.path)
+		if socket != tt.socket || apiPath != tt.apiPath {
+			t.Errorf("splitUnixPath(%q) = %q, %q, want %q, %q", tt.path, socket, apiPath, tt.socket, tt.apiPath)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package whisper
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/BBleae/whisper-lrc/internal/redact"
+)
+
+// Failure categories of API requests, for use with errors.Is
+var (
+	// ErrAuth means the API key was missing, invalid or not allowed
+	ErrAuth = errors.New("authentication failed")
+	// ErrRateLimited means the API rejected the request for exceeding a rate
+	// limit or quota
+	ErrRateLimited = errors.New("rate limited")
+	// ErrTooLarge means the audio file exceeds the API's upload limit
+	ErrTooLarge = errors.New("audio file too large")
+)
+
+// APIError is a non-200 response from the API. It matches ErrAuth,
+// ErrRateLimited or ErrTooLarge with errors.Is when the status code says so.
+type APIError struct {
+	StatusCode int
+	Body       string
+	// RequestID identifies the request for the API provider's support, if
+	// the server sent one
+	RequestID string
+}
+
+// newAPIError creates an APIError from a response and its body. Servers may
+// echo the key back (e.g. "Incorrect API key provided: sk-..."), so the body
+// is redacted.
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	return &APIError{
+		StatusCode: resp.StatusCode,
+		Body:       redact.String(string(body)),
+		RequestID:  resp.Header.Get("X-Request-Id"),
+	}
+}
+
+func (e *APIError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("API error (status %d, request %s): %s", e.StatusCode, e.RequestID, e.Body)
+	}
+	return fmt.Sprintf("API error (status %d): %s", e.StatusCode, e.Body)
+}
+
+// Unwrap returns the failure category of the status code, if any
+func (e *APIError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrAuth
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusRequestEntityTooLarge:
+		return ErrTooLarge
+	}
+	return nil
+}
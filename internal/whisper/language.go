@@ -0,0 +1,141 @@
+package whisper
+
+import "strings"
+
+// languageCodes maps the language names returned by verbose_json responses
+// to their ISO 639-1 codes
+var languageCodes = map[string]string{
+	"afrikaans":      "af",
+	"albanian":       "sq",
+	"amharic":        "am",
+	"arabic":         "ar",
+	"armenian":       "hy",
+	"assamese":       "as",
+	"azerbaijani":    "az",
+	"bashkir":        "ba",
+	"basque":         "eu",
+	"belarusian":     "be",
+	"bengali":        "bn",
+	"bosnian":        "bs",
+	"breton":         "br",
+	"bulgarian":      "bg",
+	"burmese":        "my",
+	"cantonese":      "yue",
+	"catalan":        "ca",
+	"chinese":        "zh",
+	"croatian":       "hr",
+	"czech":          "cs",
+	"danish":         "da",
+	"dutch":          "nl",
+	"english":        "en",
+	"estonian":       "et",
+	"faroese":        "fo",
+	"finnish":        "fi",
+	"french":         "fr",
+	"galician":       "gl",
+	"georgian":       "ka",
+	"german":         "de",
+	"greek":          "el",
+	"gujarati":       "gu",
+	"haitian creole": "ht",
+	"hausa":          "ha",
+	"hawaiian":       "haw",
+	"hebrew":         "he",
+	"hindi":          "hi",
+	"hungarian":      "hu",
+	"icelandic":      "is",
+	"indonesian":     "id",
+	"italian":        "it",
+	"japanese":       "ja",
+	"javanese":       "jw",
+	"kannada":        "kn",
+	"kazakh":         "kk",
+	"khmer":          "km",
+	"korean":         "ko",
+	"lao":            "lo",
+	"latin":          "la",
+	"latvian":        "lv",
+	"lingala":        "ln",
+	"lithuanian":     "lt",
+	"luxembourgish":  "lb",
+	"macedonian":     "mk",
+	"malagasy":       "mg",
+	"malay":          "ms",
+	"malayalam":      "ml",
+	"maltese":        "mt",
+	"maori":          "mi",
+	"marathi":        "mr",
+	"mongolian":      "mn",
+	"nepali":         "ne",
+	"norwegian":      "no",
+	"nynorsk":        "nn",
+	"occitan":        "oc",
+	"pashto":         "ps",
+	"persian":        "fa",
+	"polish":         "pl",
+	"portuguese":     "pt",
+	"punjabi":        "pa",
+	"romanian":       "ro",
+	"russian":        "ru",
+	"sanskrit":       "sa",
+	"serbian":        "sr",
+	"shona":          "sn",
+	"sindhi":         "sd",
+	"sinhala":        "si",
+	"slovak":         "sk",
+	"slovenian":      "sl",
+	"somali":         "so",
+	"spanish":        "es",
+	"sundanese":      "su",
+	"swahili":        "sw",
+	"swedish":        "sv",
+	"tagalog":        "tl",
+	"tajik":          "tg",
+	"tamil":          "ta",
+	"tatar":          "tt",
+	"telugu":         "te",
+	"thai":           "th",
+	"tibetan":        "bo",
+	"turkish":        "tr",
+	"turkmen":        "tk",
+	"ukrainian":      "uk",
+	"urdu":           "ur",
+	"uzbek":          "uz",
+	"vietnamese":     "vi",
+	"welsh":          "cy",
+	"yiddish":        "yi",
+	"yoruba":         "yo",
+}
+
+// LanguageCode returns the ISO 639-1 code for a language as reported by the API.
+// Inputs that are already codes (e.g. the --language flag) are returned lowercased.
+func LanguageCode(language string) string {
+	lang := strings.ToLower(strings.TrimSpace(language))
+	if code, ok := languageCodes[lang]; ok {
+		return code
+	}
+	return lang
+}
+
+// IsLanguageCode reports whether code is the code of a language Whisper
+// knows, e.g. "ja"
+func IsLanguageCode(code string) bool {
+	for _, c := range languageCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// LanguageName returns the English name of a language code, e.g. "japanese"
+// for "ja". Unknown codes are returned unchanged.
+func LanguageName(code string) string {
+	code = LanguageCode(code)
+	for name, c := range languageCodes {
+		if c == code {
+			return name
+		}
+	}
+	return code
+}
@@ -0,0 +1,36 @@
+package whisper
+
+import "strings"
+
+// MaxUploadBytes is the largest audio file the OpenAI API accepts
+const MaxUploadBytes = 25 << 20
+
+// Append adds the transcription of a later part of the same audio, which
+// starts offset seconds into it, shifting its timestamps to match. The
+// language of the first part is kept. Raw responses cannot be combined into
+// one and are dropped.
+func (r *TranscriptionResult) Append(part *TranscriptionResult, offset float64) {
+	if r.Language == "" {
+		r.Language = part.Language
+	}
+	if text := strings.TrimSpace(part.Text); text != "" {
+		r.Text = strings.TrimSpace(r.Text + " " + text)
+	}
+	r.Duration = offset + part.Duration
+	for _, seg := range part.Segments {
+		seg.Start += offset
+		seg.End += offset
+		if seg.Words != nil {
+			words := make([]Word, len(seg.Words))
+			for i, w := range seg.Words {
+				w.Start += offset
+				w.End += offset
+				words[i] = w
+			}
+			seg.Words = words
+		}
+		r.Segments = append(r.Segments, seg)
+	}
+	r.Raw = nil
+	r.Timing.Add(part.Timing)
+}
@@ -0,0 +1,56 @@
+package whisper
+
+import (
+	"context"
+	"net/http/httptrace"
+	"sync/atomic"
+	"time"
+)
+
+// Timing is where the time of API requests went
+type Timing struct {
+	// Upload is the time from starting a request until it was fully sent,
+	// including connecting
+	Upload time.Duration
+	// Wait is the time from then until the response was read: the server's
+	// processing and the download of its answer
+	Wait time.Duration
+}
+
+// Add adds the timing of another request
+func (t *Timing) Add(other Timing) {
+	t.Upload += other.Upload
+	t.Wait += other.Wait
+}
+
+// timer measures one request, including requests sent again after a
+// refusal
+type timer struct {
+	start time.Time
+	// wrote is when the request was last fully written, in Unix
+	// nanoseconds; the transport reports it from its own goroutine
+	wrote atomic.Int64
+}
+
+func newTimer() *timer {
+	return &timer{start: time.Now()}
+}
+
+// trace returns ctx with a trace that records when the request is written
+func (t *timer) trace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.wrote.Store(time.Now().UnixNano())
+		},
+	})
+}
+
+// timing returns the timing of a request whose response has been read
+func (t *timer) timing() Timing {
+	end := time.Now()
+	wrote := end
+	if nanos := t.wrote.Load(); nanos != 0 {
+		wrote = time.Unix(0, nanos)
+	}
+	return Timing{Upload: wrote.Sub(t.start), Wait: end.Sub(wrote)}
+}
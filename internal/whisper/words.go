@@ -0,0 +1,70 @@
+package whisper
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// assignWords moves the word timing of a response into its segments. The
+// OpenAI API lists words apart from the segments and without the text
+// between them, which is taken from the segment text so that the words of a
+// segment spell it. Words that do not match the text are dropped for the
+// segment. Segments that already have words, as some servers send them,
+// keep theirs.
+func (r *TranscriptionResult) assignWords() {
+	words := r.Words
+	r.Words = nil
+	for i := range r.Segments {
+		seg := &r.Segments[i]
+		end := len(words)
+		if i+1 < len(r.Segments) {
+			end = 0
+			for end < len(words) && words[end].Start < r.Segments[i+1].Start {
+				end++
+			}
+		}
+		if len(seg.Words) == 0 && end > 0 {
+			seg.Words = spellWords(seg.Text, words[:end])
+		}
+		words = words[end:]
+	}
+}
+
+// spellWords gives each word the whitespace before it and the punctuation
+// after it in text, or returns nil if the words do not spell text
+func spellWords(text string, words []Word) []Word {
+	spelled := make([]Word, len(words))
+	pos := 0
+	for i, word := range words {
+		token := strings.TrimSpace(word.Word)
+		idx := strings.Index(text[pos:], token)
+		// Only whitespace and punctuation may be skipped to find a word
+		if token == "" || idx < 0 || !isPunct(text[pos:pos+idx]) {
+			return nil
+		}
+		end := pos + idx + len(token)
+		for end < len(text) {
+			r, size := utf8.DecodeRuneInString(text[end:])
+			if !unicode.IsPunct(r) || i+1 < len(words) && strings.HasPrefix(text[end:], strings.TrimSpace(words[i+1].Word)) {
+				break
+			}
+			end += size
+		}
+		word.Word = text[pos:end]
+		spelled[i] = word
+		pos = end
+	}
+	spelled[len(spelled)-1].Word += text[pos:]
+	return spelled
+}
+
+// isPunct reports whether s holds only punctuation and whitespace
+func isPunct(s string) bool {
+	for _, r := range s {
+		if !unicode.IsPunct(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}